@@ -0,0 +1,84 @@
+// Package api provides the local control API of dutyme daemon.
+//
+// The API is JSON over HTTP served on Unix domain socket (only the user
+// who runs daemon can access it). It exposes the same operations as CLI
+// (start, stop, extend, status and history) so that editor plugins or
+// scripts can control overrides without PagerDuty API token, and CLI
+// subcommands use it transparently when daemon is running.
+package api
+
+import (
+	"github.com/tcnksm/dutyme/config"
+	"github.com/tcnksm/dutyme/journal"
+)
+
+// Paths of API endpoints.
+const (
+	PathStart   = "/v1/start"
+	PathStop    = "/v1/stop"
+	PathExtend  = "/v1/extend"
+	PathStatus  = "/v1/status"
+	PathHistory = "/v1/history"
+)
+
+// StartRequest is the request to create an override from now.
+type StartRequest struct {
+	// ScheduleID is the schedule to override. If it's empty,
+	// the schedule configured on daemon is used.
+	ScheduleID string `json:"schedule_id,omitempty"`
+
+	// Working is the duration of override. If it's zero,
+	// 1 hour is used.
+	Working config.Duration `json:"working,omitempty"`
+
+	// Reason and Ticket are why user overrides. They may be
+	// required by the configuration of daemon.
+	Reason string `json:"reason,omitempty"`
+	Ticket string `json:"ticket,omitempty"`
+
+	// Force is required to override the schedule protected by the
+	// policy of daemon.
+	Force bool `json:"force,omitempty"`
+}
+
+// StopRequest is the request to stop the active override.
+type StopRequest struct {
+	// OverrideID is the override to stop. If it's empty,
+	// the override which ends first is stopped.
+	OverrideID string `json:"override_id,omitempty"`
+}
+
+// ExtendRequest is the request to extend the active override.
+type ExtendRequest struct {
+	// OverrideID is the override to extend. If it's empty,
+	// the override which ends first is extended.
+	OverrideID string `json:"override_id,omitempty"`
+
+	// By is the duration to extend. If it's zero, the duration
+	// configured on daemon is used.
+	By config.Duration `json:"by,omitempty"`
+}
+
+// EventResponse is the response of start, stop and extend. Event
+// describes the override which is created (or stopped).
+type EventResponse struct {
+	Event journal.Event `json:"event"`
+}
+
+// StatusResponse is the response of status.
+type StatusResponse struct {
+	// Overrides is the active overrides created by dutyme
+	// (sorted by end time).
+	Overrides []journal.Event `json:"overrides"`
+}
+
+// HistoryResponse is the response of history.
+type HistoryResponse struct {
+	// Events is all events on journal (oldest first).
+	Events []journal.Event `json:"events"`
+}
+
+// ErrorResponse is the response when request fails.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
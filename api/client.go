@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultTimeout is default timeout of API request. Start and extend
+// request PagerDuty API on daemon, so it should be long enough.
+const DefaultTimeout = 30 * time.Second
+
+// Client is the client of the control API on Unix domain socket.
+type Client struct {
+	SocketPath string
+
+	// Timeout is the timeout of each request. If it's zero,
+	// DefaultTimeout is used.
+	Timeout time.Duration
+}
+
+// Error is the error returned by the API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("daemon returned %d: %s", e.StatusCode, e.Message)
+}
+
+// NotFound returns true when the requested override is not found.
+func (e *Error) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// Ping checks the daemon is listening on the socket.
+func (c *Client) Ping() error {
+	conn, err := net.DialTimeout("unix", c.SocketPath, time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Start requests to create an override.
+func (c *Client) Start(req *StartRequest) (*EventResponse, error) {
+	var res EventResponse
+	if err := c.do("POST", PathStart, req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Stop requests to stop the active override.
+func (c *Client) Stop(req *StopRequest) (*EventResponse, error) {
+	var res EventResponse
+	if err := c.do("POST", PathStop, req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Extend requests to extend the active override.
+func (c *Client) Extend(req *ExtendRequest) (*EventResponse, error) {
+	var res EventResponse
+	if err := c.do("POST", PathExtend, req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Status requests the active overrides.
+func (c *Client) Status() (*StatusResponse, error) {
+	var res StatusResponse
+	if err := c.do("GET", PathStatus, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// History requests all events on journal.
+func (c *Client) History() (*HistoryResponse, error) {
+	var res HistoryResponse
+	if err := c.do("GET", PathHistory, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) do(method, path string, body, v interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return errors.Wrap(err, "failed to encode request")
+		}
+	}
+
+	// Host is not used for Unix domain socket.
+	req, err := http.NewRequest(method, "http://dutyme"+path, &buf)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to request daemon")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var errRes ErrorResponse
+		if err := json.NewDecoder(res.Body).Decode(&errRes); err != nil {
+			errRes.Error = http.StatusText(res.StatusCode)
+		}
+		return &Error{StatusCode: res.StatusCode, Message: errRes.Error}
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+
+	return nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Dial: func(_, _ string) (net.Conn, error) {
+				return net.Dial("unix", c.SocketPath)
+			},
+		},
+	}
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+)
+
+const (
+	// DefaultWorking is default duration of override created by start.
+	DefaultWorking = 1 * time.Hour
+
+	// DefaultExtend is default duration of extend.
+	DefaultExtend = 30 * time.Minute
+)
+
+// Server serves the control API. Overrides are operated by Dutyme
+// on behalf of User.
+type Server struct {
+	Dutyme *dutyme.Dutyme
+	User   *dutyme.User
+
+	// ScheduleID is the schedule which is overridden when
+	// start request doesn't specify it.
+	ScheduleID string
+
+	// Extend is the duration used when extend request doesn't
+	// specify it. If it's zero, DefaultExtend is used.
+	Extend time.Duration
+}
+
+// Listen listens on the Unix domain socket of the given path. If the
+// socket file is left by the daemon which is not running anymore, it's
+// removed. It fails when another daemon is listening on it.
+func Listen(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, errors.Errorf("another daemon is listening on %s", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, errors.Wrap(err, "failed to remove stale socket")
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen on socket")
+	}
+
+	// Only the user who runs daemon can control overrides.
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, errors.Wrap(err, "failed to change socket permission")
+	}
+
+	return l, nil
+}
+
+// Serve serves the API on the given listener until it's closed.
+func (s *Server) Serve(l net.Listener) error {
+	return http.Serve(l, s.Handler())
+}
+
+// Handler returns http.Handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathStart, s.post(s.start))
+	mux.HandleFunc(PathStop, s.post(s.stop))
+	mux.HandleFunc(PathExtend, s.post(s.extend))
+	mux.HandleFunc(PathStatus, s.get(s.status))
+	mux.HandleFunc(PathHistory, s.get(s.history))
+	return mux
+}
+
+type handlerFunc func(r *http.Request) (interface{}, error)
+
+func (s *Server) get(h handlerFunc) http.HandlerFunc {
+	return s.handle("GET", h)
+}
+
+func (s *Server) post(h handlerFunc) http.HandlerFunc {
+	return s.handle("POST", h)
+}
+
+func (s *Server) handle(method string, h handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeJSON(w, http.StatusMethodNotAllowed, &ErrorResponse{
+				Error: fmt.Sprintf("method %s is not allowed", r.Method),
+			})
+			return
+		}
+
+		res, err := h(r)
+		if err != nil {
+			writeJSON(w, statusCode(err), &ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+func (s *Server) start(r *http.Request) (interface{}, error) {
+	var req StartRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	scheduleID := req.ScheduleID
+	if len(scheduleID) == 0 {
+		scheduleID = s.ScheduleID
+	}
+	if len(scheduleID) == 0 {
+		return nil, &errBadRequest{"schedule_id is required (no schedule is configured on daemon)"}
+	}
+
+	working := time.Duration(req.Working)
+	if working == 0 {
+		working = DefaultWorking
+	}
+	if working < 0 {
+		return nil, &errBadRequest{"working must be positive"}
+	}
+
+	start := time.Now()
+	end := start.Add(working)
+
+	// Confirmation is done by the client, so it's always forced here.
+	d := s.Dutyme.WithReason(req.Reason, req.Ticket)
+	d.Force = req.Force
+	override, err := d.Override(scheduleID, s.User, start, end, true)
+	if err != nil {
+		return nil, err
+	}
+
+	e := overrideEvent(journal.ActionStart, scheduleID, override, s.User)
+	e.Start, e.End = start, end
+	e.Reason, e.Ticket = req.Reason, req.Ticket
+
+	// Schedule name is resolved when the override is recorded on journal.
+	if recorded, err := s.Dutyme.FindActive(override.ID); err == nil {
+		e.ScheduleName = recorded.ScheduleName
+	}
+
+	return &EventResponse{Event: e}, nil
+}
+
+func (s *Server) stop(r *http.Request) (interface{}, error) {
+	var req StopRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	e, err := s.Dutyme.Stop(req.OverrideID)
+	if err != nil {
+		return nil, err
+	}
+	e.Action = journal.ActionStop
+	e.Time = time.Now()
+
+	return &EventResponse{Event: *e}, nil
+}
+
+func (s *Server) extend(r *http.Request) (interface{}, error) {
+	var req ExtendRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	by := time.Duration(req.By)
+	if by == 0 {
+		by = s.Extend
+	}
+	if by == 0 {
+		by = DefaultExtend
+	}
+
+	target, err := s.Dutyme.FindActive(req.OverrideID)
+	if err != nil {
+		return nil, err
+	}
+
+	override, end, err := s.Dutyme.Extend(*target, s.User, by)
+	if err != nil {
+		return nil, err
+	}
+
+	e := overrideEvent(journal.ActionExtend, target.ScheduleID, override, s.User)
+	e.PreviousID = target.OverrideID
+	e.ScheduleName = target.ScheduleName
+	e.End = end
+
+	return &EventResponse{Event: e}, nil
+}
+
+func (s *Server) status(r *http.Request) (interface{}, error) {
+	active, err := s.Dutyme.ActiveOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatusResponse{Overrides: active}, nil
+}
+
+func (s *Server) history(r *http.Request) (interface{}, error) {
+	if s.Dutyme.Journal == nil {
+		return nil, errors.New("journal is not configured")
+	}
+
+	events, err := s.Dutyme.Journal.Events()
+	if err != nil {
+		return nil, err
+	}
+
+	if events == nil {
+		events = []journal.Event{}
+	}
+	return &HistoryResponse{Events: events}, nil
+}
+
+// overrideEvent returns the event which describes the given override.
+// Start and end are left zero when PagerDuty returns invalid time.
+func overrideEvent(action, scheduleID string, o *pagerduty.Override, user *dutyme.User) journal.Event {
+	e := journal.Event{
+		Action:     action,
+		Time:       time.Now(),
+		OverrideID: o.ID,
+		ScheduleID: scheduleID,
+	}
+
+	if user != nil {
+		e.UserEmail = user.Email
+		if user.Obj != nil {
+			e.UserID = user.Obj.ID
+		}
+	}
+
+	start, err1 := time.Parse(time.RFC3339, o.Start)
+	end, err2 := time.Parse(time.RFC3339, o.End)
+	if err1 == nil && err2 == nil {
+		e.Start, e.End = start, end
+	}
+
+	return e
+}
+
+// decode decodes the JSON request body. Empty body is allowed
+// (then all fields are default).
+func decode(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil && err != io.EOF {
+		return &errBadRequest{fmt.Sprintf("invalid request body: %s", err)}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func statusCode(err error) int {
+	if _, ok := err.(*errBadRequest); ok {
+		return http.StatusBadRequest
+	}
+
+	// Missing reason or invalid ticket is the fault of request.
+	if _, ok := err.(*dutyme.ReasonError); ok {
+		return http.StatusBadRequest
+	}
+
+	if _, ok := err.(*dutyme.PolicyError); ok {
+		return http.StatusForbidden
+	}
+
+	if dutyme.IsNotFound(err) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+// errBadRequest is the error of invalid request.
+type errBadRequest struct {
+	msg string
+}
+
+func (e *errBadRequest) Error() string {
+	return e.msg
+}
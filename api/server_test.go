@@ -0,0 +1,165 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/tcnksm/dutyme/config"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/pdtest"
+)
+
+const (
+	testScheduleID   = "PI7DH85"
+	testScheduleName = "Dutyme primary"
+	testUserID       = "PXPGF42"
+)
+
+func testServe(t *testing.T) (*Client, *pdtest.PD, func()) {
+	dir, err := ioutil.TempDir("", "dutyme-api")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+
+	pd := &pdtest.PD{
+		Schedules: map[string]*pagerduty.Schedule{
+			testScheduleID: {
+				APIObject: pagerduty.APIObject{ID: testScheduleID},
+				Name:      testScheduleName,
+			},
+		},
+	}
+	s := &Server{
+		Dutyme: &dutyme.Dutyme{
+			PD:      pd,
+			Journal: &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")},
+		},
+		User: &dutyme.User{
+			Email: "taichi.nakashima@dutyme.com",
+			Obj:   &pagerduty.APIObject{ID: testUserID, Summary: "Taichi Nakashima"},
+		},
+		ScheduleID: testScheduleID,
+	}
+
+	path := filepath.Join(dir, "dutyme.sock")
+	l, err := Listen(path)
+	if err != nil {
+		t.Fatal("Listen failed:", err)
+	}
+	go s.Serve(l)
+
+	return &Client{SocketPath: path}, pd, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestServer(t *testing.T) {
+	client, pd, cleanup := testServe(t)
+	defer cleanup()
+
+	if err := client.Ping(); err != nil {
+		t.Fatal("Ping failed:", err)
+	}
+
+	started, err := client.Start(&StartRequest{Working: config.Duration(time.Hour), Reason: "deploy v1.2.0"})
+	if err != nil {
+		t.Fatal("Start failed:", err)
+	}
+
+	if got, want := started.Event.Reason, "deploy v1.2.0"; got != want {
+		t.Fatalf("Reason = %q, want %q", got, want)
+	}
+
+	if got, want := started.Event.ScheduleID, testScheduleID; got != want {
+		t.Fatalf("ScheduleID = %q, want %q", got, want)
+	}
+
+	if got, want := started.Event.ScheduleName, testScheduleName; got != want {
+		t.Fatalf("ScheduleName = %q, want %q", got, want)
+	}
+
+	if got, want := started.Event.End.Sub(started.Event.Start), time.Hour; got != want {
+		t.Fatalf("override length = %s, want %s", got, want)
+	}
+
+	extended, err := client.Extend(&ExtendRequest{By: config.Duration(30 * time.Minute)})
+	if err != nil {
+		t.Fatal("Extend failed:", err)
+	}
+
+	if got, want := extended.Event.PreviousID, started.Event.OverrideID; got != want {
+		t.Fatalf("PreviousID = %q, want %q", got, want)
+	}
+
+	if got, want := extended.Event.End, started.Event.End.Add(30*time.Minute); !got.Equal(want) {
+		t.Fatalf("End = %s, want %s", got, want)
+	}
+
+	status, err := client.Status()
+	if err != nil {
+		t.Fatal("Status failed:", err)
+	}
+
+	if got, want := len(status.Overrides), 1; got != want {
+		t.Fatalf("active overrides number = %d, want %d", got, want)
+	}
+
+	if got, want := status.Overrides[0].OverrideID, extended.Event.OverrideID; got != want {
+		t.Fatalf("active override = %q, want %q", got, want)
+	}
+
+	if got, want := status.Overrides[0].Reason, started.Event.Reason; got != want {
+		t.Fatalf("active override reason = %q, want %q", got, want)
+	}
+
+	if _, err := client.Stop(&StopRequest{}); err != nil {
+		t.Fatal("Stop failed:", err)
+	}
+
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	history, err := client.History()
+	if err != nil {
+		t.Fatal("History failed:", err)
+	}
+
+	var actions []string
+	for _, e := range history.Events {
+		actions = append(actions, e.Action)
+	}
+	if got, want := fmt.Sprint(actions), "[start extend stop]"; got != want {
+		t.Fatalf("history = %s, want %s", got, want)
+	}
+}
+
+func TestServer_notFound(t *testing.T) {
+	client, _, cleanup := testServe(t)
+	defer cleanup()
+
+	_, err := client.Stop(&StopRequest{OverrideID: "PNOTEXIST"})
+	if err == nil {
+		t.Fatal("expect to fail")
+	}
+
+	if !dutyme.IsNotFound(err) {
+		t.Fatalf("expect not found error: %s", err)
+	}
+}
+
+func TestListen_running(t *testing.T) {
+	client, _, cleanup := testServe(t)
+	defer cleanup()
+
+	if _, err := Listen(client.SocketPath); err == nil {
+		t.Fatal("expect to fail when daemon is running")
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/mitchellh/cli"
 	"github.com/tcnksm/dutyme/command"
+	"github.com/tcnksm/dutyme/finder"
 	input "github.com/tcnksm/go-input"
 )
 
@@ -20,6 +21,11 @@ func Run(args []string) int {
 			Writer: os.Stderr,
 			Reader: os.Stdin,
 		},
+
+		Finder: &finder.Finder{
+			In:  os.Stdin,
+			Out: os.Stderr,
+		},
 	}
 
 	return RunCustom(args, Commands(meta))
@@ -0,0 +1,523 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/config"
+	input "github.com/tcnksm/go-input"
+)
+
+type ConfigCommand struct {
+	Meta
+}
+
+func (c *ConfigCommand) Synopsis() string {
+	return "Show, change and validate configuration"
+}
+
+func (c *ConfigCommand) Help() string {
+	helpText := fmt.Sprintf(`Usage: dutyme config <subcommand> [args...]
+
+Subcommands:
+
+  show [-origin]    Print all effective values.
+  get KEY           Print the effective value of KEY (or its children).
+  set KEY VALUE     Set KEY in the user's configuration file.
+  unset KEY         Remove KEY from the user's configuration file.
+  edit              Open the user's configuration file by $VISUAL or
+                    $EDITOR. It's validated when the editor exits.
+  validate          Validate the configuration and check that saved
+                    user and schedule still exist on PagerDuty.
+  trust             Trust the project configuration of the current
+                    directory as it is now.
+
+config shows the effective configuration values merged from the
+following layers (later one wins):
+
+  system   %s (organization-wide defaults)
+  user     $XDG_CONFIG_HOME/dutyme/config.json or ~/%s
+  project  %s found in the repository of the current directory
+  env      DUTYME_* env vars (e.g., DUTYME_SCHEDULE_ID, DUTYME_HOOKS_PRE_START)
+  flag     command line flags (e.g., -working of start command)
+
+Project configuration comes from the repository, so its hooks, reminder
+command and webhook and notifiers are ignored until it's trusted by
+trust subcommand (its hash is added to "trusted_projects" of the user's
+file). Changing the file requires trusting it again.
+
+Only "policy" of the system layer is binding: the other layers can
+tighten it (shorter max_duration, more protected schedules or the keys
+which the system layer doesn't set) but can't loosen it.
+
+Files can be JSON, YAML (.yaml, .yml) or TOML (.toml) by extension
+(e.g., ~/.config/dutyme/config.yaml). set, unset and edit write the user's
+file back in its format and keep keys which dutyme doesn't know.
+
+Every key can be set by env var: DUTYME_ and the key in upper case
+where "." is replaced with "_". Lists of strings are comma-separated
+and other non-string values are JSON (the same for VALUE of set).
+Secrets (API token, SMTP password and webhook URLs) are masked.
+
+Options of show:
+
+  -origin   Show which layer each value comes from.
+
+`, config.SystemPath(), DefaultConfigName, config.ProjectName)
+	return helpText
+}
+
+func (c *ConfigCommand) Run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: subcommand is required (show, get, set, unset, edit, validate or trust)\n")
+		return ExitCodeError
+	}
+
+	switch args[0] {
+	case "show":
+		return c.show(args[1:])
+	case "get":
+		return c.get(args[1:])
+	case "set":
+		return c.set(args[1:])
+	case "unset":
+		return c.unset(args[1:])
+	case "edit":
+		return c.edit(args[1:])
+	case "validate":
+		return c.validate(args[1:])
+	case "trust":
+		return c.trust(args[1:])
+	default:
+		fmt.Fprintf(c.ErrStream, "Invalid argument: unknown subcommand %q\n", args[0])
+		return ExitCodeError
+	}
+}
+
+func (c *ConfigCommand) show(args []string) int {
+
+	var origin bool
+
+	flags := c.Meta.NewFlagSet("config", c.Help())
+	flags.BoolVar(&origin, "origin", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	layers, _, err := c.Meta.Layers()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	for _, v := range layers.Values() {
+		line := fmt.Sprintf("%s = %s", v.Key, formatValue(v))
+		if origin {
+			line += fmt.Sprintf("  (%s)", formatOrigin(v))
+		}
+		fmt.Fprintln(c.OutStream, line)
+	}
+
+	return ExitCodeOK
+}
+
+func (c *ConfigCommand) get(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: get requires KEY\n")
+		return ExitCodeError
+	}
+	key := args[0]
+
+	layers, _, err := c.Meta.Layers()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if v, ok := layers.Origin(key); ok {
+		fmt.Fprintln(c.OutStream, formatValue(v))
+		return ExitCodeOK
+	}
+
+	// For the object key (e.g., "hooks"), print its children.
+	found := false
+	for _, v := range layers.Values() {
+		if strings.HasPrefix(v.Key, key+".") {
+			fmt.Fprintf(c.OutStream, "%s = %s\n", v.Key, formatValue(v))
+			found = true
+		}
+	}
+
+	if !found {
+		fmt.Fprintf(c.ErrStream, "%s is not set\n", key)
+		return ExitCodeError
+	}
+
+	return ExitCodeOK
+}
+
+func (c *ConfigCommand) set(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: set requires KEY and VALUE\n")
+		return ExitCodeError
+	}
+
+	cfgPath, err := c.Meta.ConfigPath()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to get config path: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if err := config.SetFile(cfgPath, args[0], args[1]); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to set %s: %s\n", args[0], err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	c.warnOverridden(args[0])
+	return ExitCodeOK
+}
+
+func (c *ConfigCommand) unset(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: unset requires KEY\n")
+		return ExitCodeError
+	}
+
+	cfgPath, err := c.Meta.ConfigPath()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to get config path: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if err := config.UnsetFile(cfgPath, args[0]); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to unset %s: %s\n", args[0], err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	c.warnOverridden(args[0])
+	return ExitCodeOK
+}
+
+// warnOverridden warns when the key changed in user's configuration
+// file is overridden by the upper layer (e.g., project configuration).
+func (c *ConfigCommand) warnOverridden(key string) {
+	layers, _, err := c.Meta.Layers()
+	if err != nil {
+		return
+	}
+
+	if v, ok := layers.Origin(key); ok && v.Origin != config.OriginUser {
+		fmt.Fprintf(c.ErrStream, "WARNING: %s is overridden by %s\n", key, formatOrigin(v))
+	}
+}
+
+func (c *ConfigCommand) edit(args []string) int {
+	cfgPath, err := c.Meta.ConfigPath()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to get config path: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if _, err := config.MigrateFile(cfgPath); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to migrate configuration file: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	// Keep the original to restore it when the edited one is invalid.
+	original, err := ioutil.ReadFile(cfgPath)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(c.ErrStream, "Failed to read configuration file: %s\n", err)
+		return ExitCodeError
+	}
+
+	if os.IsNotExist(err) {
+		if err := config.CreateFile(cfgPath); err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to create configuration file: %s\n", err)
+			return ExitCodeError
+		}
+	}
+
+	for {
+		if err := c.runEditor(cfgPath); err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to run editor: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		err := validateFile(cfgPath)
+		if err == nil {
+			return ExitCodeOK
+		}
+
+		fmt.Fprintf(c.ErrStream, "Invalid configuration: %s\n", err)
+		ans, askErr := c.Meta.UI.Ask("Edit again? (the original is restored if not) [Y/n]", &input.Options{
+			Default:     "Y",
+			Loop:        true,
+			HideOrder:   true,
+			HideDefault: true,
+			ValidateFunc: func(s string) error {
+				if s != "Y" && s != "y" && s != "N" && s != "n" {
+					return fmt.Errorf("input must be Y or n")
+				}
+				return nil
+			},
+		})
+		if askErr == nil && (ans == "Y" || ans == "y") {
+			continue
+		}
+
+		if original == nil {
+			err = os.Remove(cfgPath)
+		} else {
+			err = ioutil.WriteFile(cfgPath, original, 0600)
+		}
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to restore configuration file: %s\n", err)
+			return ExitCodeError
+		}
+
+		fmt.Fprintf(c.ErrStream, "Restored the original configuration\n")
+		return ExitCodeError
+	}
+}
+
+// runEditor opens the file by $VISUAL or $EDITOR (vi or notepad by
+// default). The editor may have arguments (e.g., "code --wait").
+func (c *ConfigCommand) runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if len(editor) == 0 {
+		editor = os.Getenv("EDITOR")
+	}
+	if len(editor) == 0 {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = c.ErrStream
+
+	Debugf("Run editor: %s %s", editor, path)
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "failed to run %s", editor)
+	}
+	return nil
+}
+
+// validateFile checks the configuration file can be read as layer
+// and as configuration.
+func validateFile(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return nil
+	}
+
+	layers := &config.Layered{}
+	if err := layers.AddFile(config.OriginUser, path); err != nil {
+		return err
+	}
+
+	cfg, err := layers.Config()
+	if err != nil {
+		return err
+	}
+
+	return cfg.Validate()
+}
+
+func (c *ConfigCommand) validate(args []string) int {
+	cfg, _, err := c.Meta.ReadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(c.ErrStream, "Invalid configuration: %s\n", err)
+		return ExitCodeError
+	}
+
+	if len(cfg.Token) == 0 {
+		fmt.Fprintf(c.OutStream, "Configuration is valid (PagerDuty check is skipped: no API token)\n")
+		return ExitCodeOK
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	stale, err := dutyme.CheckStale(cfg.User, cfg.ScheduleID, cfg.ScheduleName)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to check PagerDuty: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if len(stale) != 0 {
+		fmt.Fprintf(c.ErrStream, "Stale configuration:\n")
+		for _, s := range stale {
+			fmt.Fprintf(c.ErrStream, "  %s\n", s)
+		}
+		return ExitCodeError
+	}
+
+	fmt.Fprintf(c.OutStream, "Configuration is valid\n")
+	return ExitCodeOK
+}
+
+func (c *ConfigCommand) trust(args []string) int {
+	projectPath, err := c.Meta.ProjectConfigPath()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to find project configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if len(projectPath) == 0 {
+		fmt.Fprintf(c.ErrStream, "No project configuration (%s) is found\n", config.ProjectName)
+		return ExitCodeError
+	}
+
+	cfgPath, err := c.Meta.ConfigPath()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to get config path: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if err := config.TrustProject(cfgPath, projectPath); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to trust %s: %s\n", projectPath, err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	fmt.Fprintf(c.OutStream, "Trusted project configuration %s\n", projectPath)
+	return ExitCodeOK
+}
+
+// formatValue formats the configuration value for displaying. Strings
+// are shown as they are and the others as JSON. Secrets are masked.
+func formatValue(v config.Value) string {
+	value := maskSecret(v.Key, v.Value)
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(buf)
+}
+
+// maskSecret masks the value if the key is secret: token, SMTP password
+// and webhook URLs (their path works as credential). Notifiers are also
+// configured in presets, so notify keys are matched by suffix.
+func maskSecret(key string, value interface{}) interface{} {
+	switch {
+	case key == "token":
+		if s, ok := value.(string); ok {
+			return maskToken(s)
+		}
+	case key == "reminder.webhook":
+		if s, ok := value.(string); ok {
+			return maskURL(s)
+		}
+	case key == "notify.email.password" || strings.HasSuffix(key, ".notify.email.password"):
+		if s, ok := value.(string); ok {
+			return maskToken(s)
+		}
+	case key == "notify.webhooks" || strings.HasSuffix(key, ".notify.webhooks"),
+		key == "notify.slack" || strings.HasSuffix(key, ".notify.slack"):
+		items, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+
+		masked := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				masked = append(masked, item)
+				continue
+			}
+
+			copied := make(map[string]interface{}, len(obj))
+			for k, v := range obj {
+				copied[k] = v
+			}
+			if s, ok := copied["url"].(string); ok {
+				copied["url"] = maskURL(s)
+			}
+			masked = append(masked, copied)
+		}
+		return masked
+	}
+
+	return value
+}
+
+// formatOrigin formats where the value comes from.
+func formatOrigin(v config.Value) string {
+	switch {
+	case len(v.Path) != 0:
+		return fmt.Sprintf("%s: %s", v.Origin, v.Path)
+	case v.Origin == config.OriginEnv:
+		name := config.EnvName(v.Key)
+		if v.Key == "token" && len(os.Getenv(name)) == 0 {
+			name = EnvToken
+		}
+		return fmt.Sprintf("%s: %s", v.Origin, name)
+	default:
+		return string(v.Origin)
+	}
+}
+
+// maskToken masks the secret (e.g., API token) except its last 4 characters.
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return strings.Repeat("*", len(token)-4) + token[len(token)-4:]
+}
+
+// maskURL masks the URL except its scheme and host so that user can
+// still tell which service it is (e.g., hooks.slack.com).
+func maskURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || len(u.Host) == 0 || u.User != nil {
+		return maskToken(s)
+	}
+
+	prefix := u.Scheme + "://" + u.Host
+	return prefix + maskToken(strings.TrimPrefix(s, prefix))
+}
@@ -0,0 +1,55 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/tcnksm/dutyme/config"
+)
+
+func TestConfigCommand_implement(t *testing.T) {
+	var _ cli.Command = &ConfigCommand{}
+}
+
+func TestFormatValue(t *testing.T) {
+	slackURL := "https://hooks.slack.com/services/T000/B000/XXXXXXXXsecret"
+	webhooks := []interface{}{
+		map[string]interface{}{"url": "https://example.com/oncall?key=secret", "template": "{}"},
+	}
+	slack := []interface{}{
+		map[string]interface{}{"url": slackURL, "channel": "#oncall"},
+	}
+
+	cases := []struct {
+		key    string
+		value  interface{}
+		secret string
+		want   string
+	}{
+		{key: "token", value: "abcdefgh1234", secret: "abcdefgh", want: "1234"},
+		{key: "notify.email.password", value: "p@ssw0rd-smtp", secret: "p@ssw0rd", want: "smtp"},
+		{key: "presets.deploy.notify.email.password", value: "p@ssw0rd-smtp", secret: "p@ssw0rd", want: "smtp"},
+		{key: "reminder.webhook", value: "https://example.com/remind/secret", secret: "secret", want: "https://example.com"},
+		{key: "notify.webhooks", value: webhooks, secret: "key=secret", want: `"template":"{}"`},
+		{key: "notify.slack", value: slack, secret: "XXXXXXXX", want: "https://hooks.slack.com"},
+		{key: "presets.deploy.notify.slack", value: slack, secret: "XXXXXXXX", want: `"channel":"#oncall"`},
+		{key: "notify.email.from", value: "dutyme@example.com", want: "dutyme@example.com"},
+	}
+
+	for _, tc := range cases {
+		got := formatValue(config.Value{Key: tc.key, Value: tc.value})
+		if len(tc.secret) != 0 && strings.Contains(got, tc.secret) {
+			t.Fatalf("formatValue(%s) = %q, should mask %q", tc.key, got, tc.secret)
+		}
+
+		if !strings.Contains(got, tc.want) {
+			t.Fatalf("formatValue(%s) = %q, want to contain %q", tc.key, got, tc.want)
+		}
+	}
+
+	// The configured value itself must not be changed.
+	if got := slack[0].(map[string]interface{})["url"]; got != slackURL {
+		t.Fatalf("url = %q, want %q", got, slackURL)
+	}
+}
@@ -0,0 +1,278 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/api"
+	"github.com/tcnksm/dutyme/config"
+	"github.com/tcnksm/dutyme/daemon"
+	"github.com/tcnksm/dutyme/hook"
+)
+
+type DaemonCommand struct {
+	Meta
+}
+
+func (c *DaemonCommand) Synopsis() string {
+	return "Remind you before your override expires"
+}
+
+func (c *DaemonCommand) Help() string {
+	helpText := `Usage: dutyme daemon [options...]
+
+daemon tracks the overrides created by dutyme and fires reminders before
+they expire. Reminders are sent to the following:
+
+  - watch command (it rings terminal bell and offers extending)
+  - shell command configured by "reminder.command" in configuration file.
+    Override details are passed via DUTYME_HOOK_* env vars.
+  - webhook URL configured by "reminder.webhook" in configuration file.
+    Override details are posted as JSON.
+  - "hooks.on_expire_soon" hook in configuration file.
+
+Each reminder includes the command to extend the override by one step.
+daemon runs in foreground until it receives SIGINT or SIGTERM.
+
+When API token, user and schedule are available (saved by start command),
+daemon also serves the control API (JSON over HTTP) on the Unix domain
+socket ~/.dutyme.d/dutyme.sock:
+
+  POST /v1/start     {"schedule_id": "...", "working": "1h"}
+  POST /v1/stop      {"override_id": "..."}
+  POST /v1/extend    {"override_id": "...", "by": "30m"}
+  GET  /v1/status
+  GET  /v1/history
+
+start, stop, extend, status and history commands use it transparently
+while daemon is running.
+
+Options:
+
+  -before TIME    How long before expiry reminders are fired. By default,
+                  it's 10 minutes (or "reminder.before" in configuration).
+
+  -interval TIME  Interval of checking overrides. By default, it's 1 minute.
+
+  -no-api         Don't serve the control API.
+
+  -auto-extend    Extend the override which is about to expire while the
+                  triggered or acknowledged incidents assigned to you on
+                  the schedule's services are open. It extends by 30
+                  minutes up to 4 hours in total (or "auto_extend.step"
+                  and "auto_extend.max" in configuration file). Each
+                  extension is logged. It requires API token.
+
+`
+	return helpText
+}
+
+func (c *DaemonCommand) Run(args []string) int {
+
+	var (
+		before   time.Duration
+		interval time.Duration
+		noAPI    bool
+
+		autoExtend bool
+	)
+
+	flags := c.Meta.NewFlagSet("daemon", c.Help())
+
+	flags.DurationVar(&before, "before", 0, "")
+	flags.DurationVar(&interval, "interval", daemon.DefaultInterval, "")
+	flags.BoolVar(&noAPI, "no-api", false, "")
+	flags.BoolVar(&autoExtend, "auto-extend", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	// Reminders don't request PagerDuty API, so daemon doesn't require
+	// API token (then the control API is not served).
+	cfg, _, err := c.Meta.ReadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	journal, err := c.Meta.Journal()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read journal: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	feed, err := c.Meta.Feed()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read reminder feed: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	st, err := c.Meta.State()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read state file: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	leases, err := c.Meta.Leases(nil)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read lease file: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	d := &daemon.Daemon{
+		Journal:   journal,
+		Notifiers: []daemon.Notifier{feed},
+		Before:    before,
+		Interval:  interval,
+		User:      cfg.User,
+		State:     st,
+		Leases:    leases,
+		Log:       c.OutStream,
+	}
+
+	if r := cfg.Reminder; r != nil {
+		if before == 0 {
+			d.Before = time.Duration(r.Before)
+		}
+
+		if len(r.Command) != 0 {
+			d.Notifiers = append(d.Notifiers, &daemon.CommandNotifier{
+				Command: r.Command,
+				Stdout:  c.OutStream,
+				Stderr:  c.ErrStream,
+			})
+		}
+
+		if len(r.Webhook) != 0 {
+			d.Notifiers = append(d.Notifiers, &daemon.WebhookNotifier{
+				URL: r.Webhook,
+			})
+		}
+	}
+
+	if hooks := c.Meta.Hooks(cfg); hooks.Has(hook.OnExpireSoon) {
+		d.Notifiers = append(d.Notifiers, &daemon.HookNotifier{Hooks: hooks})
+	}
+
+	// Auto-extending is opt-in (by -auto-extend or "auto_extend" in
+	// configuration) and it requests PagerDuty API.
+	if autoExtend || cfg.AutoExtend != nil {
+		if len(cfg.Token) == 0 || cfg.User == nil {
+			fmt.Fprintf(c.ErrStream, "Auto-extending requires API token and user. Run `dutyme start` first and save it.\n")
+			return ExitCodeError
+		}
+
+		dutyme, err := c.Meta.NewDutyme(cfg)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		d.AutoExtender = &daemon.AutoExtender{
+			Dutyme: dutyme,
+			User:   cfg.User,
+			Step:   daemon.DefaultAutoExtendStep,
+			Max:    daemon.DefaultAutoExtendMax,
+		}
+
+		if a := cfg.AutoExtend; a != nil {
+			if a.Step > 0 {
+				d.AutoExtender.Step = time.Duration(a.Step)
+			}
+			if a.Max > 0 {
+				d.AutoExtender.Max = time.Duration(a.Max)
+			}
+		}
+
+		fmt.Fprintf(c.OutStream, "Auto-extend overrides by %s while your incidents are open (up to %s)\n",
+			d.AutoExtender.Step, d.AutoExtender.Max)
+	}
+
+	stop := make(chan struct{})
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		close(stop)
+	}()
+
+	if !noAPI {
+		closeAPI, err := c.serveAPI(cfg)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to serve control API: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		defer closeAPI()
+	}
+
+	fmt.Fprintf(c.OutStream, "Start watching overrides in %s\n", journal.Path)
+	if err := d.Run(stop); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to run daemon: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	return ExitCodeOK
+}
+
+// serveAPI starts serving the control API in background and returns the
+// function to stop it. If API token or user is not configured, it only
+// logs that the API is disabled.
+func (c *DaemonCommand) serveAPI(cfg *config.Config) (func(), error) {
+	if len(cfg.Token) == 0 || cfg.User == nil {
+		fmt.Fprintf(c.OutStream, "Control API is disabled (run `dutyme start` and save user first)\n")
+		return func() {}, nil
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create dutyme")
+	}
+
+	path, err := c.Meta.SocketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to create data directory")
+	}
+
+	l, err := api.Listen(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &api.Server{
+		Dutyme:     dutyme,
+		User:       cfg.User,
+		ScheduleID: cfg.ScheduleID,
+		Extend:     extendTime(cfg),
+	}
+
+	go func() {
+		// Serve returns error when listener is closed on exit.
+		if err := s.Serve(l); err != nil {
+			Debugf("Control API stopped: %s", err)
+		}
+	}()
+
+	fmt.Fprintf(c.OutStream, "Serving control API on %s\n", path)
+	return func() {
+		l.Close()
+		os.Remove(path)
+	}, nil
+}
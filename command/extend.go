@@ -0,0 +1,124 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tcnksm/dutyme/api"
+	"github.com/tcnksm/dutyme/config"
+)
+
+const (
+	// DefaultExtendTime is default duration of one-step extend.
+	DefaultExtendTime = 30 * time.Minute
+)
+
+type ExtendCommand struct {
+	Meta
+}
+
+func (c *ExtendCommand) Synopsis() string {
+	return "Extend your override created by dutyme"
+}
+
+func (c *ExtendCommand) Help() string {
+	helpText := `Usage: dutyme extend [options...]
+
+extend extends the override created by dutyme. By default, it extends the
+override which ends first by 30 minutes (or the duration configured by
+"reminder.extend" in configuration file).
+
+Options:
+
+  -by TIME       Duration to extend.
+
+  -override ID   ID of the override to extend.
+
+When daemon is running, extend is requested to it via its control API.
+
+`
+	return helpText
+}
+
+func (c *ExtendCommand) Run(args []string) int {
+
+	var (
+		by         time.Duration
+		overrideID string
+	)
+
+	flags := c.Meta.NewFlagSet("extend", c.Help())
+
+	flags.DurationVar(&by, "by", 0, "")
+	flags.StringVar(&overrideID, "override", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	// When daemon is running, it extends the override on behalf of you.
+	if client := c.Meta.APIClient(); client != nil {
+		res, err := client.Extend(&api.ExtendRequest{
+			OverrideID: overrideID,
+			By:         config.Duration(by),
+		})
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to extend override: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		fmt.Fprintf(c.OutStream, "Successfuly extended override %s to %s (new override %s)\n",
+			res.Event.PreviousID, res.Event.End.Local().Format(TimeFmt), res.Event.OverrideID)
+		return ExitCodeOK
+	}
+
+	cfg, _, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if by == 0 {
+		by = extendTime(cfg)
+	}
+
+	if cfg.User == nil {
+		fmt.Fprintf(c.ErrStream, "No user is configured. Run `dutyme start` first and save it.\n")
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	target, err := dutyme.FindActive(overrideID)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to find override: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	override, end, err := dutyme.Extend(*target, cfg.User, by)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to extend override: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	fmt.Fprintf(c.OutStream, "Successfuly extended override %s to %s (new override %s)\n",
+		target.OverrideID, end.Format(TimeFmt), override.ID)
+	return ExitCodeOK
+}
+
+// extendTime returns the duration of one-step extend.
+func extendTime(cfg *config.Config) time.Duration {
+	if cfg.Reminder != nil && cfg.Reminder.Extend > 0 {
+		return time.Duration(cfg.Reminder.Extend)
+	}
+	return DefaultExtendTime
+}
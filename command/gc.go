@@ -0,0 +1,201 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tcnksm/dutyme/state"
+	input "github.com/tcnksm/go-input"
+)
+
+type GCCommand struct {
+	Meta
+}
+
+func (c *GCCommand) Synopsis() string {
+	return "Clean up overrides left by killed dutyme processes"
+}
+
+func (c *GCCommand) Help() string {
+	helpText := `Usage: dutyme gc [options...]
+
+gc detects orphaned sessions, i.e., the overrides held by dutyme
+processes (e.g., hold and run) which were killed and couldn't clean them
+up, and offers to truncate them. Overrides which have already started
+are truncated to end now and the others are deleted. Sessions are
+recorded on ~/.dutyme.d/sessions.json (hold) and the lease file (run).
+
+Options:
+
+  -dry-run   Only show the orphaned sessions.
+
+  -force     Truncate all orphaned overrides without confirmation.
+
+`
+	return helpText
+}
+
+func (c *GCCommand) Run(args []string) int {
+
+	var (
+		dryRun bool
+		force  bool
+	)
+
+	flags := c.Meta.NewFlagSet("gc", c.Help())
+
+	flags.BoolVar(&dryRun, "dry-run", false, "")
+	flags.BoolVar(&force, "force", false, "")
+	flags.BoolVar(&force, "f", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	st, err := c.Meta.State()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read state file: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	sessions, err := st.Sessions()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read sessions: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	leases, err := c.Meta.Leases(nil)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read lease file: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	groups, err := leases.Orphaned()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read leases: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	host, _ := os.Hostname()
+	orphaned := state.Orphaned(sessions, host, time.Now())
+	if len(orphaned) == 0 && len(groups) == 0 {
+		fmt.Fprintln(c.OutStream, "No orphaned session is found")
+		return ExitCodeOK
+	}
+
+	for _, s := range orphaned {
+		fmt.Fprintf(c.OutStream, "Orphaned session: %s (pid %d) holds override %s on schedule %q until %s\n",
+			s.Command, s.PID, s.OverrideID, sessionScheduleName(s), s.End.Local().Format(TimeFmt))
+	}
+
+	for _, g := range groups {
+		fmt.Fprintf(c.OutStream, "Orphaned session: run (all holders are gone) holds override %s on schedule %q until %s\n",
+			g.OverrideID, g.ScheduleID, g.End.Local().Format(TimeFmt))
+	}
+
+	if dryRun {
+		return ExitCodeOK
+	}
+
+	cfg, _, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	leases.Dutyme = dutyme
+
+	exitCode := ExitCodeOK
+	for _, s := range orphaned {
+		if ok, err := c.confirm(s.OverrideID, force); err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to ask: %s\n", err)
+			return ExitCodeError
+		} else if !ok {
+			continue
+		}
+
+		if err := dutyme.DeleteOverride(s.ScheduleID, s.OverrideID); err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to truncate override %s: %s\n", s.OverrideID, err)
+			TracePrint(c.ErrStream, err)
+			exitCode = ExitCodeError
+			continue
+		}
+
+		if err := st.Remove(s.Host, s.PID); err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to remove session: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			exitCode = ExitCodeError
+			continue
+		}
+
+		fmt.Fprintf(c.OutStream, "Successfuly truncated override %s\n", s.OverrideID)
+	}
+
+	for _, g := range groups {
+		if ok, err := c.confirm(g.OverrideID, force); err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to ask: %s\n", err)
+			return ExitCodeError
+		} else if !ok {
+			continue
+		}
+
+		if err := leases.Stop(g.OverrideID); err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to truncate override %s: %s\n", g.OverrideID, err)
+			TracePrint(c.ErrStream, err)
+			exitCode = ExitCodeError
+			continue
+		}
+
+		fmt.Fprintf(c.OutStream, "Successfuly truncated override %s\n", g.OverrideID)
+	}
+
+	return exitCode
+}
+
+// confirm asks whether to truncate the override unless force is true.
+func (c *GCCommand) confirm(overrideID string, force bool) (bool, error) {
+	if force {
+		return true, nil
+	}
+
+	query := fmt.Sprintf("Truncate override %s? [Y/n]", overrideID)
+	ans, err := c.Meta.UI.Ask(query, &input.Options{
+		Default:     "Y",
+		Loop:        true,
+		HideOrder:   true,
+		HideDefault: true,
+		ValidateFunc: func(s string) error {
+			if s != "Y" && s != "y" && s != "N" && s != "n" {
+				return fmt.Errorf("input must be Y or n")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return ans != "N" && ans != "n", nil
+}
+
+// sessionScheduleName returns the schedule name of the session. If it's
+// not recorded, schedule ID is returned instead.
+func sessionScheduleName(s state.Session) string {
+	if len(s.ScheduleName) != 0 {
+		return s.ScheduleName
+	}
+	return s.ScheduleID
+}
@@ -0,0 +1,241 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tcnksm/dutyme/githook"
+)
+
+type GitHookCommand struct {
+	Meta
+}
+
+func (c *GitHookCommand) Synopsis() string {
+	return "Take on-call automatically when pushing to deploy branches"
+}
+
+func (c *GitHookCommand) Help() string {
+	helpText := `Usage: dutyme git-hook install [options...]
+       dutyme git-hook uninstall
+
+git-hook installs git pre-push hook to the repository in the current
+directory. When the pushed branch matches the deploy branch patterns,
+the hook overrides the schedule from now without any prompt (saved
+API token and user are used). If overriding fails, push is aborted
+(use "git push --no-verify" to skip it). If you already have an
+active override on the schedule which covers the duration, nothing
+is created.
+
+The existing pre-push hook is kept and executed after dutyme. The
+settings are stored in the repository local git config (dutyme.*).
+
+uninstall removes the hook and the settings and restores the existing
+pre-push hook.
+
+Options (install):
+
+  -branch PATTERNS  Comma separated patterns of deploy branches
+                    (e.g., "release/*,main"). By default, it's
+                    "release/*".
+
+  -schedule ID      PagerDuty schedule ID to override. By default,
+                    the schedule saved by start command is used.
+
+  -working TIME     Duration of the override. By default, it's 1h.
+
+  -command PATH     dutyme command executed by the hook. By default,
+                    it's "dutyme" (found in PATH).
+
+`
+	return helpText
+}
+
+func (c *GitHookCommand) Run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: install or uninstall is required\n")
+		return ExitCodeError
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to get working directory: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	switch args[0] {
+	case "install":
+		return c.install(wd, args[1:])
+	case "uninstall":
+		return c.uninstall(wd)
+	case "run":
+		// run is executed by the installed hook.
+		return c.run(wd)
+	default:
+		fmt.Fprintf(c.ErrStream, "Invalid argument: unknown subcommand %q\n", args[0])
+		return ExitCodeError
+	}
+}
+
+func (c *GitHookCommand) install(wd string, args []string) int {
+
+	var (
+		branches   string
+		scheduleID string
+		working    time.Duration
+		command    string
+	)
+
+	flags := c.Meta.NewFlagSet("git-hook", c.Help())
+
+	flags.StringVar(&branches, "branch", "release/*", "")
+	flags.StringVar(&scheduleID, "schedule", "", "")
+	flags.DurationVar(&working, "working", githook.DefaultWorking, "")
+	flags.StringVar(&command, "command", "dutyme", "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	if working <= 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: -working must be positive value\n")
+		return ExitCodeError
+	}
+
+	dir, err := githook.HooksDir(wd)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to find git hooks directory: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	cfg := &githook.Config{
+		ScheduleID: scheduleID,
+		Working:    working,
+	}
+	for _, b := range strings.Split(branches, ",") {
+		if b = strings.TrimSpace(b); len(b) != 0 {
+			cfg.Branches = append(cfg.Branches, b)
+		}
+	}
+
+	if len(cfg.Branches) == 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: -branch must not be empty\n")
+		return ExitCodeError
+	}
+
+	if err := githook.WriteConfig(wd, cfg); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to save git-hook settings: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if err := githook.Install(dir, command); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to install hook: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	fmt.Fprintf(c.OutStream, "Installed %s hook: pushing to %s takes on-call for %s\n",
+		githook.HookName, strings.Join(cfg.Branches, ", "), cfg.Working)
+	return ExitCodeOK
+}
+
+func (c *GitHookCommand) uninstall(wd string) int {
+	dir, err := githook.HooksDir(wd)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to find git hooks directory: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if err := githook.Uninstall(dir); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to uninstall hook: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+	githook.RemoveConfig(wd)
+
+	fmt.Fprintf(c.OutStream, "Uninstalled %s hook\n", githook.HookName)
+	return ExitCodeOK
+}
+
+// run overrides the schedule when the pushed branch (read from stdin)
+// matches the deploy branches. It never prompts.
+func (c *GitHookCommand) run(wd string) int {
+	hookCfg, err := githook.ReadConfig(wd)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read git-hook settings: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	refs, err := githook.ParseRefs(c.UI.Reader)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read pushed refs: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	branch, ok := githook.Match(hookCfg.Branches, refs)
+	if !ok {
+		return ExitCodeOK
+	}
+
+	// Token is never asked because stdin is used by git.
+	cfg, _, err := c.Meta.ReadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	scheduleID, scheduleName := hookCfg.ScheduleID, hookCfg.ScheduleID
+	if len(scheduleID) == 0 {
+		scheduleID, scheduleName = cfg.ScheduleID, cfg.ScheduleName
+	}
+
+	if len(cfg.Token) == 0 || cfg.User == nil || len(scheduleID) == 0 {
+		fmt.Fprintf(c.ErrStream, "No API token, user or schedule is configured. Run `dutyme start` first and save it.\n")
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	// Pushed branch satisfies the reason required by configuration.
+	dutyme.Reason = fmt.Sprintf("git push %s", branch)
+
+	start := time.Now()
+	end := start.Add(hookCfg.Working)
+
+	// Pushing again during the deploy doesn't stack overrides.
+	if active, err := dutyme.ActiveOverridesFor(cfg.User); err == nil {
+		for _, e := range active {
+			if e.ScheduleID == scheduleID && !e.End.Before(end) {
+				fmt.Fprintf(c.ErrStream, "dutyme: you are already on call for schedule %q until %s\n",
+					scheduleName, e.End.Format(TimeFmt))
+				return ExitCodeOK
+			}
+		}
+	}
+
+	override, err := dutyme.Override(scheduleID, cfg.User, start, end, true)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to override: %s\n", err)
+		fmt.Fprintf(c.ErrStream, "Push is aborted. Use `git push --no-verify` to push without taking on-call.\n")
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	fmt.Fprintf(c.ErrStream, "dutyme: pushing to %s, you are on call for schedule %q until %s (%s)\n",
+		branch, scheduleName, end.Format(TimeFmt), override.ID)
+	return ExitCodeOK
+}
@@ -0,0 +1,11 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestGitHookCommand_implement(t *testing.T) {
+	var _ cli.Command = &GitHookCommand{}
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/tcnksm/dutyme/journal"
+)
+
+// DefaultHistoryNum is default number of events shown by history.
+const DefaultHistoryNum = 20
+
+type HistoryCommand struct {
+	Meta
+}
+
+func (c *HistoryCommand) Synopsis() string {
+	return "Show history of overrides created by dutyme"
+}
+
+func (c *HistoryCommand) Help() string {
+	helpText := `Usage: dutyme history [options...]
+
+history shows the events (start, extend and stop) of the overrides
+created by dutyme recorded on the journal (newest last).
+
+When daemon is running, history is requested to it via its control API.
+
+Options:
+
+  -n N   Number of events to show. By default, it's 20.
+         If it's 0, all events are shown.
+
+`
+	return helpText
+}
+
+func (c *HistoryCommand) Run(args []string) int {
+
+	var num int
+
+	flags := c.Meta.NewFlagSet("history", c.Help())
+	flags.IntVar(&num, "n", DefaultHistoryNum, "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	var events []journal.Event
+	if client := c.Meta.APIClient(); client != nil {
+		res, err := client.History()
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to read history: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		events = res.Events
+	} else {
+		j, err := c.Meta.Journal()
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to read journal: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		events, err = j.Events()
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to read history: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+	}
+
+	if num > 0 && len(events) > num {
+		events = events[len(events)-num:]
+	}
+
+	for _, e := range events {
+		fmt.Fprintf(c.OutStream, "%s  %-6s  %s  %-20s", e.Time.Local().Format(TimeFmt),
+			e.Action, e.OverrideID, scheduleName(e))
+
+		// Stop event doesn't have override time.
+		if e.Action != journal.ActionStop {
+			fmt.Fprintf(c.OutStream, "  %s - %s",
+				e.Start.Local().Format(TimeFmt), e.End.Local().Format(TimeFmt))
+		}
+
+		switch {
+		case len(e.Ticket) != 0 && len(e.Reason) != 0:
+			fmt.Fprintf(c.OutStream, "  [%s] %s", e.Ticket, e.Reason)
+		case len(e.Ticket) != 0:
+			fmt.Fprintf(c.OutStream, "  [%s]", e.Ticket)
+		case len(e.Reason) != 0:
+			fmt.Fprintf(c.OutStream, "  %s", e.Reason)
+		}
+		fmt.Fprintln(c.OutStream)
+	}
+
+	return ExitCodeOK
+}
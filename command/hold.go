@@ -0,0 +1,184 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tcnksm/dutyme/hold"
+)
+
+type HoldCommand struct {
+	Meta
+}
+
+func (c *HoldCommand) Synopsis() string {
+	return "Keep you on call while heartbeat continues"
+}
+
+func (c *HoldCommand) Help() string {
+	helpText := `Usage: dutyme hold [options...]
+
+hold keeps you on call of the configured schedule by short rolling
+overrides (15 minutes segments by default) as long as the heartbeat
+continues. Each segment is renewed ahead of its end. It's useful when
+you can't guess how long your work takes (e.g., long migration).
+
+Heartbeat is the hold process itself by default. You can also use
+the existence of the process (-pid) or the heartbeat file which keeps
+being touched (-file). When heartbeat is lost (or your laptop dies),
+the segment is not renewed and on-call goes back to the rotation when
+it ends.
+
+Press Ctrl-C to stop holding (the current override is stopped then).
+If hold is killed and can't stop the override, it's left as orphaned
+session on ~/.dutyme.d/sessions.json. Run gc command to clean it up.
+
+Options:
+
+  -segment TIME  Duration of one override segment. By default, it's
+                 15 minutes.
+
+  -ahead TIME    How long before the segment ends it's renewed.
+                 By default, it's 5 minutes.
+
+  -pid PID       Hold while the process of PID exists.
+
+  -file PATH     Hold while the file keeps being touched.
+
+  -stale TIME    Duration after which the heartbeat file is regarded as
+                 stale (used with -file). By default, it's 5 minutes.
+
+  -reason TEXT   Why you hold on-call. It's recorded on journal and passed
+                 to hooks and notifiers (may be required by configuration).
+
+  -ticket ID     Ticket of the work (e.g., "OPS-123").
+
+  -force         Override the schedule protected by "policy" in
+                 configuration file (reason is also required).
+
+`
+	return helpText
+}
+
+func (c *HoldCommand) Run(args []string) int {
+
+	var (
+		segment time.Duration
+		ahead   time.Duration
+		pid     int
+		file    string
+		stale   time.Duration
+		reason  string
+		ticket  string
+		force   bool
+	)
+
+	flags := c.Meta.NewFlagSet("hold", c.Help())
+
+	flags.DurationVar(&segment, "segment", hold.DefaultSegment, "")
+	flags.DurationVar(&ahead, "ahead", hold.DefaultAhead, "")
+	flags.IntVar(&pid, "pid", 0, "")
+	flags.StringVar(&file, "file", "", "")
+	flags.DurationVar(&stale, "stale", hold.DefaultStale, "")
+	flags.StringVar(&reason, "reason", "", "")
+	flags.StringVar(&ticket, "ticket", "", "")
+	flags.BoolVar(&force, "force", false, "")
+	flags.BoolVar(&force, "f", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	if segment <= 0 || ahead <= 0 || ahead >= segment {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: -ahead must be positive and shorter than -segment\n")
+		return ExitCodeError
+	}
+
+	if pid != 0 && file != "" {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: -pid and -file can't be used together\n")
+		return ExitCodeError
+	}
+
+	c.Meta.WarnOrphaned()
+
+	cfg, _, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if cfg.User == nil || cfg.ScheduleID == "" {
+		fmt.Fprintf(c.ErrStream, "No user or schedule is configured. Run `dutyme start` first and save it.\n")
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+	dutyme.Reason, dutyme.Ticket = reason, ticket
+	dutyme.Force = force
+
+	if err := c.Meta.askReason(dutyme, cfg.ScheduleID); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to ask reason: %s\n", err)
+		return ExitCodeError
+	}
+
+	if err := dutyme.CheckReason(cfg.ScheduleID); err != nil {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: %s\n", err)
+		return ExitCodeError
+	}
+
+	st, err := c.Meta.State()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read state file: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	holder := &hold.Holder{
+		Dutyme:       dutyme,
+		User:         cfg.User,
+		ScheduleID:   cfg.ScheduleID,
+		ScheduleName: cfg.ScheduleName,
+		Segment:      segment,
+		Ahead:        ahead,
+		Log:          c.OutStream,
+		State:        st,
+	}
+
+	switch {
+	case pid != 0:
+		holder.Heartbeat = &hold.ProcessHeartbeat{PID: pid}
+	case file != "":
+		holder.Heartbeat = &hold.FileHeartbeat{Path: file, Stale: stale}
+	}
+
+	stop := make(chan struct{})
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		close(stop)
+	}()
+
+	fmt.Fprintf(c.OutStream, "Hold schedule %q by user %q (press Ctrl-C to stop)\n",
+		cfg.ScheduleName, cfg.User.Email)
+	if err := holder.Run(stop); err != nil {
+		if err == hold.ErrHeartbeatLost {
+			return ExitCodeOK
+		}
+
+		fmt.Fprintf(c.ErrStream, "Failed to hold schedule: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	return ExitCodeOK
+}
@@ -7,8 +7,23 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/api"
+	"github.com/tcnksm/dutyme/config"
+	"github.com/tcnksm/dutyme/daemon"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/finder"
+	"github.com/tcnksm/dutyme/hook"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/lease"
+	"github.com/tcnksm/dutyme/notify"
+	"github.com/tcnksm/dutyme/state"
+	"github.com/tcnksm/dutyme/term"
 	input "github.com/tcnksm/go-input"
 )
 
@@ -17,6 +32,10 @@ const (
 
 	EnvDebug = "DUTYME_DEBUG"
 	EnvTrace = "DUTYME_TRACE"
+
+	// EnvXDGConfigHome is the base directory of user's configuration
+	// files (XDG Base Directory Specification).
+	EnvXDGConfigHome = "XDG_CONFIG_HOME"
 )
 
 const (
@@ -26,6 +45,29 @@ const (
 
 const (
 	DefaultConfigName = ".dutyme.json"
+
+	// DefaultDataDirName is the directory where dutyme stores its
+	// data (e.g., journal of overrides).
+	DefaultDataDirName = ".dutyme.d"
+
+	// JournalName is the file name of the journal in data directory.
+	JournalName = "journal.jsonl"
+
+	// FeedName is the file name of the reminder feed in data directory.
+	// daemon writes reminders to it and watch follows it.
+	FeedName = "reminders.jsonl"
+
+	// SocketName is the file name of the Unix domain socket in data
+	// directory where daemon serves the control API.
+	SocketName = "dutyme.sock"
+
+	// StateName is the file name of the state file of in-flight
+	// sessions (e.g., hold) in data directory.
+	StateName = "sessions.json"
+
+	// LeaseName is the file name of the lease file of shared
+	// overrides (used by run) in data directory.
+	LeaseName = "leases.json"
 )
 
 var (
@@ -49,24 +91,529 @@ type Meta struct {
 	ErrStream io.Writer
 
 	UI *input.UI
+
+	// Finder is used for selection prompts. When the terminal
+	// doesn't support raw mode, UI is used instead.
+	Finder *finder.Finder
 }
 
 func (m *Meta) NewFlagSet(name, usage string) *flag.FlagSet {
 	flags := flag.NewFlagSet(name, flag.ContinueOnError)
 	flags.SetOutput(m.ErrStream)
 	flags.Usage = func() {
-		fmt.Fprintf(m.OutStream, usage+"\n")
+		fmt.Fprintln(m.OutStream, usage)
 	}
 	return flags
 }
 
+// ConfigPath returns the path of user's configuration file. It's
+// config.{json,yaml,yml,toml} in $XDG_CONFIG_HOME/dutyme (~/.config/dutyme
+// by default) or legacy ~/.dutyme.{json,yaml,yml,toml}. If neither exists,
+// the new file is $XDG_CONFIG_HOME/dutyme/config.json when XDG_CONFIG_HOME
+// is set and ~/.dutyme.json otherwise.
 func (m *Meta) ConfigPath() (string, error) {
 	home, err := homedir.Dir()
 	if err != nil {
 		return "", err
 	}
 
-	return filepath.Join(home, DefaultConfigName), nil
+	xdg := os.Getenv(EnvXDGConfigHome)
+	xdgDir := xdg
+	if len(xdgDir) == 0 {
+		xdgDir = filepath.Join(home, ".config")
+	}
+
+	if path := config.FindFile(filepath.Join(xdgDir, "dutyme", "config")); len(path) != 0 {
+		return path, nil
+	}
+
+	legacy := filepath.Join(home, DefaultConfigName)
+	if path := config.FindFile(strings.TrimSuffix(legacy, filepath.Ext(legacy))); len(path) != 0 {
+		return path, nil
+	}
+
+	if len(xdg) != 0 {
+		return filepath.Join(xdg, "dutyme", "config.json"), nil
+	}
+	return legacy, nil
+}
+
+// DataDir returns the directory where dutyme stores its data.
+func (m *Meta) DataDir() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, DefaultDataDirName), nil
+}
+
+// Journal returns the journal of overrides created by dutyme.
+func (m *Meta) Journal() (*journal.Journal, error) {
+	dir, err := m.DataDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read data directory path")
+	}
+
+	return &journal.Journal{
+		Path: filepath.Join(dir, JournalName),
+	}, nil
+}
+
+// Feed returns the reminder feed.
+func (m *Meta) Feed() (*daemon.FeedNotifier, error) {
+	dir, err := m.DataDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read data directory path")
+	}
+
+	return &daemon.FeedNotifier{
+		Path: filepath.Join(dir, FeedName),
+	}, nil
+}
+
+// State returns the state file of in-flight sessions.
+func (m *Meta) State() (*state.State, error) {
+	dir, err := m.DataDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read data directory path")
+	}
+
+	return &state.State{
+		Path: filepath.Join(dir, StateName),
+	}, nil
+}
+
+// Leases returns the manager of leases of shared overrides.
+func (m *Meta) Leases(d *dutyme.Dutyme) (*lease.Manager, error) {
+	dir, err := m.DataDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read data directory path")
+	}
+
+	return &lease.Manager{
+		Path:   filepath.Join(dir, LeaseName),
+		Dutyme: d,
+	}, nil
+}
+
+// WarnOrphaned warns when there are sessions whose owning process is
+// gone (e.g., killed by SIGKILL) but whose overrides are not ended yet.
+// Failing to check is ignored since it's only a hint.
+func (m *Meta) WarnOrphaned() {
+	s, err := m.State()
+	if err != nil {
+		return
+	}
+
+	sessions, err := s.Sessions()
+	if err != nil {
+		Debugf("Failed to read sessions: %s", err)
+		return
+	}
+
+	host, _ := os.Hostname()
+	count := len(state.Orphaned(sessions, host, time.Now()))
+
+	if leases, err := m.Leases(nil); err == nil {
+		if groups, err := leases.Orphaned(); err == nil {
+			count += len(groups)
+		}
+	}
+
+	if count > 0 {
+		fmt.Fprintf(m.ErrStream, "WARNING: found %d orphaned session(s) whose process is gone. Run `dutyme gc` to clean up the overrides.\n",
+			count)
+	}
+}
+
+// SocketPath returns the path of the control API socket.
+func (m *Meta) SocketPath() (string, error) {
+	dir, err := m.DataDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read data directory path")
+	}
+
+	return filepath.Join(dir, SocketName), nil
+}
+
+// APIClient returns the control API client when daemon is running and
+// serving the API. If not, it returns nil and caller should operate
+// overrides by itself.
+func (m *Meta) APIClient() *api.Client {
+	path, err := m.SocketPath()
+	if err != nil {
+		return nil
+	}
+
+	client := &api.Client{SocketPath: path}
+	if err := client.Ping(); err != nil {
+		return nil
+	}
+
+	Debugf("Use daemon via %s", path)
+	return client
+}
+
+// ReadConfig reads and merges the configuration layers (see Layers).
+// The returned bool is true if user's configuration file exists.
+func (m *Meta) ReadConfig() (*config.Config, bool, error) {
+	layers, exists, err := m.Layers()
+	if err != nil {
+		return nil, false, err
+	}
+
+	cfg, err := layers.Config()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to merge configuration")
+	}
+
+	return cfg, exists, nil
+}
+
+// Layers reads the configuration layers in the order of precedence:
+// system-wide configuration, user's configuration file, project
+// configuration and DUTYME_* env vars (command line flags are applied
+// by each command). The returned bool is true if user's configuration
+// file exists.
+func (m *Meta) Layers() (*config.Layered, bool, error) {
+	layers := &config.Layered{}
+
+	if err := layers.AddFile(config.OriginSystem, config.SystemPath()); err != nil {
+		return nil, false, errors.Wrap(err, "failed to read system configuration file")
+	}
+
+	cfgPath, err := m.ConfigPath()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to read config path")
+	}
+
+	exists := false
+	if _, err := os.Stat(cfgPath); err == nil {
+		Debugf("Use existing configuration file: %s", cfgPath)
+		exists = true
+	}
+
+	// User's configuration file of older format is upgraded in place.
+	// System and project ones are migrated only in memory because they
+	// are owned by others.
+	backup, err := config.MigrateFile(cfgPath)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to migrate configuration file")
+	}
+
+	if len(backup) != 0 {
+		fmt.Fprintf(m.ErrStream, "Upgraded configuration file %s to format version %d (backup: %s)\n",
+			cfgPath, config.Version, backup)
+	}
+
+	if err := layers.AddFile(config.OriginUser, cfgPath); err != nil {
+		return nil, false, errors.Wrap(err, "failed to parse configuration file")
+	}
+
+	// Project configuration in the repository is merged over
+	// user's configuration.
+	projectPath, err := m.ProjectConfigPath()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to find project configuration")
+	}
+
+	if len(projectPath) != 0 {
+		Debugf("Use project configuration file: %s", projectPath)
+		if err := layers.AddFile(config.OriginProject, projectPath); err != nil {
+			return nil, false, errors.Wrap(err, "failed to parse project configuration file")
+		}
+
+		if path, keys := layers.Untrusted(); len(keys) != 0 {
+			fmt.Fprintf(m.ErrStream, "WARNING: ignored %s of project configuration %s which is not trusted. Run `dutyme config trust` if you trust it.\n",
+				strings.Join(keys, ", "), path)
+		}
+	}
+
+	if err := layers.AddEnv(os.Environ()); err != nil {
+		return nil, false, errors.Wrap(err, "failed to read env vars")
+	}
+
+	// Override API token via env var if exsits (DUTYME_TOKEN is preferred).
+	if v := os.Getenv(EnvToken); len(v) != 0 && len(os.Getenv(config.EnvName("token"))) == 0 {
+		Debugf("Read PD API token from env var: %s", v)
+		layers.Set(config.OriginEnv, "token", v)
+	}
+
+	return layers, exists, nil
+}
+
+// ProjectConfigPath returns the path of the project configuration found
+// from the current directory. If it's not found, it returns empty.
+func (m *Meta) ProjectConfigPath() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	cfgPath, err := m.ConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return config.FindProject(wd, cfgPath)
+}
+
+// SaveConfig saves token, user and schedule of the given configuration
+// to user's configuration file and returns its path. The other settings
+// in the file are kept as they are, and the schedule is not saved when
+// it comes from the other layers (e.g., project configuration).
+func (m *Meta) SaveConfig(cfg *config.Config) (string, error) {
+	cfgPath, err := m.ConfigPath()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read config path")
+	}
+
+	saved := &config.Config{Token: cfg.Token, User: cfg.User}
+	keys := []string{"token", "user"}
+
+	fromOther := false
+	if layers, _, err := m.Layers(); err == nil {
+		if v, ok := layers.Origin("schedule_id"); ok && v.Origin != config.OriginUser {
+			fromOther = v.Value == cfg.ScheduleID
+		}
+	}
+
+	if !fromOther {
+		saved.ScheduleID = cfg.ScheduleID
+		saved.ScheduleName = cfg.ScheduleName
+		keys = append(keys, "schedule_id", "schedule_name")
+	}
+
+	if err := saved.UpdateFile(cfgPath, keys...); err != nil {
+		return "", errors.Wrap(err, "failed to save file")
+	}
+
+	return cfgPath, nil
+}
+
+// resolveWorking returns the duration of override. The value given by
+// -working flag is preferred over "working" in configuration.
+func resolveWorking(flags *flag.FlagSet, value time.Duration, cfg *config.Config) time.Duration {
+	if !flagGiven(flags, "working") && cfg != nil && cfg.Working > 0 {
+		return time.Duration(cfg.Working)
+	}
+	return value
+}
+
+// flagGiven returns true if the flag of the given name is set on the
+// command line (not default value).
+func flagGiven(flags *flag.FlagSet, name string) bool {
+	given := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
+}
+
+// LoadConfig reads the configuration by ReadConfig. If API token is not
+// set in both the file and env var, it asks the token.
+func (m *Meta) LoadConfig() (*config.Config, bool, error) {
+	cfg, exists, err := m.ReadConfig()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(cfg.Token) == 0 {
+		token, err := m.AskToken()
+		if err != nil {
+			return nil, false, errors.Wrap(err, "failed to ask API token")
+		}
+		cfg.Token = token
+	}
+
+	return cfg, exists, nil
+}
+
+// NewDutyme creates Dutyme with PagerDuty HTTP client and the notifiers
+// configured in the given configuration.
+func (m *Meta) NewDutyme(cfg *config.Config) (*dutyme.Dutyme, error) {
+	pd, err := dutyme.NewPDClient(cfg.Token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create PD HTTP client")
+	}
+
+	journal, err := m.Journal()
+	if err != nil {
+		return nil, err
+	}
+
+	reason, err := reasonPolicy(cfg.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := newPolicy(cfg.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dutyme.Dutyme{
+		UI:           m.UI,
+		PD:           pd,
+		Finder:       m.Finder,
+		Journal:      journal,
+		Notifiers:    newNotifiers(cfg.Notify),
+		Hooks:        m.Hooks(cfg),
+		ReasonPolicy: reason,
+		Policy:       policy,
+	}, nil
+}
+
+// reasonPolicy returns the policy of reason configured by the given
+// configuration. If it's not configured, it returns nil.
+func reasonPolicy(cfg *config.Reason) (*dutyme.ReasonPolicy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	policy := &dutyme.ReasonPolicy{
+		Required:  cfg.Required,
+		Schedules: cfg.Schedules,
+	}
+
+	if len(cfg.TicketPattern) != 0 {
+		pattern, err := regexp.Compile(cfg.TicketPattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid reason.ticket_pattern")
+		}
+		policy.TicketPattern = pattern
+	}
+
+	return policy, nil
+}
+
+// newPolicy returns the policy configured by the given configuration.
+// If it's not configured, it returns nil.
+func newPolicy(cfg *config.Policy) (*dutyme.Policy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	policy := &dutyme.Policy{
+		MaxDuration: time.Duration(cfg.MaxDuration),
+		Protected:   cfg.Protected,
+	}
+
+	if len(cfg.Hours) != 0 {
+		hours, err := dutyme.ParseHours(cfg.Hours)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid policy.hours")
+		}
+		policy.Hours = hours
+	}
+
+	for _, w := range cfg.Weekdays {
+		day, err := dutyme.ParseWeekday(w)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid policy.weekdays")
+		}
+		policy.Weekdays = append(policy.Weekdays, day)
+	}
+
+	if len(cfg.Timezone) != 0 {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid policy.timezone")
+		}
+		policy.Location = loc
+	}
+
+	return policy, nil
+}
+
+// requireReason makes reason required for all overrides by the given
+// dutyme (e.g., by preset) keeping the other policy.
+func requireReason(d *dutyme.Dutyme) {
+	if d.ReasonPolicy == nil {
+		d.ReasonPolicy = &dutyme.ReasonPolicy{}
+	}
+	d.ReasonPolicy.Required = true
+}
+
+// askReason asks reason when it's required for the schedules but not
+// given, only if user can answer (stdin is terminal). Otherwise, the
+// missing reason is reported by overriding.
+func (m *Meta) askReason(d *dutyme.Dutyme, scheduleIDs ...string) error {
+	if f, ok := m.UI.Reader.(*os.File); !ok || !term.IsTerminal(f) {
+		return nil
+	}
+	return d.AskReason(scheduleIDs...)
+}
+
+// Hooks returns the hook runner configured by the given configuration.
+// If no hook is configured, it returns nil.
+func (m *Meta) Hooks(cfg *config.Config) *hook.Runner {
+	h := cfg.Hooks
+	if h == nil {
+		return nil
+	}
+
+	commands := map[hook.Point]string{
+		hook.PreStart:     h.PreStart,
+		hook.PostStart:    h.PostStart,
+		hook.PreStop:      h.PreStop,
+		hook.PostStop:     h.PostStop,
+		hook.OnExpireSoon: h.OnExpireSoon,
+	}
+
+	for point, command := range commands {
+		if len(command) == 0 {
+			delete(commands, point)
+		}
+	}
+
+	if len(commands) == 0 {
+		return nil
+	}
+
+	return &hook.Runner{
+		Commands: commands,
+		Stdout:   m.OutStream,
+		Stderr:   m.ErrStream,
+	}
+}
+
+// newNotifiers returns the notifiers configured by the given configuration.
+func newNotifiers(cfg *config.Notify) []notify.Notifier {
+	if cfg == nil {
+		return nil
+	}
+
+	var notifiers []notify.Notifier
+	for _, w := range cfg.Webhooks {
+		notifiers = append(notifiers, &notify.WebhookNotifier{
+			URL:      w.URL,
+			Template: w.Template,
+		})
+	}
+
+	for _, s := range cfg.Slack {
+		notifiers = append(notifiers, &notify.SlackNotifier{
+			URL:     s.URL,
+			Channel: s.Channel,
+		})
+	}
+
+	if e := cfg.Email; e != nil {
+		notifiers = append(notifiers, &notify.EmailNotifier{
+			Addr:        e.Addr,
+			From:        e.From,
+			To:          e.To,
+			ToDisplaced: e.ToDisplaced,
+			Username:    e.Username,
+			Password:    e.Password,
+		})
+	}
+
+	return notifiers
 }
 
 func (m *Meta) AskToken() (string, error) {
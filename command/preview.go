@@ -0,0 +1,135 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+type PreviewCommand struct {
+	Meta
+}
+
+func (c *PreviewCommand) Synopsis() string {
+	return "Preview the final schedule when you override it"
+}
+
+func (c *PreviewCommand) Help() string {
+	helpText := `Usage: dutyme preview [options...]
+
+preview shows the timeline of the final schedule around the override
+window: who is on call before, during and after the window, which
+existing overrides are superseded and when nobody is on call. It's same
+as the one displayed on start confirmation but doesn't create anything.
+
+Options:
+
+  -working TIME  Working time (overriding time). By default, it's 1 hour.
+
+  -service NAME  Preview the schedules which are responsible for the given
+                 service instead of the saved schedule.
+
+  -level N       Escalation level which is used with -service. By default,
+                 it's 1.
+
+`
+	return helpText
+}
+
+func (c *PreviewCommand) Run(args []string) int {
+
+	var (
+		workingTime time.Duration
+
+		service string
+		level   int
+	)
+
+	flags := c.Meta.NewFlagSet("preview", c.Help())
+
+	flags.DurationVar(&workingTime, "working", DefaultWorkingTime, "")
+	flags.StringVar(&service, "service", "", "")
+	flags.IntVar(&level, "level", DefaultLevel, "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	if workingTime <= 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: -working must be positive value\n")
+		return ExitCodeError
+	}
+
+	cfg, _, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if cfg.User == nil {
+		user, err := dutyme.GetUser("")
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to get PagerDuty user: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		cfg.User = user
+	}
+
+	var schedules []pagerduty.APIObject
+	switch {
+	case service != "":
+		svc, err := dutyme.GetService(service)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to get PagerDuty service: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		chain, err := dutyme.ResolveService(svc.ID, level)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to resolve schedules from service: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		schedules = chain.Schedules
+	case cfg.ScheduleID != "":
+		schedules = []pagerduty.APIObject{
+			{ID: cfg.ScheduleID, Summary: cfg.ScheduleName},
+		}
+	default:
+		name, ID, err := dutyme.GetSchedule("")
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to get PagerDuty schedule: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		schedules = []pagerduty.APIObject{
+			{ID: ID, Summary: name},
+		}
+	}
+
+	start := time.Now()
+	end := start.Add(workingTime)
+
+	for _, schedule := range schedules {
+		preview, err := dutyme.Preview(schedule.ID, cfg.User, start, end)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to preview schedule %q: %s\n", schedule.Summary, err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		fmt.Fprintln(c.OutStream, preview.String())
+	}
+
+	return ExitCodeOK
+}
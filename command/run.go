@@ -0,0 +1,201 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type RunCommand struct {
+	Meta
+}
+
+func (c *RunCommand) Synopsis() string {
+	return "Run command while you are on call"
+}
+
+func (c *RunCommand) Help() string {
+	helpText := `Usage: dutyme run [options...] -- COMMAND [ARGS...]
+
+run assigns on-call of the configured schedule to you, runs the given
+command and hands on-call back when the command exits. It exits with
+the exit code of the command.
+
+Overrides created by run are shared leases: when other run processes on
+the same host (e.g., parallel deploy jobs on the same CI runner) already
+hold the override for the same user and schedule, run joins it instead
+of creating new one. The end of the override is extended to the latest
+requested one and the override is stopped only when the last holder
+leaves. Leases are coordinated via ~/.dutyme.d/leases.json.
+
+Options:
+
+  -working TIME  Working time (overriding time). By default, it's 1 hour
+                 (or "working" in configuration file).
+
+  -reason TEXT   Why you override. It's recorded on journal and passed
+                 to hooks and notifiers (may be required by configuration).
+
+  -ticket ID     Ticket of the work (e.g., "OPS-123").
+
+  -force         Override the schedule protected by "policy" in
+                 configuration file (reason is also required).
+
+`
+	return helpText
+}
+
+func (c *RunCommand) Run(args []string) int {
+
+	var (
+		workingTime time.Duration
+		reason      string
+		ticket      string
+		force       bool
+	)
+
+	flags := c.Meta.NewFlagSet("run", c.Help())
+	flags.DurationVar(&workingTime, "working", DefaultWorkingTime, "")
+	flags.StringVar(&reason, "reason", "", "")
+	flags.StringVar(&ticket, "ticket", "", "")
+	flags.BoolVar(&force, "force", false, "")
+	flags.BoolVar(&force, "f", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	cmdArgs := flags.Args()
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: COMMAND is required\n")
+		return ExitCodeError
+	}
+
+	if workingTime <= 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: -working must be positive value\n")
+		return ExitCodeError
+	}
+
+	c.Meta.WarnOrphaned()
+
+	cfg, _, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	workingTime = resolveWorking(flags, workingTime, cfg)
+
+	if cfg.User == nil || cfg.ScheduleID == "" {
+		fmt.Fprintf(c.ErrStream, "No user or schedule is configured. Run `dutyme start` first and save it.\n")
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+	dutyme.Reason, dutyme.Ticket = reason, ticket
+	dutyme.Force = force
+
+	if err := c.Meta.askReason(dutyme, cfg.ScheduleID); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to ask reason: %s\n", err)
+		return ExitCodeError
+	}
+
+	if err := dutyme.CheckReason(cfg.ScheduleID); err != nil {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: %s\n", err)
+		return ExitCodeError
+	}
+
+	leases, err := c.Meta.Leases(dutyme)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read leases: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	start := time.Now()
+	end := start.Add(workingTime)
+	if err := dutyme.CheckPolicy(cfg.ScheduleID, start, end); err != nil {
+		fmt.Fprintf(c.ErrStream, "Policy violation: %s\n", err)
+		return ExitCodeError
+	}
+
+	l, err := leases.Acquire(cfg.ScheduleID, cfg.User, end)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to override: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if l.Joined {
+		fmt.Fprintf(c.ErrStream, "Joined override %s on schedule %q until %s (%d holders)\n",
+			l.OverrideID, cfg.ScheduleName, l.End.Format(TimeFmt), len(l.Holders))
+	} else {
+		fmt.Fprintf(c.ErrStream, "Successfuly overrided schedule %q until %s (%s)\n",
+			cfg.ScheduleName, l.End.Format(TimeFmt), l.OverrideID)
+	}
+
+	exitCode := c.runCommand(cmdArgs)
+
+	released, err := leases.Release(l)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to release override: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if released {
+		fmt.Fprintf(c.ErrStream, "Successfuly stopped override %s\n", l.OverrideID)
+	} else {
+		fmt.Fprintf(c.ErrStream, "Left override %s (other holders still hold it)\n", l.OverrideID)
+	}
+
+	return exitCode
+}
+
+// runCommand runs the given command and returns its exit code. Signals
+// are forwarded to the command so that the lease is released after the
+// command exits.
+func (c *RunCommand) runCommand(args []string) int {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = c.OutStream
+	cmd.Stderr = c.ErrStream
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to run command: %s\n", err)
+		return ExitCodeError
+	}
+
+	go func() {
+		for sig := range sigCh {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	err := cmd.Wait()
+	if err == nil {
+		return ExitCodeOK
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus()
+		}
+	}
+
+	fmt.Fprintf(c.ErrStream, "Failed to run command: %s\n", err)
+	return ExitCodeError
+}
@@ -0,0 +1,133 @@
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/tcnksm/dutyme/mapping"
+	"github.com/tcnksm/dutyme/slack"
+)
+
+// EnvSlackSigningSecret is env var for Slack app signing secret.
+const EnvSlackSigningSecret = "DUTYME_SLACK_SIGNING_SECRET"
+
+// DefaultSlackAddr is default address where serve-slack listens.
+const DefaultSlackAddr = "127.0.0.1:8081"
+
+type ServeSlackCommand struct {
+	Meta
+}
+
+func (c *ServeSlackCommand) Synopsis() string {
+	return "Serve Slack slash-command endpoint for /dutyme"
+}
+
+func (c *ServeSlackCommand) Help() string {
+	helpText := fmt.Sprintf(`Usage: dutyme serve-slack [options...]
+
+serve-slack serves Slack slash-command (/dutyme) and interactive message
+endpoint. Set its URL as both slash-command request URL and interactivity
+request URL of your Slack app. It supports,
+
+  /dutyme start [DURATION] [SCHEDULE] [-ticket ID] [-reason TEXT...]
+  /dutyme stop [SCHEDULE]
+  /dutyme status   (with buttons to extend or stop each override)
+
+-reason and -ticket are required when the schedule requires reason
+("reason" in configuration file). -reason takes the rest of the text.
+
+Requests are verified by Slack app signing secret set via %q
+env var. Replies are ephemeral (only visible to you).
+
+Slack users are mapped to PagerDuty users by the mapping file (keys of
+"users" are Slack user IDs and keys of "schedules" are schedule aliases),
+
+  {
+    "users": {"U2147483697": "octocat@example.com"},
+    "schedules": {"checkout": "PI7DH85"},
+    "default_schedule": "PI7DH85"
+  }
+
+Options:
+
+  -listen ADDR    Address to listen. By default, it's %s.
+
+  -mapping PATH   Path to the mapping file (required).
+
+  -working TIME   Duration of override when start doesn't specify it.
+                  By default, it's 1 hour.
+
+`, EnvSlackSigningSecret, DefaultSlackAddr)
+	return helpText
+}
+
+func (c *ServeSlackCommand) Run(args []string) int {
+
+	var (
+		addr        string
+		mappingPath string
+		workingTime time.Duration
+	)
+
+	flags := c.Meta.NewFlagSet("serve-slack", c.Help())
+
+	flags.StringVar(&addr, "listen", DefaultSlackAddr, "")
+	flags.StringVar(&mappingPath, "mapping", "", "")
+	flags.DurationVar(&workingTime, "working", slack.DefaultWorking, "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	if len(mappingPath) == 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: -mapping is required\n")
+		return ExitCodeError
+	}
+
+	secret := os.Getenv(EnvSlackSigningSecret)
+	if len(secret) == 0 {
+		fmt.Fprintf(c.ErrStream, "Signing secret is required. Set it via %q env var.\n", EnvSlackSigningSecret)
+		return ExitCodeError
+	}
+
+	m, err := mapping.ParseFile(mappingPath)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load mapping: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	cfg, _, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	s := &slack.Server{
+		Dutyme:        dutyme,
+		Mapping:       m,
+		SigningSecret: []byte(secret),
+		Working:       workingTime,
+		Extend:        extendTime(cfg),
+		Log:           c.OutStream,
+	}
+
+	fmt.Fprintf(c.OutStream, "Listening Slack requests on %s\n", addr)
+	if err := http.ListenAndServe(addr, s); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to serve Slack endpoint: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	return ExitCodeOK
+}
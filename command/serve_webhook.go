@@ -0,0 +1,139 @@
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/tcnksm/dutyme/mapping"
+	"github.com/tcnksm/dutyme/webhook"
+)
+
+// EnvWebhookSecret is env var for the shared secret of webhook signature.
+const EnvWebhookSecret = "DUTYME_WEBHOOK_SECRET"
+
+// DefaultWebhookAddr is default address where serve-webhook listens.
+const DefaultWebhookAddr = "127.0.0.1:8080"
+
+type ServeWebhookCommand struct {
+	Meta
+}
+
+func (c *ServeWebhookCommand) Synopsis() string {
+	return "Start/stop overrides from CI/CD deployment events"
+}
+
+func (c *ServeWebhookCommand) Help() string {
+	helpText := fmt.Sprintf(`Usage: dutyme serve-webhook [options...]
+
+serve-webhook receives events from CI/CD pipelines and assigns on-call to
+the person who triggered them. It accepts the following events (POST):
+
+  - GitHub "deployment" event starts override and "deployment_status"
+    event (success, failure, error or inactive) stops it. Deployment
+    creator is the actor, deployment environment is the schedule and
+    deployment description is the reason.
+
+  - Generic event,
+      {"actor": "octocat", "schedule": "production", "action": "start", "working": "1h",
+       "reason": "deploy v1.2.0", "ticket": "OPS-123"}
+    action is "start" or "stop". reason and ticket are required when
+    the schedule requires reason (otherwise it responds 400). Optional
+    "key" pairs stop with start (actor and schedule by default).
+
+Stop only stops the overrides started by the events (the same
+deployment for GitHub), not the ones started manually.
+
+Requests must be signed by HMAC-SHA256 with the shared secret set via
+%q env var (X-Hub-Signature-256 or X-Dutyme-Signature
+header in "sha256=<hex>" format).
+
+Actors and schedules are mapped by the mapping file,
+
+  {
+    "users": {"octocat": "octocat@example.com"},
+    "schedules": {"production": "PI7DH85"},
+    "default_schedule": "PI7DH85"
+  }
+
+Options:
+
+  -listen ADDR    Address to listen. By default, it's %s.
+
+  -mapping PATH   Path to the mapping file (required).
+
+  -working TIME   Duration of override when event doesn't specify it.
+                  By default, it's 1 hour.
+
+`, EnvWebhookSecret, DefaultWebhookAddr)
+	return helpText
+}
+
+func (c *ServeWebhookCommand) Run(args []string) int {
+
+	var (
+		addr        string
+		mappingPath string
+		workingTime time.Duration
+	)
+
+	flags := c.Meta.NewFlagSet("serve-webhook", c.Help())
+
+	flags.StringVar(&addr, "listen", DefaultWebhookAddr, "")
+	flags.StringVar(&mappingPath, "mapping", "", "")
+	flags.DurationVar(&workingTime, "working", webhook.DefaultWorking, "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	if len(mappingPath) == 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: -mapping is required\n")
+		return ExitCodeError
+	}
+
+	secret := os.Getenv(EnvWebhookSecret)
+	if len(secret) == 0 {
+		fmt.Fprintf(c.ErrStream, "Shared secret is required. Set it via %q env var.\n", EnvWebhookSecret)
+		return ExitCodeError
+	}
+
+	m, err := mapping.ParseFile(mappingPath)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load mapping: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	cfg, _, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	s := &webhook.Server{
+		Dutyme:  dutyme,
+		Mapping: m,
+		Secret:  []byte(secret),
+		Working: workingTime,
+		Log:     c.OutStream,
+	}
+
+	fmt.Fprintf(c.OutStream, "Listening webhook on %s\n", addr)
+	if err := http.ListenAndServe(addr, s); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to serve webhook: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	return ExitCodeOK
+}
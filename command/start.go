@@ -1,10 +1,14 @@
 package command
 
 import (
+	"flag"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/api"
 	"github.com/tcnksm/dutyme/config"
 	"github.com/tcnksm/dutyme/dutyme"
 	"github.com/tcnksm/go-input"
@@ -14,6 +18,10 @@ const (
 	// DefaultWorkingTime is default duration of override.
 	DefaultWorkingTime = 1 * time.Hour
 
+	// DefaultLevel is default escalation level which is used
+	// when resolving schedules from service.
+	DefaultLevel = 1
+
 	// TimeFmt is used for displaying time
 	TimeFmt = "2006-01-02 15:04:05-0700"
 )
@@ -27,11 +35,16 @@ func (c *StartCommand) Synopsis() string {
 }
 
 func (c *StartCommand) Help() string {
-	helpText := fmt.Sprintf(`Usage: dutyme start [options...]
+	helpText := fmt.Sprintf(`Usage: dutyme start [options...] [PRESET]
 
 start overrides the schedule and assigns on-call to you. By default,
 it creates 1 hour override (you can change this via -working option).
 
+PRESET is the name of the preset in "presets" of configuration file.
+It bundles schedules, working time, exclusive and wait, notifiers and
+whether reason is required. Options given by flags override the preset
+(e.g., -working 3h or -exclusive=false).
+
 To use dutyme command, you need a PagerDuty API v2 token.
 The token must have full access to read, write, update, and delete.
 
@@ -42,18 +55,62 @@ You can set the token via %q env var (then you can
 skip the following input). Or after running first overriding, you can
 save login info on config file.
 
+Inside a git repository, the project configuration (%s without token
+and user) found by walking up from the current directory to the
+repository root is merged over the config file. It can set schedule,
+working time and hooks for the codebase.
+
 Options:
 
-  -working TIME  Working time (overriding time). By default, it's 1 hour.
+  -working TIME  Working time (overriding time). By default, it's 1 hour
+                 (or "working" in configuration file).
                  TIME can be specified by decimal numbers with a unit suffix,
                  such "1.5h" or "2h45m". It must be positive value.
 
+  -service NAME  Override the schedules which are responsible for the given
+                 service instead of the saved schedule. The service is resolved
+                 to its escalation policy and then to the schedules on the
+                 escalation level specified by -level.
+
+  -level N       Escalation level which is used with -service. By default,
+                 it's 1.
+
+  -dry-run       Show the schedules to override and the override window
+                 and quit without overriding. With -service, the resolved
+                 service and escalation policy are also shown.
+
+  -mine          Select schedule from the schedules you belong to (grouped
+                 by team) instead of searching schedule by its name.
+
   -update        Update existing configuration file. It asks email and
                  schedule name again.
 
-  -force         Force overriding without confirmation.
+  -exclusive     Fail when other users hold overrides on the schedule
+                 during the working time (shows who and until when).
+                 It lets dutyme double as a lightweight deploy lock.
+
+  -wait TIME     Wait until other users' overrides end, checking every 30
+                 seconds, up to TIME. It implies -exclusive.
+
+  -reason TEXT   Why you override (e.g., "deploy v1.2.0"). It's recorded
+                 on journal and passed to hooks and notifiers.
 
-`, EnvToken)
+  -ticket ID     Ticket of the work (e.g., "OPS-123"). It's recorded like
+                 reason and validated by "reason.ticket_pattern".
+
+Reason (or ticket) can be required for all or some schedules by "reason"
+in configuration file or by preset. If it's required but missing, start
+asks it when stdin is terminal, otherwise it fails.
+
+  -force         Force overriding without confirmation. It's also
+                 required (with reason) to override the schedule
+                 protected by "policy" in configuration file.
+
+When daemon is running and start doesn't need any input (-force is given
+and user and schedule are saved), overriding is requested to daemon via
+its control API.
+
+`, EnvToken, config.ProjectName)
 	return helpText
 }
 
@@ -64,7 +121,18 @@ func (c *StartCommand) Run(args []string) int {
 		update      bool
 		workingTime time.Duration
 
-		useExisting bool
+		service string
+		level   int
+		dryRun  bool
+
+		mine bool
+
+		exclusive bool
+		wait      time.Duration
+
+		presetName string
+		reason     string
+		ticket     string
 	)
 
 	flags := c.Meta.NewFlagSet("start", c.Help())
@@ -75,64 +143,96 @@ func (c *StartCommand) Run(args []string) int {
 	flags.BoolVar(&update, "update", false, "")
 	flags.DurationVar(&workingTime, "working", DefaultWorkingTime, "")
 
+	flags.StringVar(&service, "service", "", "")
+	flags.IntVar(&level, "level", DefaultLevel, "")
+	flags.BoolVar(&dryRun, "dry-run", false, "")
+
+	flags.BoolVar(&mine, "mine", false, "")
+
+	flags.BoolVar(&exclusive, "exclusive", false, "")
+	flags.DurationVar(&wait, "wait", 0, "")
+
+	flags.StringVar(&reason, "reason", "", "")
+	flags.StringVar(&ticket, "ticket", "", "")
+
+	// Preset can be given before or after options.
+	if len(args) != 0 && !strings.HasPrefix(args[0], "-") {
+		presetName, args = args[0], args[1:]
+	}
+
 	if err := flags.Parse(args); err != nil {
 		return ExitCodeError
 	}
 
-	// Find configuration file for dutyme.
-	cfgPath, err := c.Meta.ConfigPath()
-	if err != nil {
-		fmt.Fprintf(c.ErrStream, "Failed to read config path: %s\n", err)
-		TracePrint(c.ErrStream, err)
+	if flags.NArg() == 1 && len(presetName) == 0 {
+		presetName = flags.Arg(0)
+	} else if flags.NArg() != 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: too many arguments: %s\n", strings.Join(flags.Args(), " "))
 		return ExitCodeError
 	}
 
-	cfg := &config.Config{}
-	if _, err := os.Stat(cfgPath); err == nil {
-		Debugf("Use existing configuration file: %s", cfgPath)
+	c.Meta.WarnOrphaned()
 
-		var err error
-		cfg, err = config.ParseFile(cfgPath)
-		if err != nil {
-			fmt.Fprintf(c.ErrStream, "Failed to parse configuration file: %s\n", err)
-			TracePrint(c.ErrStream, err)
-			return ExitCodeError
+	// When daemon is running, overriding without any input is delegated
+	// to it (then API token is not required).
+	if force && !update && !mine && service == "" && !exclusive && wait == 0 && presetName == "" && !dryRun {
+		if client := c.Meta.APIClient(); client != nil {
+			return c.startViaDaemon(client, flags, workingTime, reason, ticket)
 		}
-		useExisting = true
 	}
 
-	// Override API token via env var if exsits.
-	if v := os.Getenv(EnvToken); len(v) != 0 {
-		Debugf("Read PD API token from env var: %s", v)
-		cfg.Token = v
+	cfg, useExisting, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
 	}
+	workingTime = resolveWorking(flags, workingTime, cfg)
 
-	if len(cfg.Token) == 0 {
-		token, err := c.Meta.AskToken()
+	var preset *config.Preset
+	if len(presetName) != 0 {
+		preset, err = cfg.Preset(presetName)
 		if err != nil {
-			fmt.Fprintf(c.ErrStream, "Failed to ask API token: %s\n", err)
-			TracePrint(c.ErrStream, err)
+			fmt.Fprintf(c.ErrStream, "Invalid argument: %s\n", err)
 			return ExitCodeError
 		}
-		cfg.Token = token
+
+		// Options given by flags override the preset.
+		if !flagGiven(flags, "working") && preset.Working > 0 {
+			workingTime = time.Duration(preset.Working)
+		}
+
+		if !flagGiven(flags, "exclusive") {
+			exclusive = preset.Exclusive
+		}
+
+		if !flagGiven(flags, "wait") && preset.Wait > 0 {
+			wait = time.Duration(preset.Wait)
+		}
 	}
 
-	// Construct Dutyme client with PD HTTP client
-	pd, err := dutyme.NewPDClient(cfg.Token)
+	// Schedules of preset are used unless they are given by flags.
+	usePreset := preset != nil && len(preset.Schedules) != 0 && service == "" && !mine
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
 	if err != nil {
-		fmt.Fprintf(c.ErrStream, "Failed to create PD HTTP client: %s\n", err)
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
 		TracePrint(c.ErrStream, err)
 		return ExitCodeError
 	}
+	dutyme.Reason, dutyme.Ticket = reason, ticket
+	dutyme.Force = force
 
-	dutyme := &dutyme.Dutyme{
-		UI: c.Meta.UI,
-		PD: pd,
+	if preset != nil {
+		dutyme.Notifiers = append(dutyme.Notifiers, newNotifiers(preset.Notify)...)
+		if preset.RequireReason {
+			requireReason(dutyme)
+		}
 	}
 
 	// When configuration file is not exist (fisrt time to execute or not saved before).
 	// or when -update flag is provided, ask/get user information.
-	if cfg.IsEmpty() || update {
+	if cfg.User == nil || update {
 		user, err := dutyme.GetUser("")
 		if err != nil {
 			fmt.Fprintf(c.ErrStream, "Failed to get PagerDuty user: %s\n", err)
@@ -140,8 +240,17 @@ func (c *StartCommand) Run(args []string) int {
 			return ExitCodeError
 		}
 		cfg.User = user
+	}
 
-		scheduleName, scheduleID, err := dutyme.GetSchedule("")
+	// Schedule is asked only when it's not resolved from service or preset.
+	if (cfg.ScheduleID == "" || update || mine) && service == "" && !usePreset {
+		var scheduleName, scheduleID string
+		var err error
+		if mine {
+			scheduleName, scheduleID, err = dutyme.SelectMySchedule(cfg.User)
+		} else {
+			scheduleName, scheduleID, err = dutyme.GetSchedule("")
+		}
 		if err != nil {
 			fmt.Fprintf(c.ErrStream, "Failed to get PagerDuty schedule: %s\n", err)
 			TracePrint(c.ErrStream, err)
@@ -151,33 +260,188 @@ func (c *StartCommand) Run(args []string) int {
 		cfg.ScheduleID = scheduleID
 	}
 	Debugf("User: %s", cfg.User.Email)
-	Debugf("Schedule: %s", cfg.ScheduleName)
+
+	schedules := []pagerduty.APIObject{
+		{ID: cfg.ScheduleID, Summary: cfg.ScheduleName},
+	}
+
+	if service != "" {
+		svc, err := dutyme.GetService(service)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to get PagerDuty service: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		chain, err := dutyme.ResolveService(svc.ID, level)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to resolve schedules from service: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		fmt.Fprint(c.OutStream, chain.String())
+		schedules = chain.Schedules
+	}
+
+	if usePreset {
+		schedules, err = dutyme.Schedules(preset.Schedules)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to get schedules of preset %q: %s\n", presetName, err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+	}
+
+	// Dry run quits before waiting for or overriding any schedule.
+	if dryRun {
+		start := time.Now()
+		for _, schedule := range schedules {
+			fmt.Fprintf(c.OutStream, "Override schedule %q (%s) by user %q\n",
+				schedule.Summary, schedule.ID, cfg.User.Email)
+			fmt.Fprintf(c.OutStream, "from %s to %s\n",
+				start.Format(TimeFmt), start.Add(workingTime).Format(TimeFmt))
+		}
+		fmt.Fprintln(c.OutStream, "Dry run: no schedule is overridden")
+		return ExitCodeOK
+	}
+
+	// Reason is checked for all schedules before overriding any of them.
+	scheduleIDs := make([]string, 0, len(schedules))
+	for _, schedule := range schedules {
+		scheduleIDs = append(scheduleIDs, schedule.ID)
+	}
+
+	if err := c.Meta.askReason(dutyme, scheduleIDs...); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to ask reason: %s\n", err)
+		return ExitCodeError
+	}
+
+	if err := dutyme.CheckReason(scheduleIDs...); err != nil {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: %s\n", err)
+		return ExitCodeError
+	}
+
+	// Other users must not hold the schedules (used as deploy lock).
+	// -wait implies -exclusive.
+	deadline := time.Now().Add(wait)
+	waiting := func(busy error) {
+		fmt.Fprintf(c.OutStream, "Waiting: %s\n", busy)
+	}
+	if exclusive || wait > 0 {
+		for _, schedule := range schedules {
+			err := dutyme.WaitExclusive(schedule.ID, cfg.User, workingTime, wait, 0, waiting)
+			if err != nil {
+				fmt.Fprintf(c.ErrStream, "Failed to take schedule exclusively: %s\n", err)
+				TracePrint(c.ErrStream, err)
+				return ExitCodeError
+			}
+		}
+	}
 
 	// Override time: from now to now + working time
 	start := time.Now()
 	end := start.Add(workingTime)
 
-	fmt.Fprintf(c.OutStream, "Override schedule %q (%s) by user %q\n",
-		cfg.ScheduleName, cfg.ScheduleID, cfg.User.Email)
-	fmt.Fprintf(c.OutStream, "from %s to %s\n",
-		start.Format(TimeFmt), end.Format(TimeFmt))
+	// Policy is checked for all schedules before overriding any of them.
+	for _, id := range scheduleIDs {
+		if err := dutyme.CheckPolicy(id, start, end); err != nil {
+			fmt.Fprintf(c.ErrStream, "Policy violation: %s\n", err)
+			return ExitCodeError
+		}
+	}
 
-	override, err := dutyme.Override(cfg.ScheduleID, cfg.User, start, end, force)
-	if err != nil {
-		if IsCancel(err) {
-			fmt.Fprintln(c.OutStream, "Override canceled")
+	// Multiple schedules are overridden all or nothing: when one of them
+	// is canceled or fails, the overrides already created are rolled back.
+	type created struct {
+		scheduleID string
+		overrideID string
+	}
+	var overridden []created
+	rollback := func() {
+		for _, o := range overridden {
+			if err := dutyme.DeleteOverride(o.scheduleID, o.overrideID); err != nil {
+				fmt.Fprintf(c.ErrStream, "Failed to roll back override %s: %s\n", o.overrideID, err)
+				continue
+			}
+			fmt.Fprintf(c.OutStream, "Rolled back override (%s)\n", o.overrideID)
+		}
+	}
+
+	for _, schedule := range schedules {
+		Debugf("Schedule: %s", schedule.Summary)
+		fmt.Fprintf(c.OutStream, "Override schedule %q (%s) by user %q\n",
+			schedule.Summary, schedule.ID, cfg.User.Email)
+		fmt.Fprintf(c.OutStream, "from %s to %s\n",
+			start.Format(TimeFmt), end.Format(TimeFmt))
+
+		if err := dutyme.ConfirmMember(schedule.ID, cfg.User, force); err != nil {
+			rollback()
+			if IsCancel(err) {
+				fmt.Fprintln(c.OutStream, "Override canceled")
+				return ExitCodeError
+			}
+
+			fmt.Fprintf(c.ErrStream, "Failed to check schedule membership: %s\n", err)
+			TracePrint(c.ErrStream, err)
 			return ExitCodeError
 		}
 
-		fmt.Fprintf(c.ErrStream, "Failed to override: %s\n", err)
-		TracePrint(c.ErrStream, err)
-		return ExitCodeError
+		var override *pagerduty.Override
+		for {
+			override, err = dutyme.Override(schedule.ID, cfg.User, start, end, force)
+			if err != nil {
+				rollback()
+				if IsCancel(err) {
+					fmt.Fprintln(c.OutStream, "Override canceled")
+					return ExitCodeError
+				}
+
+				fmt.Fprintf(c.ErrStream, "Failed to override: %s\n", err)
+				TracePrint(c.ErrStream, err)
+				return ExitCodeError
+			}
+
+			if !exclusive && wait == 0 {
+				break
+			}
+
+			// Other users may take the schedule at the same time. Back off
+			// (ConfirmExclusive deletes the override) if they did, and with
+			// -wait, wait for them again until the deadline.
+			err = dutyme.ConfirmExclusive(schedule.ID, cfg.User, override, start, end)
+			if err == nil {
+				break
+			}
+
+			if isBusy(err) && time.Now().Before(deadline) {
+				waiting(err)
+				err = dutyme.WaitExclusive(schedule.ID, cfg.User, workingTime, deadline.Sub(time.Now()), 0, waiting)
+				if err == nil {
+					start = time.Now()
+					end = start.Add(workingTime)
+					err = dutyme.CheckPolicy(schedule.ID, start, end)
+				}
+				if err == nil {
+					fmt.Fprintf(c.OutStream, "from %s to %s\n",
+						start.Format(TimeFmt), end.Format(TimeFmt))
+					continue
+				}
+			}
+
+			rollback()
+			fmt.Fprintf(c.ErrStream, "Failed to take schedule exclusively: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		fmt.Fprintf(c.OutStream, "Successfuly overrided schedule (%s)\n", override.ID)
+
+		overridden = append(overridden, created{scheduleID: schedule.ID, overrideID: override.ID})
 	}
-	fmt.Fprintf(c.OutStream, "Successfuly overrided schedule (%s)\n", override.ID)
 
 	// If it's used exsiting configuration file,
-	// and -update flag is not provided, then skip the following section.
-	if useExisting && !update {
+	// and -update (or -mine) flag is not provided, then skip the following section.
+	if useExisting && !update && !mine {
 		return ExitCodeOK
 	}
 
@@ -200,7 +464,8 @@ func (c *StartCommand) Run(args []string) int {
 		return ExitCodeOK
 	}
 
-	if err := cfg.WriteFile(cfgPath, true); err != nil {
+	cfgPath, err := c.Meta.SaveConfig(cfg)
+	if err != nil {
 		fmt.Fprintf(c.ErrStream, "Failed to save file: %s\n", err)
 		TracePrint(c.ErrStream, err)
 		return ExitCodeError
@@ -210,6 +475,45 @@ func (c *StartCommand) Run(args []string) int {
 	return ExitCodeOK
 }
 
+// startViaDaemon requests daemon to override the configured schedule.
+func (c *StartCommand) startViaDaemon(client *api.Client, flags *flag.FlagSet, workingTime time.Duration, reason, ticket string) int {
+	cfg, _, err := c.Meta.ReadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+	workingTime = resolveWorking(flags, workingTime, cfg)
+
+	res, err := client.Start(&api.StartRequest{
+		ScheduleID: cfg.ScheduleID,
+		Working:    config.Duration(workingTime),
+		Reason:     reason,
+		Ticket:     ticket,
+		Force:      true,
+	})
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to override: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	e := res.Event
+	fmt.Fprintf(c.OutStream, "Override schedule %q (%s) by user %q\n",
+		e.ScheduleName, e.ScheduleID, e.UserEmail)
+	fmt.Fprintf(c.OutStream, "from %s to %s\n",
+		e.Start.Local().Format(TimeFmt), e.End.Local().Format(TimeFmt))
+	fmt.Fprintf(c.OutStream, "Successfuly overrided schedule (%s)\n", e.OverrideID)
+
+	return ExitCodeOK
+}
+
+// isBusy returns true if other users hold the schedule.
+func isBusy(err error) bool {
+	_, ok := errors.Cause(err).(*dutyme.BusyError)
+	return ok
+}
+
 type isCancel interface {
 	IsCancel() bool
 }
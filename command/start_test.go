@@ -1,11 +1,65 @@
 package command
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mitchellh/cli"
+	"github.com/mitchellh/go-homedir"
 )
 
 func TestStartCommand_implement(t *testing.T) {
 	var _ cli.Command = &StartCommand{}
 }
+
+func TestStartCommand_dryRun(t *testing.T) {
+	home, err := ioutil.TempDir("", "dutyme-start")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(home)
+
+	for key, value := range map[string]string{"HOME": home, EnvXDGConfigHome: ""} {
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, value)
+	}
+	defer func(disable bool) { homedir.DisableCache = disable }(homedir.DisableCache)
+	homedir.DisableCache = true
+
+	cfg := `{
+  "token": "dummy",
+  "user": {"Email": "alice@example.com", "Obj": {"id": "PALICE1"}},
+  "schedule_id": "PSCHED1",
+  "schedule_name": "Backend"
+}`
+	if err := ioutil.WriteFile(filepath.Join(home, ".dutyme.json"), []byte(cfg), 0600); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+
+	for _, args := range [][]string{
+		{"-dry-run"},
+		{"-dry-run", "-force"},
+		{"-dry-run", "-exclusive", "-wait", "1m"},
+	} {
+		outStream, errStream := new(bytes.Buffer), new(bytes.Buffer)
+		c := &StartCommand{Meta: Meta{OutStream: outStream, ErrStream: errStream}}
+
+		if got := c.Run(args); got != ExitCodeOK {
+			t.Fatalf("Run(%v) = %d, want %d: %s", args, got, ExitCodeOK, errStream.String())
+		}
+
+		for _, want := range []string{`"Backend" (PSCHED1)`, "Dry run"} {
+			if !strings.Contains(outStream.String(), want) {
+				t.Fatalf("Run(%v) output %q, want to contain %q", args, outStream.String(), want)
+			}
+		}
+
+		if strings.Contains(outStream.String(), "Successfuly overrided") {
+			t.Fatalf("Run(%v) overrided schedule in dry run", args)
+		}
+	}
+}
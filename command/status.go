@@ -0,0 +1,85 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+)
+
+type StatusCommand struct {
+	Meta
+}
+
+func (c *StatusCommand) Synopsis() string {
+	return "Show your active overrides created by dutyme"
+}
+
+func (c *StatusCommand) Help() string {
+	helpText := `Usage: dutyme status
+
+status shows the overrides created by dutyme which are not ended yet
+(sorted by end time). It reads the journal of overrides, so it doesn't
+request PagerDuty API.
+
+When daemon is running, status is requested to it via its control API.
+
+`
+	return helpText
+}
+
+func (c *StatusCommand) Run(args []string) int {
+
+	flags := c.Meta.NewFlagSet("status", c.Help())
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	var active []journal.Event
+	if client := c.Meta.APIClient(); client != nil {
+		res, err := client.Status()
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to read active overrides: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		active = res.Overrides
+	} else {
+		j, err := c.Meta.Journal()
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to read journal: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		// Reading journal doesn't need PagerDuty client.
+		active, err = (&dutyme.Dutyme{Journal: j}).ActiveOverrides()
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to read active overrides: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+	}
+
+	if len(active) == 0 {
+		fmt.Fprintln(c.OutStream, "No active override created by dutyme")
+		return ExitCodeOK
+	}
+
+	for _, e := range active {
+		fmt.Fprintf(c.OutStream, "%s  %-20s  %s - %s\n",
+			e.OverrideID, scheduleName(e),
+			e.Start.Local().Format(TimeFmt), e.End.Local().Format(TimeFmt))
+	}
+
+	return ExitCodeOK
+}
+
+// scheduleName returns the schedule name of the event. If it's not
+// recorded, schedule ID is returned instead.
+func scheduleName(e journal.Event) string {
+	if len(e.ScheduleName) != 0 {
+		return e.ScheduleName
+	}
+	return e.ScheduleID
+}
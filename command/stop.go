@@ -0,0 +1,81 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/tcnksm/dutyme/api"
+	"github.com/tcnksm/dutyme/journal"
+)
+
+type StopCommand struct {
+	Meta
+}
+
+func (c *StopCommand) Synopsis() string {
+	return "Stop your override created by dutyme"
+}
+
+func (c *StopCommand) Help() string {
+	helpText := `Usage: dutyme stop [options...]
+
+stop stops the override created by dutyme. By default, it stops the
+override which ends first. If the override has already started, it's
+truncated to end now (on-call is handed back to the original user).
+
+When daemon is running, stop is requested to it via its control API.
+
+Options:
+
+  -override ID   ID of the override to stop.
+
+`
+	return helpText
+}
+
+func (c *StopCommand) Run(args []string) int {
+
+	var overrideID string
+
+	flags := c.Meta.NewFlagSet("stop", c.Help())
+	flags.StringVar(&overrideID, "override", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	var stopped *journal.Event
+	if client := c.Meta.APIClient(); client != nil {
+		res, err := client.Stop(&api.StopRequest{OverrideID: overrideID})
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to stop override: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		stopped = &res.Event
+	} else {
+		cfg, _, err := c.Meta.LoadConfig()
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		dutyme, err := c.Meta.NewDutyme(cfg)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+
+		stopped, err = dutyme.Stop(overrideID)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to stop override: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+	}
+
+	fmt.Fprintf(c.OutStream, "Successfuly stopped override %s on schedule %q\n",
+		stopped.OverrideID, scheduleName(*stopped))
+	return ExitCodeOK
+}
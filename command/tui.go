@@ -0,0 +1,125 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/tcnksm/dutyme/tui"
+)
+
+type TUICommand struct {
+	Meta
+}
+
+func (c *TUICommand) Synopsis() string {
+	return "Show interactive dashboard of your schedules"
+}
+
+func (c *TUICommand) Help() string {
+	helpText := `Usage: dutyme tui [options...]
+
+tui shows full-screen dashboard of your configured schedule as horizontal
+timeline for the next 24 hours (or 7 days). Overrides are highlighted and
+you can create, extend, shorten and delete your overrides by keystrokes:
+
+  up/down (k/j)      Select schedule
+  left/right (h/l)   Select your override
+  n                  Create new override from now (asks reason if the
+                     schedule requires it and -reason is not given)
+  +/-                Extend/shorten the selected override
+  d                  Delete the selected override
+  t                  Toggle 24 hours/7 days
+  r                  Refresh
+  q                  Quit
+
+It requires configuration file (run start command first to save it).
+
+Options:
+
+  -refresh TIME  Interval of refreshing schedules. By default, it's 1 minute.
+
+  -step TIME     Duration used for creating, extending and shortening
+                 override. By default, it's 30 minutes.
+
+  -reason TEXT   Why you create overrides. It's recorded on journal and
+                 passed to hooks and notifiers.
+
+  -ticket ID     Ticket of the work (e.g., "OPS-123").
+
+`
+	return helpText
+}
+
+func (c *TUICommand) Run(args []string) int {
+
+	var (
+		refresh time.Duration
+		step    time.Duration
+		reason  string
+		ticket  string
+	)
+
+	flags := c.Meta.NewFlagSet("tui", c.Help())
+
+	flags.DurationVar(&refresh, "refresh", tui.DefaultRefresh, "")
+	flags.DurationVar(&step, "step", tui.DefaultStep, "")
+	flags.StringVar(&reason, "reason", "", "")
+	flags.StringVar(&ticket, "ticket", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	if refresh <= 0 || step <= 0 {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: -refresh and -step must be positive value\n")
+		return ExitCodeError
+	}
+
+	cfg, _, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	if cfg.User == nil || cfg.ScheduleID == "" {
+		fmt.Fprintf(c.ErrStream, "No user or schedule is configured. Run `dutyme start` first and save it.\n")
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+	dutyme.Reason, dutyme.Ticket = reason, ticket
+
+	// Reason is asked on dashboard when it's required, but the ticket
+	// is validated here.
+	if err := dutyme.CheckReason(); err != nil {
+		fmt.Fprintf(c.ErrStream, "Invalid argument: %s\n", err)
+		return ExitCodeError
+	}
+
+	dashboard := &tui.Dashboard{
+		Dutyme: dutyme,
+		User:   cfg.User,
+		Schedules: []pagerduty.APIObject{
+			{ID: cfg.ScheduleID, Summary: cfg.ScheduleName},
+		},
+		In:      c.Meta.UI.Reader,
+		Out:     c.OutStream,
+		Refresh: refresh,
+		Step:    step,
+	}
+
+	if err := dashboard.Run(); err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to run dashboard: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	return ExitCodeOK
+}
@@ -37,9 +37,9 @@ func (c *VersionCommand) Run(args []string) int {
 	}
 
 	if res.Outdated {
-		fmt.Fprintln(c.OutStream,
+		fmt.Fprintf(c.OutStream,
 			"\n Your version of `dutyme` is out of date! The latest version is %s.\n"+
-				"You can donwloand it from github.com/tcnksm/dutyme", res.Latest)
+				"You can donwloand it from github.com/tcnksm/dutyme\n", res.Current)
 	}
 	return 0
 }
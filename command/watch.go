@@ -0,0 +1,138 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/tcnksm/go-input"
+)
+
+const (
+	// watchInterval is the interval of reading reminder feed.
+	watchInterval = 1 * time.Second
+)
+
+type WatchCommand struct {
+	Meta
+}
+
+func (c *WatchCommand) Synopsis() string {
+	return "Ring terminal bell on reminders from daemon"
+}
+
+func (c *WatchCommand) Help() string {
+	helpText := `Usage: dutyme watch
+
+watch follows reminders fired by daemon command. On reminder, it rings
+terminal bell, shows which override is about to expire and asks whether
+to extend it (by "reminder.extend" in configuration file, 30 minutes by
+default). daemon command must be running.
+`
+	return helpText
+}
+
+func (c *WatchCommand) Run(args []string) int {
+	flags := c.Meta.NewFlagSet("watch", c.Help())
+	if err := flags.Parse(args); err != nil {
+		return ExitCodeError
+	}
+
+	cfg, _, err := c.Meta.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to load configuration: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	dutyme, err := c.Meta.NewDutyme(cfg)
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to create dutyme: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	feed, err := c.Meta.Feed()
+	if err != nil {
+		fmt.Fprintf(c.ErrStream, "Failed to read reminder feed: %s\n", err)
+		TracePrint(c.ErrStream, err)
+		return ExitCodeError
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+
+	by := extendTime(cfg)
+	fmt.Fprintf(c.OutStream, "Watching reminders (press Ctrl-C to quit)\n")
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var offset int64 = -1
+	for {
+		reminders, next, err := feed.Follow(offset)
+		if err != nil {
+			fmt.Fprintf(c.ErrStream, "Failed to read reminder feed: %s\n", err)
+			TracePrint(c.ErrStream, err)
+			return ExitCodeError
+		}
+		offset = next
+
+		for _, r := range reminders {
+			fmt.Fprintf(c.OutStream, "\a%s\n", r)
+
+			if cfg.User == nil {
+				continue
+			}
+
+			query := fmt.Sprintf("Extend it by %s? [y/N]", by)
+			ans, err := c.Meta.UI.Ask(query, &input.Options{
+				Default:     "N",
+				Loop:        true,
+				HideOrder:   true,
+				HideDefault: true,
+				ValidateFunc: func(s string) error {
+					if s != "Y" && s != "y" && s != "N" && s != "n" {
+						return fmt.Errorf("input must be Y or n")
+					}
+					return nil
+				},
+			})
+			if err != nil {
+				fmt.Fprintf(c.ErrStream, "Failed to ask: %s\n", err)
+				return ExitCodeError
+			}
+
+			if ans != "Y" && ans != "y" {
+				continue
+			}
+
+			active, err := dutyme.ActiveOverrides()
+			if err != nil {
+				fmt.Fprintf(c.ErrStream, "Failed to read active overrides: %s\n", err)
+				continue
+			}
+
+			for _, e := range active {
+				if e.OverrideID != r.OverrideID {
+					continue
+				}
+
+				override, end, err := dutyme.Extend(e, cfg.User, by)
+				if err != nil {
+					fmt.Fprintf(c.ErrStream, "Failed to extend override: %s\n", err)
+					break
+				}
+				fmt.Fprintf(c.OutStream, "Successfuly extended override to %s (new override %s)\n",
+					end.Format(TimeFmt), override.ID)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-sigCh:
+			return ExitCodeOK
+		}
+	}
+}
@@ -12,6 +12,81 @@ func Commands(meta *command.Meta) map[string]cli.CommandFactory {
 				Meta: *meta,
 			}, nil
 		},
+		"config": func() (cli.Command, error) {
+			return &command.ConfigCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"daemon": func() (cli.Command, error) {
+			return &command.DaemonCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"extend": func() (cli.Command, error) {
+			return &command.ExtendCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"git-hook": func() (cli.Command, error) {
+			return &command.GitHookCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"run": func() (cli.Command, error) {
+			return &command.RunCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"serve-slack": func() (cli.Command, error) {
+			return &command.ServeSlackCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"serve-webhook": func() (cli.Command, error) {
+			return &command.ServeWebhookCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"stop": func() (cli.Command, error) {
+			return &command.StopCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"status": func() (cli.Command, error) {
+			return &command.StatusCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"history": func() (cli.Command, error) {
+			return &command.HistoryCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"gc": func() (cli.Command, error) {
+			return &command.GCCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"hold": func() (cli.Command, error) {
+			return &command.HoldCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"preview": func() (cli.Command, error) {
+			return &command.PreviewCommand{
+				Meta: *meta,
+			}, nil
+		},
+		"tui": func() (cli.Command, error) {
+			return &command.TUICommand{
+				Meta: *meta,
+			}, nil
+		},
+		"watch": func() (cli.Command, error) {
+			return &command.WatchCommand{
+				Meta: *meta,
+			}, nil
+		},
 		"version": func() (cli.Command, error) {
 			return &command.VersionCommand{
 				Meta:     *meta,
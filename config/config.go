@@ -1,70 +1,214 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
-	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/tcnksm/dutyme/dutyme"
 )
 
 type Config struct {
+	// Version is the format version of the file (see migrations).
+	// It's always set to the current Version on writing.
+	Version int `json:"version,omitempty"`
+
 	Token string `json:"token,omitempty"`
 
 	User *dutyme.User `json:"user,omitempty"`
 
 	ScheduleID   string `json:"schedule_id,omitempty"`
 	ScheduleName string `json:"schedule_name,omitempty"`
+
+	// Working is default duration of override.
+	Working Duration `json:"working,omitempty"`
+
+	Reminder *Reminder `json:"reminder,omitempty"`
+
+	AutoExtend *AutoExtend `json:"auto_extend,omitempty"`
+
+	Notify *Notify `json:"notify,omitempty"`
+
+	Hooks *Hooks `json:"hooks,omitempty"`
+
+	// Presets are named override settings (see Preset).
+	Presets map[string]*Preset `json:"presets,omitempty"`
+
+	Reason *Reason `json:"reason,omitempty"`
+
+	Policy *Policy `json:"policy,omitempty"`
+
+	// TrustedProjects is the hashes (see ProjectHash) of the project
+	// configuration files which are allowed to run commands and send
+	// notifications (see isCommandKey). It's only read from system and
+	// user's configuration files.
+	TrustedProjects []string `json:"trusted_projects,omitempty"`
 }
 
-func (c *Config) IsEmpty() bool {
-	return c.User == nil && c.ScheduleID == ""
+// Reminder is configuration of reminders which daemon fires
+// before overrides created by dutyme expire.
+type Reminder struct {
+	// Before is how long before expiry reminders are fired.
+	Before Duration `json:"before,omitempty"`
+
+	// Extend is the duration which is used for one-step extend.
+	Extend Duration `json:"extend,omitempty"`
+
+	// Command is shell command which is executed on reminder. Reminder
+	// details are passed via DUTYME_HOOK_* env vars.
+	Command string `json:"command,omitempty"`
+
+	// Webhook is URL where reminder is posted as JSON.
+	Webhook string `json:"webhook,omitempty"`
 }
 
-func (c *Config) WriteFile(path string, indent bool) error {
-	path, err := filepath.Abs(path)
-	if err != nil {
-		return errors.Wrap(err, "faield to get abs path")
-	}
+// AutoExtend is configuration of auto-extending. When it's set, daemon
+// extends the override which is about to expire while incidents assigned
+// to the user on the schedule's services are still open.
+type AutoExtend struct {
+	// Step is the duration of one extension.
+	Step Duration `json:"step,omitempty"`
 
-	f, err := os.Create(path)
-	if err != nil {
-		return errors.Wrap(err, "failed to open file")
-	}
-	defer f.Close()
+	// Max is the cap of total extended duration of an override, counted
+	// from the end of the override which is first started (on journal).
+	Max Duration `json:"max,omitempty"`
+}
+
+// Notify is configuration of notifiers which are notified after
+// overriding and stopping. Each field can be used together.
+type Notify struct {
+	Webhooks []WebhookNotify `json:"webhooks,omitempty"`
+	Slack    []SlackNotify   `json:"slack,omitempty"`
+	Email    *EmailNotify    `json:"email,omitempty"`
+}
+
+// WebhookNotify is a generic webhook.
+type WebhookNotify struct {
+	URL string `json:"url"`
+
+	// Template is text/template of JSON body. If empty,
+	// notification is posted as it is.
+	Template string `json:"template,omitempty"`
+}
+
+// SlackNotify is Slack incoming webhook.
+type SlackNotify struct {
+	URL string `json:"url"`
 
-	return c.write(f, indent)
+	// Channel overrides the default channel of the webhook.
+	Channel string `json:"channel,omitempty"`
 }
 
-func (c *Config) write(wr io.Writer, indent bool) error {
-	encoder := json.NewEncoder(wr)
+// EmailNotify is SMTP email.
+type EmailNotify struct {
+	// Addr is SMTP server address (host:port).
+	Addr string `json:"addr"`
 
-	if indent {
-		encoder.SetIndent("", "  ")
+	From string   `json:"from"`
+	To   []string `json:"to,omitempty"`
+
+	// ToDisplaced sends the email to the displaced on-call too.
+	ToDisplaced bool `json:"to_displaced,omitempty"`
+
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// Hooks is shell commands which are executed around dutyme actions.
+// Override details are passed via DUTYME_HOOK_* env vars and JSON on stdin.
+// If pre_* hook exits with non-zero status, the action is aborted.
+type Hooks struct {
+	PreStart     string `json:"pre_start,omitempty"`
+	PostStart    string `json:"post_start,omitempty"`
+	PreStop      string `json:"pre_stop,omitempty"`
+	PostStop     string `json:"post_stop,omitempty"`
+	OnExpireSoon string `json:"on_expire_soon,omitempty"`
+}
+
+// Reason is configuration of reason of overriding (-reason and -ticket
+// flags). Either reason or ticket satisfies the requirement.
+type Reason struct {
+	// Required requires reason for all overrides.
+	Required bool `json:"required,omitempty"`
+
+	// Schedules are IDs of the schedules which require reason.
+	Schedules []string `json:"schedules,omitempty"`
+
+	// TicketPattern is regular expression which ticket IDs must
+	// match (e.g., "^OPS-[0-9]+$").
+	TicketPattern string `json:"ticket_pattern,omitempty"`
+}
+
+// Policy is guardrails of overriding. It's usually shipped by
+// organization in the system configuration.
+type Policy struct {
+	// MaxDuration is the longest override (e.g., "8h").
+	MaxDuration Duration `json:"max_duration,omitempty"`
+
+	// Hours is the time of day when overrides are allowed
+	// (e.g., "09:00-18:00" or overnight "22:00-06:00").
+	Hours string `json:"hours,omitempty"`
+
+	// Weekdays are the days when overrides are allowed
+	// (e.g., ["mon", "tue", "wed", "thu", "fri"]).
+	Weekdays []string `json:"weekdays,omitempty"`
+
+	// Timezone is IANA time zone of hours and weekdays
+	// (e.g., "Asia/Tokyo"). By default, it's local.
+	Timezone string `json:"timezone,omitempty"`
+
+	// Protected are IDs of the schedules which can be overridden only
+	// with -force and reason.
+	Protected []string `json:"protected,omitempty"`
+}
+
+// Duration is time.Duration which is encoded as string
+// like "1h30m" in JSON.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.Wrap(err, "duration must be string like \"1h30m\"")
 	}
 
-	if err := encoder.Encode(c); err != nil {
-		return errors.Wrap(err, "failed to encode json")
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse duration")
 	}
 
+	*d = Duration(v)
 	return nil
 }
 
+func (c *Config) IsEmpty() bool {
+	return c.User == nil && c.ScheduleID == ""
+}
+
 func ParseFile(path string) (*Config, error) {
 	path, err := filepath.Abs(path)
 	if err != nil {
 		return nil, errors.Wrap(err, "faield to get abs path")
 	}
 
-	f, err := os.Open(path)
+	raw, err := readFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read file")
+	}
+
+	buf, err := json.Marshal(raw)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to open file")
+		return nil, errors.Wrap(err, "failed to encode json")
 	}
-	defer f.Close()
 
-	return parse(f)
+	return parse(bytes.NewReader(buf))
 }
 
 func parse(rd io.Reader) (*Config, error) {
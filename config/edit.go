@@ -0,0 +1,270 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/dutyme"
+)
+
+// Validate checks the values which are not checked by decoding (e.g.,
+// missing URL of notifier). It returns all problems in one error.
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, errors.Errorf(format, args...).Error())
+	}
+
+	if c.Working < 0 {
+		add("working must be positive")
+	}
+
+	if len(c.ScheduleName) != 0 && len(c.ScheduleID) == 0 {
+		add("schedule_name is set without schedule_id")
+	}
+
+	if c.User != nil && len(c.User.Email) == 0 {
+		add("user.Email is empty")
+	}
+
+	if r := c.Reminder; r != nil && (r.Before < 0 || r.Extend < 0) {
+		add("reminder.before and reminder.extend must be positive")
+	}
+
+	if a := c.AutoExtend; a != nil && (a.Step < 0 || a.Max < 0) {
+		add("auto_extend.step and auto_extend.max must be positive")
+	}
+
+	if n := c.Notify; n != nil {
+		for i, w := range n.Webhooks {
+			if len(w.URL) == 0 {
+				add("notify.webhooks[%d].url is empty", i)
+			}
+		}
+
+		for i, s := range n.Slack {
+			if len(s.URL) == 0 {
+				add("notify.slack[%d].url is empty", i)
+			}
+		}
+
+		if e := n.Email; e != nil && (len(e.Addr) == 0 || len(e.From) == 0 || (len(e.To) == 0 && !e.ToDisplaced)) {
+			add("notify.email requires addr, from and to (or to_displaced)")
+		}
+	}
+
+	if r := c.Reason; r != nil && len(r.TicketPattern) != 0 {
+		if _, err := regexp.Compile(r.TicketPattern); err != nil {
+			add("reason.ticket_pattern is invalid: %s", err)
+		}
+	}
+
+	if p := c.Policy; p != nil {
+		if p.MaxDuration < 0 {
+			add("policy.max_duration must be positive")
+		}
+
+		if len(p.Hours) != 0 {
+			if _, err := dutyme.ParseHours(p.Hours); err != nil {
+				add("policy.hours is invalid: %s", err)
+			}
+		}
+
+		for _, w := range p.Weekdays {
+			if _, err := dutyme.ParseWeekday(w); err != nil {
+				add("policy.weekdays is invalid: %s", err)
+			}
+		}
+
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			add("policy.timezone is invalid: %s", err)
+		}
+	}
+
+	for _, name := range c.PresetNames() {
+		p := c.Presets[name]
+		if p == nil {
+			add("presets.%s is empty", name)
+			continue
+		}
+
+		for _, id := range p.Schedules {
+			if len(strings.TrimSpace(id)) == 0 {
+				add("presets.%s.schedules has empty schedule ID", name)
+			}
+		}
+
+		if p.Working < 0 || p.Wait < 0 {
+			add("presets.%s.working and wait must be positive", name)
+		}
+
+		if p.Notify != nil {
+			preset := &Config{Notify: p.Notify}
+			if err := preset.Validate(); err != nil {
+				add("presets.%s: %s", name, err)
+			}
+		}
+	}
+
+	if len(problems) != 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// SetFile sets the value of the key in the configuration file. The
+// value is parsed as the type of the key in the same way as env vars.
+// Keys which dutyme doesn't know in the file are kept as they are.
+func SetFile(path, key, value string) error {
+	t, ok := keyType(key)
+	if !ok {
+		return errors.Errorf("unknown key: %s", key)
+	}
+
+	v, err := parseValue(t, value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid value of %s", key)
+	}
+
+	raw, err := readRaw(path)
+	if err != nil {
+		return err
+	}
+
+	// Setting schedule by ID leaves the name of the old one.
+	if key == "schedule_id" {
+		delete(raw, "schedule_name")
+	}
+
+	unflatten(raw, key, v)
+	return writeRaw(path, raw)
+}
+
+// UpdateFile writes the values of the given keys (e.g., "token" or
+// "hooks.pre_start") of the configuration to the file. The other keys
+// in the file (including ones which dutyme doesn't know) are kept. Keys
+// which are empty in the configuration are removed from the file.
+func (c *Config) UpdateFile(path string, keys ...string) error {
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode json")
+	}
+
+	values := make(map[string]interface{})
+	if err := decodeJSON(buf, &values); err != nil {
+		return errors.Wrap(err, "failed to decode json")
+	}
+
+	raw, err := readRaw(path)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if v, ok := lookup(values, strings.Split(key, ".")); ok {
+			unflatten(raw, key, v)
+		} else {
+			remove(raw, strings.Split(key, "."))
+		}
+	}
+
+	return writeRaw(path, raw)
+}
+
+// CreateFile creates the configuration file which has nothing but the
+// version (e.g., to open it by editor).
+func CreateFile(path string) error {
+	return writeRaw(path, make(map[string]interface{}))
+}
+
+// UnsetFile removes the key (or the object like "hooks") from the
+// configuration file. Parent objects which become empty are removed.
+func UnsetFile(path, key string) error {
+	raw, err := readRaw(path)
+	if err != nil {
+		return err
+	}
+
+	if !remove(raw, strings.Split(key, ".")) {
+		return errors.Errorf("%s is not set in %s", key, path)
+	}
+
+	return writeRaw(path, raw)
+}
+
+func lookup(raw map[string]interface{}, parts []string) (interface{}, bool) {
+	v, ok := raw[parts[0]]
+	if !ok || len(parts) == 1 {
+		return v, ok
+	}
+
+	child, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookup(child, parts[1:])
+}
+
+func remove(raw map[string]interface{}, parts []string) bool {
+	if len(parts) == 1 {
+		if _, ok := raw[parts[0]]; !ok {
+			return false
+		}
+		delete(raw, parts[0])
+		return true
+	}
+
+	child, ok := raw[parts[0]].(map[string]interface{})
+	if !ok || !remove(child, parts[1:]) {
+		return false
+	}
+
+	if len(child) == 0 {
+		delete(raw, parts[0])
+	}
+	return true
+}
+
+// readRaw reads the configuration file as JSON object. If the file
+// doesn't exist, it returns empty object.
+func readRaw(path string) (map[string]interface{}, error) {
+	// Upgrade the older file with backup before changing it.
+	if _, err := MigrateFile(path); err != nil {
+		return nil, err
+	}
+
+	raw, err := readFile(path)
+	if os.IsNotExist(err) {
+		return make(map[string]interface{}), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read file")
+	}
+	return raw, nil
+}
+
+// writeRaw checks that the JSON object can be decoded as configuration
+// and writes it to the file in the format of its extension. It doesn't
+// Validate because related keys (e.g., notify.email) are set one by one.
+func writeRaw(path string, raw map[string]interface{}) error {
+	raw["version"] = Version
+	buf, err := json.Marshal(raw)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode json")
+	}
+
+	if _, err := parse(bytes.NewReader(buf)); err != nil {
+		return err
+	}
+
+	if buf, err = encode(raw, FormatOf(path)); err != nil {
+		return errors.Wrapf(err, "failed to encode %s", FormatOf(path))
+	}
+
+	return writeFile(path, buf)
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := testWriteFile(t, dir, "config.json", `{
+  "token": "secret",
+  "schedule_id": "PI7DH85",
+  "schedule_name": "Dutyme primary",
+  "unknown": {"kept": true}
+}`)
+
+	sets := [][2]string{
+		{"working", "2h"},
+		{"schedule_id", "PI3DH03"},
+		{"hooks.post_start", "./announce.sh"},
+		{"notify.email.to", "a@dutyme.com, b@dutyme.com"},
+		{"notify.email.addr", "smtp.dutyme.com:587"},
+		{"notify.email.from", "dutyme@dutyme.com"},
+	}
+	for _, kv := range sets {
+		if err := SetFile(path, kv[0], kv[1]); err != nil {
+			t.Fatalf("SetFile(%q) failed: %s", kv[0], err)
+		}
+	}
+
+	cfg, err := ParseFile(path)
+	if err != nil {
+		t.Fatal("ParseFile failed:", err)
+	}
+
+	if got, want := time.Duration(cfg.Working), 2*time.Hour; got != want {
+		t.Fatalf("Working=%s, want=%s", got, want)
+	}
+
+	if cfg.ScheduleID != "PI3DH03" || cfg.ScheduleName != "" {
+		t.Fatalf("schedule=%q (%q), want PI3DH03 without name", cfg.ScheduleID, cfg.ScheduleName)
+	}
+
+	if cfg.Hooks == nil || cfg.Hooks.PostStart != "./announce.sh" {
+		t.Fatalf("Hooks=%#v, want post_start", cfg.Hooks)
+	}
+
+	if cfg.Notify == nil || cfg.Notify.Email == nil || len(cfg.Notify.Email.To) != 2 {
+		t.Fatalf("Notify=%#v, want 2 recipients", cfg.Notify)
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal("ReadFile failed:", err)
+	}
+	if !strings.Contains(string(buf), `"kept": true`) {
+		t.Fatalf("unknown key is lost:\n%s", buf)
+	}
+
+	if err := UnsetFile(path, "hooks.post_start"); err != nil {
+		t.Fatal("UnsetFile failed:", err)
+	}
+
+	if err := UnsetFile(path, "hooks.post_start"); err == nil {
+		t.Fatal("expect UnsetFile of unset key to fail")
+	}
+
+	cfg, err = ParseFile(path)
+	if err != nil {
+		t.Fatal("ParseFile failed:", err)
+	}
+	if cfg.Hooks != nil {
+		t.Fatalf("Hooks=%#v, want empty parent to be removed", cfg.Hooks)
+	}
+}
+
+func TestSetFile_invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := testWriteFile(t, dir, "config.json", `{"working": "1h"}`)
+
+	cases := [][2]string{
+		{"no_such_key", "value"},
+		{"working", "2 hours"},
+		{"working", "-1h"},
+		{"notify.slack", `{"url": "https://hooks.slack.com/services/..."}`},
+	}
+	for _, kv := range cases {
+		if err := SetFile(path, kv[0], kv[1]); err == nil {
+			t.Fatalf("expect SetFile(%q, %q) to fail", kv[0], kv[1])
+		}
+	}
+
+	cfg, err := ParseFile(path)
+	if err != nil {
+		t.Fatal("ParseFile failed:", err)
+	}
+	if got, want := time.Duration(cfg.Working), time.Hour; got != want {
+		t.Fatalf("Working=%s, want=%s (file must not be changed)", got, want)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := &Config{
+		ScheduleID: "PI7DH85",
+		Working:    Duration(time.Hour),
+		Notify: &Notify{
+			Slack: []SlackNotify{{URL: "https://hooks.slack.com/services/..."}},
+		},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatal("Validate failed:", err)
+	}
+
+	invalid := &Config{
+		ScheduleName: "Dutyme primary",
+		Working:      Duration(-time.Hour),
+		Notify: &Notify{
+			Webhooks: []WebhookNotify{{Template: "{}"}},
+			Email:    &EmailNotify{Addr: "smtp.dutyme.com:587"},
+		},
+		Reason: &Reason{TicketPattern: "OPS-("},
+		Policy: &Policy{Hours: "9am-6pm", Weekdays: []string{"mon", "funday"}, Timezone: "Mars/Olympus"},
+	}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("expect Validate to fail")
+	}
+
+	for _, want := range []string{"working", "schedule_name", "notify.webhooks[0].url", "notify.email", "reason.ticket_pattern", "policy.hours", "policy.weekdays", "policy.timezone"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q should mention %q", err, want)
+		}
+	}
+}
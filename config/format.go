@@ -0,0 +1,189 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Format is the file format of configuration.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// Extensions are the file extensions of configuration in the order of
+// lookup.
+var Extensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// FormatOf returns the format of the configuration file by its
+// extension. Unknown extensions are JSON.
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// FindFile returns the first existing file which is the base path with
+// one of Extensions (e.g., "~/.config/dutyme/config"). If nothing is
+// found, it returns empty.
+func FindFile(base string) string {
+	for _, ext := range Extensions {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return ""
+}
+
+// decode decodes the file content in the format into JSON object.
+// Values are normalized as JSON (e.g., numbers are json.Number) so
+// that all formats are handled in the same way.
+func decode(buf []byte, format Format) (map[string]interface{}, error) {
+	var v interface{}
+	switch format {
+	case FormatYAML:
+		var m map[interface{}]interface{}
+		if err := yaml.Unmarshal(buf, &m); err != nil {
+			return nil, err
+		}
+		v = jsonCompatible(m)
+	case FormatTOML:
+		var m map[string]interface{}
+		if _, err := toml.Decode(string(buf), &m); err != nil {
+			return nil, err
+		}
+		v = m
+	default:
+		raw := make(map[string]interface{})
+		if err := decodeJSON(buf, &raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+	if err := decodeJSON(buf, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// encode encodes JSON object in the format.
+func encode(raw map[string]interface{}, format Format) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(native(raw, false))
+	case FormatTOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(native(raw, true)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		buf, err := json.MarshalIndent(raw, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, '\n'), nil
+	}
+}
+
+// jsonCompatible converts YAML maps (which keys are interface{}) into
+// JSON objects.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprintf("%v", k)] = jsonCompatible(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonCompatible(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// native converts JSON values into the ones which YAML and TOML encoders
+// handle (e.g., json.Number into int64). TOML has no null and arrays of
+// tables must be typed, so forTOML drops nulls and types them.
+func native(v interface{}, forTOML bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			if e == nil && forTOML {
+				continue
+			}
+			m[k] = native(e, forTOML)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		tables := forTOML && len(v) != 0
+		for i, e := range v {
+			list[i] = native(e, forTOML)
+			if _, ok := list[i].(map[string]interface{}); !ok {
+				tables = false
+			}
+		}
+
+		if tables {
+			typed := make([]map[string]interface{}, len(list))
+			for i, e := range list {
+				typed[i] = e.(map[string]interface{})
+			}
+			return typed
+		}
+		return list
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// writeFile writes the content to the path creating its directory
+// (e.g., ~/.config/dutyme) if it doesn't exist.
+func writeFile(path string, buf []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "failed to create directory")
+	}
+
+	if err := ioutil.WriteFile(path, buf, 0600); err != nil {
+		return errors.Wrap(err, "failed to write file")
+	}
+	return nil
+}
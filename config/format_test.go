@@ -0,0 +1,126 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/tcnksm/dutyme/dutyme"
+)
+
+func TestFormatOf(t *testing.T) {
+	cases := map[string]Format{
+		"config.json":          FormatJSON,
+		"config.yaml":          FormatYAML,
+		"config.YML":           FormatYAML,
+		"config.toml":          FormatTOML,
+		"/home/taichi/.dutyme": FormatJSON,
+	}
+	for path, want := range cases {
+		if got := FormatOf(path); got != want {
+			t.Fatalf("FormatOf(%q)=%q, want=%q", path, got, want)
+		}
+	}
+}
+
+func TestParseFile_formats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"config.yaml": `# shared in dotfiles
+schedule_id: PI7DH85 # primary
+working: 2h
+notify:
+  slack:
+    - url: https://hooks.slack.com/services/org
+      channel: "#oncall"
+`,
+		"config.toml": `# shared in dotfiles
+schedule_id = "PI7DH85"
+working = "2h"
+
+[[notify.slack]]
+url = "https://hooks.slack.com/services/org"
+channel = "#oncall"
+`,
+	}
+
+	for name, content := range files {
+		cfg, err := ParseFile(testWriteFile(t, dir, name, content))
+		if err != nil {
+			t.Fatalf("ParseFile(%s) failed: %s", name, err)
+		}
+
+		if cfg.ScheduleID != "PI7DH85" || time.Duration(cfg.Working) != 2*time.Hour {
+			t.Fatalf("ParseFile(%s)=%#v", name, cfg)
+		}
+
+		if cfg.Notify == nil || len(cfg.Notify.Slack) != 1 || cfg.Notify.Slack[0].Channel != "#oncall" {
+			t.Fatalf("ParseFile(%s) Notify=%#v", name, cfg.Notify)
+		}
+	}
+}
+
+func TestSetFile_formats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"config.yaml": "working: 1h\nunknown:\n  kept: 3\n",
+		"config.toml": "working = \"1h\"\n\n[unknown]\nkept = 3\n",
+	}
+
+	for name, content := range files {
+		path := testWriteFile(t, dir, name, content)
+		if err := SetFile(path, "notify.slack", `[{"url": "https://hooks.slack.com/services/org"}]`); err != nil {
+			t.Fatalf("SetFile(%s) failed: %s", name, err)
+		}
+
+		user := &dutyme.User{
+			Email: "taichi.nakashima@dutyme.com",
+			Obj:   &pagerduty.APIObject{ID: "PXPGF42", Summary: "Taichi Nakashima"},
+		}
+		cfg := &Config{Token: "secret", User: user}
+		if err := cfg.UpdateFile(path, "token", "user", "schedule_id"); err != nil {
+			t.Fatalf("UpdateFile(%s) failed: %s", name, err)
+		}
+
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal("ReadFile failed:", err)
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(string(buf)), "{") {
+			t.Fatalf("%s is written as JSON:\n%s", name, buf)
+		}
+
+		raw, err := readFile(path)
+		if err != nil {
+			t.Fatalf("readFile(%s) failed: %s\n%s", name, err, buf)
+		}
+
+		if v, ok := lookup(raw, []string{"unknown", "kept"}); !ok || v.(interface{ String() string }).String() != "3" {
+			t.Fatalf("unknown key in %s is lost:\n%s", name, buf)
+		}
+
+		cfg, err = ParseFile(path)
+		if err != nil {
+			t.Fatalf("ParseFile(%s) failed: %s", name, err)
+		}
+
+		if cfg.Token != "secret" || cfg.User == nil || cfg.User.Obj == nil || cfg.User.Obj.ID != "PXPGF42" || time.Duration(cfg.Working) != time.Hour ||
+			cfg.Notify == nil || len(cfg.Notify.Slack) != 1 {
+			t.Fatalf("%s is not written back correctly:\n%s", name, buf)
+		}
+	}
+}
@@ -0,0 +1,470 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Origin is the layer which the configuration value comes from.
+type Origin string
+
+// Layers in the order of precedence (later one wins).
+const (
+	OriginSystem  Origin = "system"
+	OriginUser    Origin = "user"
+	OriginProject Origin = "project"
+	OriginEnv     Origin = "env"
+	OriginFlag    Origin = "flag"
+)
+
+// EnvPrefix is the prefix of env vars which set configuration values.
+// The rest of the name is the key in upper case where "." is replaced
+// with "_" (e.g., DUTYME_REMINDER_BEFORE for "reminder.before").
+const EnvPrefix = "DUTYME_"
+
+// SystemPath returns the path of the system-wide configuration which is
+// shipped by organization (e.g., notifiers). It's config.json or the one
+// in other format (e.g., config.yaml) if it exists.
+func SystemPath() string {
+	base := "/etc/dutyme/config"
+	if runtime.GOOS == "windows" {
+		base = filepath.Join(os.Getenv("ProgramData"), "dutyme", "config")
+	}
+
+	if path := FindFile(base); len(path) != 0 {
+		return path
+	}
+	return base + ".json"
+}
+
+// Value is the effective configuration value and where it comes from.
+type Value struct {
+	Key    string
+	Value  interface{}
+	Origin Origin
+
+	// Path is the file path of the layer (empty for env and flag).
+	Path string
+}
+
+// layer is one configuration source. Values are flattened by
+// dot-separated keys (e.g., "hooks.pre_start") and merged by key.
+type layer struct {
+	origin Origin
+	path   string
+	values map[string]interface{}
+
+	// ignored is the keys which are dropped because the project
+	// configuration is not trusted.
+	ignored []string
+}
+
+// Layered is the configuration which is merged from layers.
+type Layered struct {
+	layers []*layer
+}
+
+// AddFile adds the configuration file as the layer. If the file doesn't
+// exist, it's ignored. Files of older format are migrated in memory.
+// Project configuration must not contain token or user because it's
+// committed to the repository. Its hooks, reminder command and webhook
+// and notifiers are ignored unless it's trusted by system or user's
+// configuration (see TrustProject).
+func (l *Layered) AddFile(origin Origin, path string) error {
+	raw, err := readFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Version belongs to each file and is not merged.
+	delete(raw, "version")
+
+	values := make(map[string]interface{})
+	flatten("", raw, values)
+
+	ly := &layer{origin: origin, path: path, values: values}
+	if origin == OriginProject {
+		for key := range values {
+			if key == "token" || key == "user" || strings.HasPrefix(key, "user.") {
+				return errors.Errorf("project configuration %s must not contain token or user", path)
+			}
+			if key == trustKey {
+				return errors.Errorf("project configuration %s must not contain %s", path, trustKey)
+			}
+		}
+
+		hash, err := ProjectHash(path)
+		if err != nil {
+			return err
+		}
+
+		if !l.trusted(hash) {
+			for key := range values {
+				if isCommandKey(key) {
+					ly.ignored = append(ly.ignored, key)
+					delete(values, key)
+				}
+			}
+			sort.Strings(ly.ignored)
+		}
+	}
+
+	l.layers = append(l.layers, ly)
+	return nil
+}
+
+// Untrusted returns the path of the project configuration which is not
+// trusted and the keys which are ignored for it. If no keys are
+// ignored, it returns empty.
+func (l *Layered) Untrusted() (string, []string) {
+	for _, ly := range l.layers {
+		if len(ly.ignored) != 0 {
+			return ly.path, ly.ignored
+		}
+	}
+	return "", nil
+}
+
+// AddEnv adds DUTYME_* env vars in the given environment (os.Environ
+// format) as the layer. Lists of strings are given as comma-separated
+// values and the other non-string values as JSON. Empty values are
+// ignored (same as unset).
+func (l *Layered) AddEnv(environ []string) error {
+	leaves := Keys()
+	values := make(map[string]interface{})
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i < 0 || !strings.HasPrefix(kv[:i], EnvPrefix) {
+			continue
+		}
+
+		key, ok := envKeys[kv[:i]]
+		if !ok || len(kv[i+1:]) == 0 {
+			continue
+		}
+
+		v, err := parseValue(leaves[key], kv[i+1:])
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s", kv[:i])
+		}
+		values[key] = v
+	}
+
+	if len(values) != 0 {
+		l.layers = append(l.layers, &layer{origin: OriginEnv, values: values})
+	}
+	return nil
+}
+
+// Set sets the value of the key on the layer of the given origin (e.g.,
+// value given by command line flag or legacy env var).
+func (l *Layered) Set(origin Origin, key string, value interface{}) {
+	for _, ly := range l.layers {
+		if ly.origin == origin && ly.path == "" {
+			ly.values[key] = value
+			return
+		}
+	}
+
+	l.layers = append(l.layers, &layer{
+		origin: origin,
+		values: map[string]interface{}{key: value},
+	})
+}
+
+// Values returns the effective values sorted by key.
+func (l *Layered) Values() []Value {
+	layers := make([]*layer, len(l.layers))
+	copy(layers, l.layers)
+	sort.Stable(byPrecedence(layers))
+
+	system := systemPolicy(layers)
+	effective := make(map[string]Value)
+	for _, ly := range layers {
+		for key, v := range ly.values {
+			if ly.origin != OriginSystem && isPolicyKey(key) {
+				var ok bool
+				if v, ok = tightenPolicy(system, key, v); !ok {
+					continue
+				}
+			}
+
+			// Lists and objects are replaced as a whole by
+			// the upper layer.
+			for k := range effective {
+				if strings.HasPrefix(k, key+".") {
+					delete(effective, k)
+				}
+			}
+			effective[key] = Value{Key: key, Value: v, Origin: ly.origin, Path: ly.path}
+		}
+
+		// Schedule name belongs to schedule ID. When upper layer
+		// changes only the ID, the name of lower layer is wrong.
+		if _, ok := ly.values["schedule_id"]; ok {
+			if _, ok := ly.values["schedule_name"]; !ok {
+				delete(effective, "schedule_name")
+			}
+		}
+	}
+
+	values := make([]Value, 0, len(effective))
+	for _, v := range effective {
+		values = append(values, v)
+	}
+	sort.Sort(byKey(values))
+	return values
+}
+
+// Origin returns the effective value of the given key. If it's not
+// set in any layer, it returns false.
+func (l *Layered) Origin(key string) (Value, bool) {
+	for _, v := range l.Values() {
+		if v.Key == key {
+			return v, true
+		}
+	}
+	return Value{}, false
+}
+
+// Config returns the merged configuration.
+func (l *Layered) Config() (*Config, error) {
+	raw := make(map[string]interface{})
+	for _, v := range l.Values() {
+		unflatten(raw, v.Key, v.Value)
+	}
+
+	buf, err := json.Marshal(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode merged configuration")
+	}
+
+	cfg, err := parse(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Version = Version
+	return cfg, nil
+}
+
+// flatten flattens JSON objects into dot-separated keys.
+func flatten(prefix string, raw map[string]interface{}, values map[string]interface{}) {
+	for k, v := range raw {
+		key := k
+		if len(prefix) != 0 {
+			key = prefix + "." + k
+		}
+
+		if obj, ok := v.(map[string]interface{}); ok && len(obj) != 0 {
+			flatten(key, obj, values)
+			continue
+		}
+		values[key] = v
+	}
+}
+
+// unflatten sets the value to the nested object by dot-separated key.
+func unflatten(raw map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := raw[p].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			raw[p] = child
+		}
+		raw = child
+	}
+	raw[parts[len(parts)-1]] = value
+}
+
+// decodeJSON decodes JSON keeping numbers as they are.
+func decodeJSON(buf []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+var (
+	durationType = reflect.TypeOf(Duration(0))
+	stringsType  = reflect.TypeOf([]string{})
+)
+
+// parseValue parses the string (e.g., env var) as the value of the type.
+func parseValue(t reflect.Type, s string) (interface{}, error) {
+	switch {
+	case t == durationType:
+		var d Duration
+		if err := d.UnmarshalJSON([]byte(`"` + s + `"`)); err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			return nil, errors.New("duration must be positive")
+		}
+		return s, nil
+	case t.Kind() == reflect.String:
+		return s, nil
+	case t == stringsType && !strings.HasPrefix(strings.TrimSpace(s), "["):
+		var list []interface{}
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.TrimSpace(v); len(v) != 0 {
+				list = append(list, v)
+			}
+		}
+		return list, nil
+	default:
+		var v interface{}
+		if err := decodeJSON([]byte(s), &v); err != nil {
+			return nil, errors.Wrap(err, "value must be JSON")
+		}
+		return v, nil
+	}
+}
+
+// Keys returns all configuration keys and their types. Structs are
+// walked into and the other types (including lists) are leaves.
+func Keys() map[string]reflect.Type {
+	keys := make(map[string]reflect.Type)
+	walkKeys("", reflect.TypeOf(Config{}), keys)
+
+	// Version is not a setting but the format of each file.
+	delete(keys, "version")
+	return keys
+}
+
+func walkKeys(prefix string, t reflect.Type, keys map[string]reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) != 0 {
+			continue
+		}
+
+		name := f.Name
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if len(tag) != 0 {
+			name = tag
+		}
+
+		key := name
+		if len(prefix) != 0 {
+			key = prefix + "." + name
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct {
+			walkKeys(key, ft, keys)
+			continue
+		}
+		keys[key] = f.Type
+	}
+}
+
+// keyType returns the type of the given key. Unlike Keys, it resolves
+// keys in maps (e.g., "presets.deploy.working") by their element type.
+// Objects (e.g., "hooks") are not keys.
+func keyType(key string) (reflect.Type, bool) {
+	if t, ok := Keys()[key]; ok {
+		return t, true
+	}
+
+	t := reflect.TypeOf(Config{})
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		switch t.Kind() {
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			f, ok := fieldByTag(t, part)
+			if !ok {
+				return nil, false
+			}
+			t = f.Type
+		default:
+			return nil, false
+		}
+
+		if i == len(parts)-1 {
+			elem := t
+			for elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			return t, elem.Kind() != reflect.Struct
+		}
+	}
+	return nil, false
+}
+
+// fieldByTag returns the field of the struct by its JSON name.
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) != 0 {
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if tag == name || (len(tag) == 0 && f.Name == name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// EnvName returns the name of env var which sets the given key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// envKeys is the configuration keys by their env var names.
+var envKeys = func() map[string]string {
+	m := make(map[string]string)
+	for key := range Keys() {
+		m[EnvName(key)] = key
+	}
+	return m
+}()
+
+// precedence is the order of origins.
+var precedence = map[Origin]int{
+	OriginSystem:  0,
+	OriginUser:    1,
+	OriginProject: 2,
+	OriginEnv:     3,
+	OriginFlag:    4,
+}
+
+type byPrecedence []*layer
+
+func (l byPrecedence) Len() int           { return len(l) }
+func (l byPrecedence) Less(i, j int) bool { return precedence[l[i].origin] < precedence[l[j].origin] }
+func (l byPrecedence) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+type byKey []Value
+
+func (v byKey) Len() int           { return len(v) }
+func (v byKey) Less(i, j int) bool { return v[i].Key < v[j].Key }
+func (v byKey) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
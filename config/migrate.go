@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// migrations upgrade the configuration object from version i to i+1.
+// When the format is changed incompatibly (e.g., a key is moved), add
+// the migration here. Version is bumped automatically.
+var migrations = []func(raw map[string]interface{}) error{
+	// 0 -> 1: files written before versioning. The format is the
+	// same, it's just stamped.
+	func(raw map[string]interface{}) error { return nil },
+}
+
+// Version is the version of the configuration format which this
+// dutyme reads and writes.
+var Version = len(migrations)
+
+// NewerVersionError is returned when the configuration file is written
+// by newer dutyme than the running one.
+type NewerVersionError struct {
+	Path    string
+	Version int
+}
+
+func (e *NewerVersionError) Error() string {
+	return fmt.Sprintf("%s is written by newer dutyme (format version %d, this dutyme supports up to %d): upgrade dutyme",
+		e.Path, e.Version, Version)
+}
+
+// fileVersion returns the format version of the configuration object.
+// Files without version are version 0.
+func fileVersion(raw map[string]interface{}) (int, error) {
+	v, ok := raw["version"]
+	if !ok {
+		return 0, nil
+	}
+
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, errors.Errorf("version must be number: %v", v)
+	}
+
+	version, err := n.Int64()
+	if err != nil || version < 0 {
+		return 0, errors.Errorf("invalid version: %s", n)
+	}
+	return int(version), nil
+}
+
+// migrate upgrades the configuration object read from the path to the
+// current Version and returns the version which it was.
+func migrate(path string, raw map[string]interface{}) (int, error) {
+	from, err := fileVersion(raw)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read version of %s", path)
+	}
+
+	if from > Version {
+		return 0, &NewerVersionError{Path: path, Version: from}
+	}
+
+	for v := from; v < Version; v++ {
+		if err := migrations[v](raw); err != nil {
+			return 0, errors.Wrapf(err, "failed to migrate %s from version %d to %d", path, v, v+1)
+		}
+	}
+
+	raw["version"] = Version
+	return from, nil
+}
+
+// readFile reads the configuration file in the format of its extension
+// as JSON object which is migrated to the current version.
+func readFile(path string) (map[string]interface{}, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := decode(buf, FormatOf(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+
+	if _, err := migrate(path, raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// MigrateFile upgrades the configuration file written by older dutyme
+// in place (in the same format). The original is kept as
+// "<path>.v<version>.bak" and its path is returned. If the file doesn't
+// exist or it's already the current version, it does nothing and
+// returns empty.
+func MigrateFile(path string) (string, error) {
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read file")
+	}
+
+	raw, err := decode(buf, FormatOf(path))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse %s", path)
+	}
+
+	from, err := migrate(path, raw)
+	if err != nil {
+		return "", err
+	}
+
+	if from == Version {
+		return "", nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	if err := ioutil.WriteFile(backup, buf, 0600); err != nil {
+		return "", errors.Wrap(err, "failed to write backup")
+	}
+
+	if err := writeRaw(path, raw); err != nil {
+		return "", err
+	}
+
+	return backup, nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestMigrateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	original := `{"token": "secret", "schedule_id": "PI7DH85", "working": "2h"}`
+	path := testWriteFile(t, dir, "config.json", original)
+
+	backup, err := MigrateFile(path)
+	if err != nil {
+		t.Fatal("MigrateFile failed:", err)
+	}
+
+	if want := path + ".v0.bak"; backup != want {
+		t.Fatalf("backup=%q, want=%q", backup, want)
+	}
+
+	buf, err := ioutil.ReadFile(backup)
+	if err != nil {
+		t.Fatal("ReadFile failed:", err)
+	}
+	if string(buf) != original {
+		t.Fatalf("backup=%s, want=%s", buf, original)
+	}
+
+	cfg, err := ParseFile(path)
+	if err != nil {
+		t.Fatal("ParseFile failed:", err)
+	}
+	if cfg.Version != Version || cfg.ScheduleID != "PI7DH85" {
+		t.Fatalf("Config=%#v, want migrated to version %d", cfg, Version)
+	}
+
+	// Already the current version.
+	backup, err = MigrateFile(path)
+	if err != nil {
+		t.Fatal("MigrateFile failed:", err)
+	}
+	if backup != "" {
+		t.Fatalf("backup=%q, want no migration", backup)
+	}
+
+	// Not exist.
+	if _, err := MigrateFile(path + ".not-exist"); err != nil {
+		t.Fatal("MigrateFile failed:", err)
+	}
+}
+
+func TestMigrateFile_newer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	newer := `{"version": 100, "profiles": {}}`
+	path := testWriteFile(t, dir, "config.json", newer)
+
+	_, err = MigrateFile(path)
+	if _, ok := errors.Cause(err).(*NewerVersionError); !ok {
+		t.Fatalf("err=%v, want NewerVersionError", err)
+	}
+
+	if !strings.Contains(err.Error(), "upgrade dutyme") {
+		t.Fatalf("err=%q should tell to upgrade", err)
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal("ReadFile failed:", err)
+	}
+	if string(buf) != newer {
+		t.Fatalf("newer file must not be changed: %s", buf)
+	}
+
+	layers := &Layered{}
+	if err := layers.AddFile(OriginProject, path); err == nil {
+		t.Fatal("expect AddFile to fail")
+	}
+
+	if err := SetFile(path, "working", "1h"); err == nil {
+		t.Fatal("expect SetFile to fail")
+	}
+}
+
+func TestLayered_version(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	layers := &Layered{}
+	if err := layers.AddFile(OriginUser, testWriteFile(t, dir, "user.json", `{"working": "1h"}`)); err != nil {
+		t.Fatal("AddFile failed:", err)
+	}
+	if err := layers.AddFile(OriginProject, testWriteFile(t, dir, "project.json", `{"version": 1}`)); err != nil {
+		t.Fatal("AddFile failed:", err)
+	}
+
+	if _, ok := layers.Origin("version"); ok {
+		t.Fatal("version must not be merged")
+	}
+
+	cfg, err := layers.Config()
+	if err != nil {
+		t.Fatal("Config failed:", err)
+	}
+	if cfg.Version != Version {
+		t.Fatalf("Version=%d, want=%d", cfg.Version, Version)
+	}
+}
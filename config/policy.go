@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// policyKey is the key of the policy object.
+const policyKey = "policy"
+
+// isPolicyKey returns true if the key is policy or its child.
+func isPolicyKey(key string) bool {
+	return key == policyKey || strings.HasPrefix(key, policyKey+".")
+}
+
+// systemPolicy returns the policy values set by the system layers.
+func systemPolicy(layers []*layer) map[string]interface{} {
+	policy := make(map[string]interface{})
+	for _, ly := range layers {
+		if ly.origin != OriginSystem {
+			continue
+		}
+		for key, v := range ly.values {
+			if isPolicyKey(key) {
+				policy[key] = v
+			}
+		}
+	}
+	return policy
+}
+
+// tightenPolicy returns the value of the policy key set by the layer
+// upper than system. Policy of the system layer is the guardrail of the
+// organization, so the upper layers (user, project, env and flag) can
+// only tighten it: a shorter max_duration, more protected schedules or
+// the keys which the system layer doesn't set. Otherwise, it returns
+// false and the value is ignored.
+func tightenPolicy(system map[string]interface{}, key string, v interface{}) (interface{}, bool) {
+	if len(system) == 0 {
+		return v, true
+	}
+
+	sv, set := system[key]
+	switch key {
+	case policyKey:
+		// Replacing the whole object drops the system policy.
+		return nil, false
+	case policyKey + ".max_duration":
+		if !set {
+			return v, true
+		}
+		max, ok := policyDuration(sv)
+		if !ok {
+			return nil, false
+		}
+		d, ok := policyDuration(v)
+		if !ok || d <= 0 || d >= max {
+			return nil, false
+		}
+		return v, true
+	case policyKey + ".protected":
+		if !set {
+			return v, true
+		}
+		return union(sv, v), true
+	case policyKey + ".timezone":
+		// Timezone moves the window of hours and weekdays.
+		_, hours := system[policyKey+".hours"]
+		_, weekdays := system[policyKey+".weekdays"]
+		if set || hours || weekdays {
+			return nil, false
+		}
+		return v, true
+	default:
+		if set {
+			return nil, false
+		}
+		return v, true
+	}
+}
+
+func policyDuration(v interface{}) (time.Duration, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// union returns the list which has the items of both lists.
+func union(a, b interface{}) interface{} {
+	var list []interface{}
+	seen := make(map[string]bool)
+	for _, v := range []interface{}{a, b} {
+		items, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, item := range items {
+			if seen[fmt.Sprint(item)] {
+				continue
+			}
+			seen[fmt.Sprint(item)] = true
+			list = append(list, item)
+		}
+	}
+	return list
+}
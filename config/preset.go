@@ -0,0 +1,55 @@
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Preset is the named combination of override settings which is used
+// repeatedly (e.g., "deploy" overrides primary and secondary schedules
+// for 2 hours). It's given to start command like `dutyme start deploy`
+// and options given by flags override its fields.
+type Preset struct {
+	// Schedules are IDs of the schedules to override. If empty, the
+	// configured schedule is used.
+	Schedules []string `json:"schedules,omitempty"`
+
+	// Working is duration of override.
+	Working Duration `json:"working,omitempty"`
+
+	// Exclusive fails when other users hold overrides on the schedules.
+	// With Wait, it waits until they end up to Wait.
+	Exclusive bool     `json:"exclusive,omitempty"`
+	Wait      Duration `json:"wait,omitempty"`
+
+	// Notify is notifiers which are notified in addition to the
+	// configured ones (e.g., #deploy channel).
+	Notify *Notify `json:"notify,omitempty"`
+
+	// RequireReason requires reason of overriding (-reason or -ticket).
+	RequireReason bool `json:"require_reason,omitempty"`
+}
+
+// Preset returns the preset of the given name.
+func (c *Config) Preset(name string) (*Preset, error) {
+	if p, ok := c.Presets[name]; ok && p != nil {
+		return p, nil
+	}
+
+	if len(c.Presets) == 0 {
+		return nil, errors.Errorf("no such preset %q (no presets are configured)", name)
+	}
+	return nil, errors.Errorf("no such preset %q (available: %s)", name, strings.Join(c.PresetNames(), ", "))
+}
+
+// PresetNames returns the names of configured presets in order.
+func (c *Config) PresetNames() []string {
+	names := make([]string, 0, len(c.Presets))
+	for name := range c.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_Preset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := testWriteFile(t, dir, "config.yaml", `presets:
+  deploy:
+    schedules: [PI7DH85, PI9DH21]
+    working: 2h
+  db-migration:
+    schedules: [PDBMIGR]
+    working: 4h
+    exclusive: true
+    require_reason: true
+`)
+
+	if err := SetFile(path, "presets.deploy.wait", "10m"); err != nil {
+		t.Fatal("SetFile failed:", err)
+	}
+
+	if err := SetFile(path, "presets.deploy.no_such_key", "value"); err == nil {
+		t.Fatal("expect SetFile of unknown preset key to fail")
+	}
+
+	cfg, err := ParseFile(path)
+	if err != nil {
+		t.Fatal("ParseFile failed:", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatal("Validate failed:", err)
+	}
+
+	deploy, err := cfg.Preset("deploy")
+	if err != nil {
+		t.Fatal("Preset failed:", err)
+	}
+
+	if len(deploy.Schedules) != 2 || time.Duration(deploy.Working) != 2*time.Hour || time.Duration(deploy.Wait) != 10*time.Minute {
+		t.Fatalf("deploy = %#v", deploy)
+	}
+
+	migration, err := cfg.Preset("db-migration")
+	if err != nil {
+		t.Fatal("Preset failed:", err)
+	}
+
+	if !migration.Exclusive || !migration.RequireReason {
+		t.Fatalf("db-migration = %#v", migration)
+	}
+
+	_, err = cfg.Preset("rollback")
+	if err == nil || !strings.Contains(err.Error(), "db-migration, deploy") {
+		t.Fatalf("Preset of unknown name = %v, want error with available names", err)
+	}
+}
+
+func TestConfig_Validate_presets(t *testing.T) {
+	cfg := &Config{
+		Presets: map[string]*Preset{
+			"deploy": {
+				Schedules: []string{""},
+				Notify:    &Notify{Slack: []SlackNotify{{Channel: "#deploy"}}},
+			},
+		},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expect Validate to fail")
+	}
+
+	for _, want := range []string{"presets.deploy.schedules", "presets.deploy: notify.slack[0].url"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q should mention %q", err, want)
+		}
+	}
+}
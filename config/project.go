@@ -0,0 +1,156 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ProjectName is the file name of the project configuration which is
+// placed in the repository (e.g., to target the schedule of the service
+// which the repository deploys). It can be also YAML or TOML (e.g.,
+// .dutyme.yaml).
+const ProjectName = ".dutyme.json"
+
+// FindProject walks up from the given directory to the root of the git
+// repository which it belongs to and returns the path of the nearest
+// project configuration. If the directory is not in a repository or no
+// project configuration is found, it returns empty string. skip is the
+// path which is never returned (e.g., user's configuration file when
+// home directory is a repository).
+func FindProject(dir, skip string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get abs path")
+	}
+
+	root := repoRoot(dir)
+	if len(root) == 0 {
+		return "", nil
+	}
+
+	if len(skip) != 0 {
+		if skip, err = filepath.Abs(skip); err != nil {
+			return "", errors.Wrap(err, "failed to get abs path")
+		}
+	}
+
+	for {
+		base := strings.TrimSuffix(ProjectName, filepath.Ext(ProjectName))
+		for _, ext := range Extensions {
+			path := filepath.Join(dir, base+ext)
+			if _, err := os.Stat(path); err == nil && path != skip {
+				return path, nil
+			}
+		}
+
+		if dir == root {
+			return "", nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// trustKey is the key of the hashes of the trusted project
+// configuration files.
+const trustKey = "trusted_projects"
+
+// ProjectHash returns the hash of the project configuration file which
+// is used for trusting it ("sha256:<hex>"). Changing the file requires
+// trusting it again.
+func ProjectHash(path string) (string, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(buf)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// TrustProject adds the hash of the project configuration file to the
+// trusted ones in the (user's) configuration file.
+func TrustProject(path, projectPath string) error {
+	hash, err := ProjectHash(projectPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to read project configuration")
+	}
+
+	raw, err := readRaw(path)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+	flatten("", raw, values)
+
+	trusted, _ := values[trustKey].([]interface{})
+	for _, h := range trusted {
+		if h == hash {
+			return nil
+		}
+	}
+
+	unflatten(raw, trustKey, append(trusted, hash))
+	return writeRaw(path, raw)
+}
+
+// isCommandKey returns true if the key runs commands or sends
+// notifications (hooks, reminder command and webhook and notifiers
+// including the ones of presets). Project configuration can't set them
+// unless it's trusted because it comes from the repository.
+func isCommandKey(key string) bool {
+	parts := strings.Split(key, ".")
+	if parts[0] == "presets" && len(parts) > 2 {
+		parts = parts[2:]
+	}
+
+	switch parts[0] {
+	case "hooks", "notify":
+		return true
+	case "reminder":
+		return len(parts) == 1 || parts[1] == "command" || parts[1] == "webhook"
+	}
+	return false
+}
+
+// trusted returns true if the hash of the project configuration is in
+// the trusted ones of system or user's configuration.
+func (l *Layered) trusted(hash string) bool {
+	for _, ly := range l.layers {
+		if ly.origin != OriginSystem && ly.origin != OriginUser {
+			continue
+		}
+
+		list, _ := ly.values[trustKey].([]interface{})
+		for _, h := range list {
+			if fmt.Sprint(h) == hash {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// repoRoot returns the root of the git repository which the given
+// directory belongs to (.git is a directory or, for worktrees and
+// submodules, a file). If it's not in a repository, it returns empty.
+func repoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
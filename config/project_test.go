@@ -0,0 +1,69 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo := filepath.Join(dir, "payments")
+	sub := filepath.Join(repo, "cmd", "server")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal("MkdirAll failed:", err)
+	}
+
+	// Configuration outside of repository is never used.
+	outside := filepath.Join(dir, ProjectName)
+	if err := ioutil.WriteFile(outside, []byte(`{}`), 0644); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+
+	if path, err := FindProject(sub, ""); err != nil || path != "" {
+		t.Fatalf("FindProject not in repository = %q, %v, want empty", path, err)
+	}
+
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal("Mkdir failed:", err)
+	}
+
+	if path, err := FindProject(sub, ""); err != nil || path != "" {
+		t.Fatalf("FindProject without project configuration = %q, %v, want empty", path, err)
+	}
+
+	want := filepath.Join(repo, ProjectName)
+	if err := ioutil.WriteFile(want, []byte(`{"schedule_id": "PI7DH85"}`), 0644); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+
+	path, err := FindProject(sub, "")
+	if err != nil {
+		t.Fatal("FindProject failed:", err)
+	}
+
+	if path != want {
+		t.Fatalf("FindProject = %q, want %q", path, want)
+	}
+
+	// e.g., user's configuration file in home directory repository.
+	if path, err := FindProject(sub, want); err != nil || path != "" {
+		t.Fatalf("FindProject with skip = %q, %v, want empty", path, err)
+	}
+
+	// Nearer one is used whatever its format is.
+	yaml := filepath.Join(sub, ".dutyme.yaml")
+	if err := ioutil.WriteFile(yaml, []byte("schedule_id: PI3DH03\n"), 0644); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+
+	if path, err := FindProject(sub, want); err != nil || path != yaml {
+		t.Fatalf("FindProject = %q, %v, want %q", path, err, yaml)
+	}
+}
@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+)
+
+const (
+	// DefaultAutoExtendStep is default duration of one auto-extension.
+	DefaultAutoExtendStep = 30 * time.Minute
+
+	// DefaultAutoExtendMax is default cap of total auto-extended
+	// duration of an override.
+	DefaultAutoExtendMax = 4 * time.Hour
+)
+
+// Extension is the result of auto-extending.
+type Extension struct {
+	PreviousID string
+	OverrideID string
+	End        time.Time
+
+	// Incidents is the open incidents which cause extending.
+	Incidents []string
+
+	// Total is total auto-extended duration so far.
+	Total time.Duration
+	Max   time.Duration
+}
+
+// String returns human readable message of the extension.
+func (e *Extension) String() string {
+	return fmt.Sprintf("Auto-extended override %s to %s (new override %s) because of open incidents %s (%s of %s)",
+		e.PreviousID, e.End.Local().Format("15:04"), e.OverrideID,
+		strings.Join(e.Incidents, ", "), e.Total, e.Max)
+}
+
+// AutoExtender extends the override which is about to expire while
+// the user has open (triggered or acknowledged) incidents on the
+// services of the schedule.
+type AutoExtender struct {
+	Dutyme *dutyme.Dutyme
+	User   *dutyme.User
+
+	// Step is the duration of one extension. If it's zero,
+	// DefaultAutoExtendStep is used.
+	Step time.Duration
+
+	// Max is the cap of total extended duration of an override. It's
+	// counted from the end of the first override of the extended chain
+	// on journal, so it's kept across daemon restarts (and it includes
+	// manual extensions). If it's zero, DefaultAutoExtendMax is used.
+	Max time.Duration
+}
+
+// Extend extends the given override by Step when the user has open
+// incidents and it doesn't exceed Max. It returns nil when the override
+// is not extended.
+func (a *AutoExtender) Extend(e journal.Event) (*Extension, error) {
+	step, max := a.Step, a.Max
+	if step <= 0 {
+		step = DefaultAutoExtendStep
+	}
+	if max <= 0 {
+		max = DefaultAutoExtendMax
+	}
+
+	// Journal is shared with the other users' overrides.
+	if !dutyme.SameUser(e, a.User) {
+		return nil, nil
+	}
+
+	total, err := a.extended(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if total+step > max {
+		return nil, nil
+	}
+
+	incidents, err := a.Dutyme.OpenIncidents(e.ScheduleID, a.User)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(incidents) == 0 {
+		return nil, nil
+	}
+
+	override, end, err := a.Dutyme.Extend(e, a.User, step)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := &Extension{
+		PreviousID: e.OverrideID,
+		OverrideID: override.ID,
+		End:        end,
+		Total:      total + step,
+		Max:        max,
+	}
+	for _, incident := range incidents {
+		ext.Incidents = append(ext.Incidents, fmt.Sprintf("#%d", incident.IncidentNumber))
+	}
+
+	return ext, nil
+}
+
+// extended returns the total extended duration of the given override:
+// how much its end is later than the end of the first override of the
+// chain. If the chain is not recorded on journal, it returns zero.
+func (a *AutoExtender) extended(e journal.Event) (time.Duration, error) {
+	if a.Dutyme.Journal == nil {
+		return 0, nil
+	}
+
+	events, err := a.Dutyme.Journal.Events()
+	if err != nil {
+		return 0, err
+	}
+
+	first := journal.First(events, e.OverrideID)
+	if first == nil || !e.End.After(first.End) {
+		return 0, nil
+	}
+	return e.End.Sub(first.End), nil
+}
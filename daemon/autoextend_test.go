@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/pdtest"
+	"github.com/tcnksm/dutyme/state"
+)
+
+// testPD returns fake PagerDuty where schedules belong to the services
+// through the escalation policy.
+func testPD() *pdtest.PD {
+	return &pdtest.PD{
+		Schedules: map[string]*pagerduty.Schedule{
+			"S1": {
+				APIObject:          pagerduty.APIObject{ID: "S1"},
+				EscalationPolicies: []pagerduty.APIObject{{ID: "PEP4567"}},
+			},
+		},
+		EscalationPolicies: map[string]*pagerduty.EscalationPolicy{
+			"PEP4567": {
+				APIObject: pagerduty.APIObject{ID: "PEP4567"},
+				Services:  []pagerduty.APIReference{{ID: "PSVC123"}},
+			},
+		},
+	}
+}
+
+func TestDaemon_Check_autoExtend(t *testing.T) {
+	dir, cleanup := testTempDir(t)
+	defer cleanup()
+
+	now := time.Now()
+	j := &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	if err := j.Record(journal.Event{
+		Action: journal.ActionStart, OverrideID: "P1", ScheduleID: "S1", UserID: pdtest.UserID,
+		Start: now.Add(-time.Hour), End: now.Add(5 * time.Minute),
+	}); err != nil {
+		t.Fatal("Record failed:", err)
+	}
+
+	pd := testPD()
+	pd.Incidents = []pagerduty.Incident{{IncidentNumber: 42}}
+	pd.Overrides = []pagerduty.Override{{ID: "P1"}}
+
+	n := &testNotifier{}
+	d := &Daemon{
+		Journal:   j,
+		Notifiers: []Notifier{n},
+		AutoExtender: &AutoExtender{
+			Dutyme: &dutyme.Dutyme{PD: pd, Journal: j},
+			User:   &dutyme.User{Obj: &pagerduty.APIObject{ID: pdtest.UserID}},
+			Step:   30 * time.Minute,
+			Max:    time.Hour,
+		},
+		Now: func() time.Time { return now },
+	}
+
+	// Extended twice (up to Max) while incident is open.
+	for i, offset := range []time.Duration{0, 32 * time.Minute} {
+		d.Now = func() time.Time { return now.Add(offset) }
+		if err := d.Check(); err != nil {
+			t.Fatal("Check failed:", err)
+		}
+
+		if got, want := pd.Created, i+1; got != want {
+			t.Fatalf("#%d extended number = %d, want %d", i, got, want)
+		}
+	}
+
+	if got, want := len(n.reminders), 0; got != want {
+		t.Fatalf("reminders number = %d, want %d", got, want)
+	}
+
+	// Cap is reached, then it's reminded. It's kept after restart.
+	d.AutoExtender = &AutoExtender{
+		Dutyme: &dutyme.Dutyme{PD: pd, Journal: j},
+		User:   &dutyme.User{Obj: &pagerduty.APIObject{ID: pdtest.UserID}},
+		Step:   30 * time.Minute,
+		Max:    time.Hour,
+	}
+	d.Now = func() time.Time { return now.Add(60 * time.Minute) }
+	if err := d.Check(); err != nil {
+		t.Fatal("Check failed:", err)
+	}
+
+	if got, want := pd.Created, 2; got != want {
+		t.Fatalf("extended number = %d, want %d", got, want)
+	}
+
+	if got, want := len(n.reminders), 1; got != want {
+		t.Fatalf("reminders number = %d, want %d", got, want)
+	}
+}
+
+func TestDaemon_Check_autoExtendNoIncident(t *testing.T) {
+	dir, cleanup := testTempDir(t)
+	defer cleanup()
+
+	now := time.Now()
+	j := &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	if err := j.Record(journal.Event{
+		Action: journal.ActionStart, OverrideID: "P1", ScheduleID: "S1", UserID: pdtest.UserID, End: now.Add(5 * time.Minute),
+	}); err != nil {
+		t.Fatal("Record failed:", err)
+	}
+
+	pd := testPD()
+	n := &testNotifier{}
+	d := &Daemon{
+		Journal:   j,
+		Notifiers: []Notifier{n},
+		AutoExtender: &AutoExtender{
+			Dutyme: &dutyme.Dutyme{PD: pd, Journal: j},
+			User:   &dutyme.User{Obj: &pagerduty.APIObject{ID: pdtest.UserID}},
+		},
+	}
+
+	if err := d.Check(); err != nil {
+		t.Fatal("Check failed:", err)
+	}
+
+	if got, want := pd.Created, 0; got != want {
+		t.Fatalf("extended number = %d, want %d", got, want)
+	}
+
+	if got, want := len(n.reminders), 1; got != want {
+		t.Fatalf("reminders number = %d, want %d", got, want)
+	}
+}
+
+func TestDaemon_Check_autoExtendSkip(t *testing.T) {
+	dir, cleanup := testTempDir(t)
+	defer cleanup()
+
+	now := time.Now()
+	j := &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	records := []journal.Event{
+		// Override of the other user (e.g., created by serve-webhook).
+		{Action: journal.ActionStart, OverrideID: "P1", ScheduleID: "S1", UserID: "POTHER1", End: now.Add(5 * time.Minute)},
+
+		// Overrides held by running and killed hold.
+		{Action: journal.ActionStart, OverrideID: "P2", ScheduleID: "S1", UserID: pdtest.UserID, End: now.Add(5 * time.Minute)},
+		{Action: journal.ActionStart, OverrideID: "P3", ScheduleID: "S1", UserID: pdtest.UserID, End: now.Add(5 * time.Minute)},
+	}
+	for _, e := range records {
+		if err := j.Record(e); err != nil {
+			t.Fatal("Record failed:", err)
+		}
+	}
+
+	st := &state.State{Path: filepath.Join(dir, "sessions.json")}
+	running := state.NewSession("hold")
+	running.OverrideID, running.End = "P2", now.Add(5*time.Minute)
+	killed := state.NewSession("hold")
+	killed.PID, killed.OverrideID, killed.End = 99999999, "P3", now.Add(5*time.Minute)
+	for _, s := range []state.Session{running, killed} {
+		if err := st.Put(s); err != nil {
+			t.Fatal("Put failed:", err)
+		}
+	}
+
+	pd := testPD()
+	pd.Incidents = []pagerduty.Incident{{IncidentNumber: 42}}
+	user := &dutyme.User{Obj: &pagerduty.APIObject{ID: pdtest.UserID}}
+
+	n := &testNotifier{}
+	d := &Daemon{
+		Journal:   j,
+		Notifiers: []Notifier{n},
+		User:      user,
+		State:     st,
+		AutoExtender: &AutoExtender{
+			Dutyme: &dutyme.Dutyme{PD: pd, Journal: j},
+			User:   user,
+		},
+		Now: func() time.Time { return now },
+	}
+
+	if err := d.Check(); err != nil {
+		t.Fatal("Check failed:", err)
+	}
+
+	if got, want := pd.Created, 0; got != want {
+		t.Fatalf("extended number = %d, want %d", got, want)
+	}
+
+	// Only the orphaned override is reminded.
+	if got, want := len(n.reminders), 1; got != want {
+		t.Fatalf("reminders number = %d, want %d", got, want)
+	}
+
+	if got, want := n.reminders[0].OverrideID, "P3"; got != want {
+		t.Fatalf("reminded override = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,242 @@
+// Package daemon tracks the overrides created by dutyme (recorded on
+// journal) and fires reminders before they expire.
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/lease"
+	"github.com/tcnksm/dutyme/state"
+)
+
+const (
+	// DefaultBefore is default duration before expiry when
+	// reminders are fired.
+	DefaultBefore = 10 * time.Minute
+
+	// DefaultInterval is default interval of checking journal.
+	DefaultInterval = 1 * time.Minute
+)
+
+// Reminder is the reminder of override which is about to expire.
+type Reminder struct {
+	OverrideID   string    `json:"override_id"`
+	ScheduleID   string    `json:"schedule_id"`
+	ScheduleName string    `json:"schedule_name,omitempty"`
+	UserEmail    string    `json:"user_email,omitempty"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+
+	// MinutesLeft is the minutes until the override expires.
+	MinutesLeft int `json:"minutes_left"`
+
+	// ExtendCommand is the command to extend the override by one step.
+	ExtendCommand string `json:"extend_command"`
+}
+
+// String returns human readable message of the reminder.
+func (r *Reminder) String() string {
+	name := r.ScheduleName
+	if len(name) == 0 {
+		name = r.ScheduleID
+	}
+	return fmt.Sprintf("Your override %s on schedule %q ends in %d minutes (at %s). Run `%s` to extend it.",
+		r.OverrideID, name, r.MinutesLeft, r.End.Local().Format("15:04"), r.ExtendCommand)
+}
+
+// Notifier notifies reminder.
+type Notifier interface {
+	Notify(r *Reminder) error
+}
+
+// Daemon checks journal periodically and notifies reminders.
+type Daemon struct {
+	Journal   *journal.Journal
+	Notifiers []Notifier
+
+	// Before is how long before expiry reminders are fired.
+	Before time.Duration
+
+	// Interval is the interval of checking journal.
+	Interval time.Duration
+
+	// User is the owner of the daemon. Journal is shared with the other
+	// users' overrides (e.g., created by serve-webhook), so only the
+	// user's overrides are reminded and extended. If it's nil, all
+	// overrides are reminded.
+	User *dutyme.User
+
+	// State and Leases are the sessions of hold and run processes.
+	// Overrides held by running sessions are renewed by their owners,
+	// so they are neither reminded nor auto-extended. Orphaned ones are
+	// reminded but not auto-extended (use gc). If nil, they are not
+	// checked.
+	State  *state.State
+	Leases *lease.Manager
+
+	// AutoExtender extends the override which is about to expire
+	// while the user has open incidents (then it's not reminded).
+	// If it's nil, overrides are never extended automatically.
+	AutoExtender *AutoExtender
+
+	// Log is used for logging reminders and errors.
+	Log io.Writer
+
+	// Now returns current time. It's replaced in test.
+	Now func() time.Time
+
+	// reminded is the set of override IDs which are already reminded.
+	reminded map[string]bool
+}
+
+func (d *Daemon) setDefault() {
+	if d.Before <= 0 {
+		d.Before = DefaultBefore
+	}
+
+	if d.Interval <= 0 {
+		d.Interval = DefaultInterval
+	}
+
+	if d.Log == nil {
+		d.Log = ioutil.Discard
+	}
+
+	if d.Now == nil {
+		d.Now = time.Now
+	}
+
+	if d.reminded == nil {
+		d.reminded = make(map[string]bool)
+	}
+}
+
+// Run checks journal every interval until stop is closed.
+func (d *Daemon) Run(stop <-chan struct{}) error {
+	d.setDefault()
+
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Check(); err != nil {
+			fmt.Fprintf(d.Log, "Failed to check overrides: %s\n", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// Check reads active overrides from journal and notifies reminders
+// of the ones which expire within Before. Each override is reminded
+// only once (extended override has new ID and is reminded again).
+// When AutoExtender extends the override, it's not reminded.
+func (d *Daemon) Check() error {
+	d.setDefault()
+
+	events, err := d.Journal.Events()
+	if err != nil {
+		return err
+	}
+
+	now := d.Now()
+	held, err := d.held(now)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range journal.Active(events, now) {
+		left := e.End.Sub(now)
+		if left > d.Before || d.reminded[e.OverrideID] {
+			continue
+		}
+
+		if d.User != nil && !dutyme.SameUser(e, d.User) {
+			continue
+		}
+
+		alive, ok := held[e.OverrideID]
+		if ok && alive {
+			continue
+		}
+
+		if d.AutoExtender != nil && !ok {
+			ext, err := d.AutoExtender.Extend(e)
+			if err != nil {
+				fmt.Fprintf(d.Log, "Failed to auto-extend override %s: %s\n", e.OverrideID, err)
+			}
+
+			if ext != nil {
+				fmt.Fprintf(d.Log, "%s\n", ext)
+				continue
+			}
+		}
+		d.reminded[e.OverrideID] = true
+
+		r := &Reminder{
+			OverrideID:    e.OverrideID,
+			ScheduleID:    e.ScheduleID,
+			ScheduleName:  e.ScheduleName,
+			UserEmail:     e.UserEmail,
+			Start:         e.Start,
+			End:           e.End,
+			MinutesLeft:   int((left + time.Minute - 1) / time.Minute),
+			ExtendCommand: fmt.Sprintf("dutyme extend -override %s", e.OverrideID),
+		}
+		fmt.Fprintf(d.Log, "%s\n", r)
+
+		for _, n := range d.Notifiers {
+			if err := n.Notify(r); err != nil {
+				fmt.Fprintf(d.Log, "Failed to notify reminder: %s\n", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// held returns the IDs of the overrides held by hold and run sessions.
+// The value is true when the session is alive (the owning process
+// exists or it's on the other host).
+func (d *Daemon) held(now time.Time) (map[string]bool, error) {
+	held := make(map[string]bool)
+
+	if d.State != nil {
+		sessions, err := d.State.Sessions()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range sessions {
+			held[s.OverrideID] = true
+		}
+
+		host, _ := os.Hostname()
+		for _, s := range state.Orphaned(sessions, host, now) {
+			held[s.OverrideID] = false
+		}
+	}
+
+	if d.Leases != nil {
+		groups, err := d.Leases.Groups()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range groups {
+			held[g.OverrideID] = len(g.Holders) != 0
+		}
+	}
+
+	return held, nil
+}
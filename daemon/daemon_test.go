@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tcnksm/dutyme/journal"
+)
+
+type testNotifier struct {
+	reminders []*Reminder
+}
+
+func (n *testNotifier) Notify(r *Reminder) error {
+	n.reminders = append(n.reminders, r)
+	return nil
+}
+
+func testTempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "dutyme-daemon")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestDaemon_Check(t *testing.T) {
+	dir, cleanup := testTempDir(t)
+	defer cleanup()
+
+	now := time.Now()
+	j := &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	records := []journal.Event{
+		{Action: journal.ActionStart, OverrideID: "P1", ScheduleID: "S1", End: now.Add(5 * time.Minute)},
+		{Action: journal.ActionStart, OverrideID: "P2", ScheduleID: "S1", End: now.Add(time.Hour)},
+	}
+	for _, e := range records {
+		if err := j.Record(e); err != nil {
+			t.Fatal("Record failed:", err)
+		}
+	}
+
+	n := &testNotifier{}
+	d := &Daemon{
+		Journal:   j,
+		Notifiers: []Notifier{n},
+		Now:       func() time.Time { return now },
+	}
+
+	// Checking twice must remind only once.
+	for i := 0; i < 2; i++ {
+		if err := d.Check(); err != nil {
+			t.Fatal("Check failed:", err)
+		}
+	}
+
+	if got, want := len(n.reminders), 1; got != want {
+		t.Fatalf("reminders number = %d, want %d", got, want)
+	}
+
+	r := n.reminders[0]
+	if got, want := r.OverrideID, "P1"; got != want {
+		t.Fatalf("reminder override ID = %q, want %q", got, want)
+	}
+
+	if got, want := r.MinutesLeft, 5; got != want {
+		t.Fatalf("reminder minutes left = %d, want %d", got, want)
+	}
+
+	// Extended override is reminded again when it's about to expire.
+	if err := j.Record(journal.Event{
+		Action: journal.ActionExtend, OverrideID: "P3", PreviousID: "P1", End: now.Add(70 * time.Minute),
+	}); err != nil {
+		t.Fatal("Record failed:", err)
+	}
+	d.Now = func() time.Time { return now.Add(65 * time.Minute) }
+	if err := d.Check(); err != nil {
+		t.Fatal("Check failed:", err)
+	}
+
+	if got, want := len(n.reminders), 2; got != want {
+		t.Fatalf("reminders number = %d, want %d", got, want)
+	}
+}
+
+func TestFeedNotifier(t *testing.T) {
+	dir, cleanup := testTempDir(t)
+	defer cleanup()
+
+	n := &FeedNotifier{Path: filepath.Join(dir, "feed.jsonl")}
+	reminders, offset, err := n.Follow(-1)
+	if err != nil {
+		t.Fatal("Follow failed:", err)
+	}
+
+	if got, want := len(reminders), 0; got != want {
+		t.Fatalf("reminders number = %d, want %d", got, want)
+	}
+
+	for _, id := range []string{"P1", "P2"} {
+		if err := n.Notify(&Reminder{OverrideID: id}); err != nil {
+			t.Fatal("Notify failed:", err)
+		}
+	}
+
+	reminders, offset, err = n.Follow(offset)
+	if err != nil {
+		t.Fatal("Follow failed:", err)
+	}
+
+	if got, want := len(reminders), 2; got != want {
+		t.Fatalf("reminders number = %d, want %d", got, want)
+	}
+
+	reminders, _, err = n.Follow(offset)
+	if err != nil {
+		t.Fatal("Follow failed:", err)
+	}
+
+	if got, want := len(reminders), 0; got != want {
+		t.Fatalf("reminders number = %d, want %d", got, want)
+	}
+
+	// Feed file is truncated when it reaches max size, and follower
+	// reads the new reminder from the beginning.
+	n.MaxSize = offset
+	if err := n.Notify(&Reminder{OverrideID: "P3"}); err != nil {
+		t.Fatal("Notify failed:", err)
+	}
+
+	if info, err := os.Stat(n.Path); err != nil || info.Size() >= offset {
+		t.Fatalf("expect feed file to be truncated: %v", err)
+	}
+
+	reminders, _, err = n.Follow(offset)
+	if err != nil {
+		t.Fatal("Follow failed:", err)
+	}
+
+	if len(reminders) != 1 || reminders[0].OverrideID != "P3" {
+		t.Fatalf("reminders = %v, want P3", reminders)
+	}
+}
+
+func TestCommandNotifier(t *testing.T) {
+	dir, cleanup := testTempDir(t)
+	defer cleanup()
+
+	out := filepath.Join(dir, "out")
+	n := &CommandNotifier{
+		Command: "echo $DUTYME_HOOK_OVERRIDE_ID $DUTYME_HOOK_MINUTES_LEFT > " + out,
+	}
+
+	if err := n.Notify(&Reminder{OverrideID: "P1", MinutesLeft: 5}); err != nil {
+		t.Fatal("Notify failed:", err)
+	}
+
+	buf, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal("ReadFile failed:", err)
+	}
+
+	if got, want := strings.TrimSpace(string(buf)), "P1 5"; got != want {
+		t.Fatalf("command output = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got Reminder
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal("Decode failed:", err)
+		}
+	}))
+	defer ts.Close()
+
+	n := &WebhookNotifier{URL: ts.URL}
+	if err := n.Notify(&Reminder{OverrideID: "P1"}); err != nil {
+		t.Fatal("Notify failed:", err)
+	}
+
+	if want := "P1"; got.OverrideID != want {
+		t.Fatalf("posted override ID = %q, want %q", got.OverrideID, want)
+	}
+}
@@ -0,0 +1,197 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/hook"
+)
+
+// DefaultFeedMaxSize is default max size of the feed file.
+const DefaultFeedMaxSize = 1 << 20
+
+// FeedNotifier appends reminders to the feed file which is followed
+// by watch command (it rings terminal bell).
+type FeedNotifier struct {
+	Path string
+
+	// MaxSize is the max size of the feed file. When the file reaches
+	// it, the file is truncated before appending. If it's zero,
+	// DefaultFeedMaxSize is used.
+	MaxSize int64
+}
+
+func (n *FeedNotifier) Notify(r *Reminder) error {
+	if err := os.MkdirAll(filepath.Dir(n.Path), 0700); err != nil {
+		return errors.Wrap(err, "failed to create feed directory")
+	}
+
+	max := n.MaxSize
+	if max <= 0 {
+		max = DefaultFeedMaxSize
+	}
+
+	// Reminders are only for followers at the moment, so old ones
+	// are dropped.
+	flag := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+	if info, err := os.Stat(n.Path); err == nil && info.Size() >= max {
+		flag |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(n.Path, flag, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open feed file")
+	}
+	defer f.Close()
+
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode reminder")
+	}
+
+	if _, err := f.Write(append(buf, '\n')); err != nil {
+		return errors.Wrap(err, "failed to write reminder")
+	}
+
+	return nil
+}
+
+// Follow reads reminders appended to the feed file after the given
+// offset. It returns the reminders and the next offset. If offset is
+// negative, it starts from the end of the file (returns no reminders).
+// If the file is truncated (offset is beyond the end), it reads from the
+// beginning.
+func (n *FeedNotifier) Follow(offset int64) ([]*Reminder, int64, error) {
+	f, err := os.Open(n.Path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, offset, errors.Wrap(err, "failed to open feed file")
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, offset, errors.Wrap(err, "failed to stat feed file")
+	}
+
+	// First time to follow.
+	if offset < 0 {
+		return nil, info.Size(), nil
+	}
+
+	// File is truncated by Notify.
+	if offset > info.Size() {
+		offset = 0
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, errors.Wrap(err, "failed to seek feed file")
+	}
+
+	var reminders []*Reminder
+	rd := bufio.NewReader(f)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil {
+			// Incomplete line is read in the next time.
+			break
+		}
+		offset += int64(len(line))
+
+		var r Reminder
+		if err := json.Unmarshal(line, &r); err != nil {
+			continue
+		}
+		reminders = append(reminders, &r)
+	}
+
+	return reminders, offset, nil
+}
+
+// CommandNotifier executes shell command with reminder details
+// in DUTYME_HOOK_* environment variables (the same as hooks).
+type CommandNotifier struct {
+	Command string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func (n *CommandNotifier) Notify(r *Reminder) error {
+	cmd := exec.Command("sh", "-c", n.Command)
+	cmd.Stdout = n.Stdout
+	cmd.Stderr = n.Stderr
+	cmd.Env = append(os.Environ(),
+		hook.EnvPrefix+"OVERRIDE_ID="+r.OverrideID,
+		hook.EnvPrefix+"SCHEDULE_ID="+r.ScheduleID,
+		hook.EnvPrefix+"SCHEDULE_NAME="+r.ScheduleName,
+		hook.EnvPrefix+"USER_EMAIL="+r.UserEmail,
+		hook.EnvPrefix+"END="+r.End.Format(time.RFC3339),
+		hook.EnvPrefix+"MINUTES_LEFT="+strconv.Itoa(r.MinutesLeft),
+		hook.EnvPrefix+"EXTEND_COMMAND="+r.ExtendCommand,
+	)
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "failed to execute command %q", n.Command)
+	}
+
+	return nil
+}
+
+// HookNotifier executes on_expire_soon hook with reminder details.
+type HookNotifier struct {
+	Hooks *hook.Runner
+}
+
+func (n *HookNotifier) Notify(r *Reminder) error {
+	return n.Hooks.Run(hook.OnExpireSoon, hook.Payload{
+		OverrideID:   r.OverrideID,
+		ScheduleID:   r.ScheduleID,
+		ScheduleName: r.ScheduleName,
+		UserEmail:    r.UserEmail,
+		Start:        r.Start,
+		End:          r.End,
+	})
+}
+
+// WebhookNotifier posts reminder as JSON to the URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Notify(r *Reminder) error {
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode reminder")
+	}
+
+	res, err := client.Post(n.URL, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return errors.Wrap(err, "failed to post webhook")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returns unexpected status: %s", res.Status)
+	}
+
+	return nil
+}
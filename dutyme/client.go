@@ -1,6 +1,7 @@
 package dutyme
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -10,10 +11,20 @@ import (
 
 type PagerDuty interface {
 	GetUser(email string) (*User, error)
+	GetUserByID(userID string) (*User, error)
 	GetSchedules(name string) ([]pagerduty.Schedule, error)
 	GetOverrides(scheduleID string, since, until time.Time) ([]pagerduty.Override, error)
+	GetAllOverrides(scheduleID string, since, until time.Time) ([]pagerduty.Override, error)
 	Override(scheduleID string, user *User, start, end time.Time) (*pagerduty.Override, error)
 	DeleteOverride(scheduleID, overrideID string) error
+	GetServices(name string) ([]pagerduty.Service, error)
+	GetService(serviceID string) (*pagerduty.Service, error)
+	GetEscalationPolicy(policyID string) (*pagerduty.EscalationPolicy, error)
+	GetSchedule(scheduleID string, since, until time.Time) (*pagerduty.Schedule, error)
+	ListSchedules() ([]pagerduty.Schedule, error)
+	ListTeams() ([]pagerduty.Team, error)
+	ListEscalationPolicies() ([]pagerduty.EscalationPolicy, error)
+	ListIncidents(userID string, serviceIDs, statuses []string) ([]pagerduty.Incident, error)
 }
 
 // User represents pagerduty user
@@ -59,7 +70,7 @@ func (c *PDClient) GetUser(email string) (*User, error) {
 
 	users := res.Users
 	if len(users) == 0 {
-		return nil, errors.Errorf("no such user: %s (correct email?)", email)
+		return nil, &errNotFound{fmt.Sprintf("no such user: %s (correct email?)", email)}
 	}
 
 	// Assumption: One email belongs to only one user.
@@ -79,6 +90,24 @@ func (c *PDClient) GetUser(email string) (*User, error) {
 	}, nil
 }
 
+// GetUserByID gets the user by its ID (e.g., the user on rendered
+// schedule entries, which has no email).
+func (c *PDClient) GetUserByID(userID string) (*User, error) {
+	if len(userID) == 0 {
+		return nil, errors.New("missing pagerduty user ID")
+	}
+
+	user, err := c.Client.GetUser(userID, pagerduty.GetUserOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "PagerDuty API request failed: GetUser")
+	}
+
+	return &User{
+		Email: user.Email,
+		Obj:   &user.APIObject,
+	}, nil
+}
+
 // GetSchecules finds Pagerduty schedules by querying the given name.
 // If any or found nothing, returns error.
 func (c *PDClient) GetSchedules(name string) ([]pagerduty.Schedule, error) {
@@ -87,7 +116,7 @@ func (c *PDClient) GetSchedules(name string) ([]pagerduty.Schedule, error) {
 	}
 
 	// TODO(tcnksm): More strict search?
-	res, err := c.ListSchedules(pagerduty.ListSchedulesOptions{
+	res, err := c.Client.ListSchedules(pagerduty.ListSchedulesOptions{
 		Query: name,
 	})
 	if err != nil {
@@ -102,15 +131,26 @@ func (c *PDClient) GetSchedules(name string) ([]pagerduty.Schedule, error) {
 	return schedules, nil
 }
 
+// GetOverrides gets the overrides which can be edited (i.e., the ones
+// which haven't started yet).
 func (c *PDClient) GetOverrides(scheduleID string, since, until time.Time) ([]pagerduty.Override, error) {
+	return c.listOverrides(scheduleID, since, until, true)
+}
+
+// GetAllOverrides gets all overrides including the ones in progress.
+func (c *PDClient) GetAllOverrides(scheduleID string, since, until time.Time) ([]pagerduty.Override, error) {
+	return c.listOverrides(scheduleID, since, until, false)
+}
+
+func (c *PDClient) listOverrides(scheduleID string, since, until time.Time, editable bool) ([]pagerduty.Override, error) {
 	if len(scheduleID) == 0 {
 		return nil, errors.New("misssing scheduleID")
 	}
 
 	overrides, err := c.ListOverrides(scheduleID, pagerduty.ListOverridesOptions{
-		Since:    since.String(),
-		Until:    until.String(),
-		Editable: true,
+		Since:    since.Format(time.RFC3339),
+		Until:    until.Format(time.RFC3339),
+		Editable: editable,
 		Overflow: true,
 	})
 
@@ -140,8 +180,8 @@ func (c *PDClient) Override(scheduleID string, user *User, start, end time.Time)
 
 	// TODO(tcnksm): Handle when user is already persion in charge.
 	override, err := c.CreateOverride(scheduleID, pagerduty.Override{
-		Start: start.String(),
-		End:   end.String(),
+		Start: start.Format(time.RFC3339),
+		End:   end.Format(time.RFC3339),
 		User:  *user.Obj,
 	})
 
@@ -168,10 +208,173 @@ func (c *PDClient) DeleteOverride(scheduleID, overrideID string) error {
 	return nil
 }
 
+// GetServices finds PagerDuty services by querying the given name.
+// If found nothing, returns error.
+func (c *PDClient) GetServices(name string) ([]pagerduty.Service, error) {
+	if len(name) == 0 {
+		return nil, errors.New("missing service name")
+	}
+
+	res, err := c.ListServices(pagerduty.ListServiceOptions{
+		Query: name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "PagerDuty API request failed: ListServices")
+	}
+
+	services := res.Services
+	if len(services) == 0 {
+		return nil, errors.Errorf("no such service: %s", name)
+	}
+
+	return services, nil
+}
+
+// GetService gets a service by the given ID. The returned service
+// contains the reference to its escalation policy.
+func (c *PDClient) GetService(serviceID string) (*pagerduty.Service, error) {
+	if len(serviceID) == 0 {
+		return nil, errors.New("missing service ID")
+	}
+
+	service, err := c.Client.GetService(serviceID, &pagerduty.GetServiceOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "PagerDuty API request failed: GetService")
+	}
+
+	return service, nil
+}
+
+// GetEscalationPolicy gets an escalation policy and its rules by the given ID.
+func (c *PDClient) GetEscalationPolicy(policyID string) (*pagerduty.EscalationPolicy, error) {
+	if len(policyID) == 0 {
+		return nil, errors.New("missing escalation policy ID")
+	}
+
+	policy, err := c.Client.GetEscalationPolicy(policyID, &pagerduty.GetEscalationPolicyOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "PagerDuty API request failed: GetEscalationPolicy")
+	}
+
+	return policy, nil
+}
+
+// GetSchedule gets a schedule by the given ID. The rendered entries
+// of its layers are computed for the given time range.
+func (c *PDClient) GetSchedule(scheduleID string, since, until time.Time) (*pagerduty.Schedule, error) {
+	if len(scheduleID) == 0 {
+		return nil, errors.New("missing schedule ID")
+	}
+
+	schedule, err := c.Client.GetSchedule(scheduleID, pagerduty.GetScheduleOptions{
+		Since: since.Format(time.RFC3339),
+		Until: until.Format(time.RFC3339),
+	})
+	if err != nil {
+		// Client doesn't return typed error.
+		if strings.Contains(err.Error(), "HTTP response code: 404") {
+			return nil, &errNotFound{fmt.Sprintf("no such schedule: %s", scheduleID)}
+		}
+		return nil, errors.Wrap(err, "PagerDuty API request failed: GetSchedule")
+	}
+
+	return schedule, nil
+}
+
+// ListSchedules lists all schedules in the account.
+func (c *PDClient) ListSchedules() ([]pagerduty.Schedule, error) {
+	var schedules []pagerduty.Schedule
+	opts := pagerduty.ListSchedulesOptions{}
+	for {
+		res, err := c.Client.ListSchedules(opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "PagerDuty API request failed: ListSchedules")
+		}
+		schedules = append(schedules, res.Schedules...)
+
+		if !res.More {
+			break
+		}
+		opts.Offset += uint(len(res.Schedules))
+	}
+
+	return schedules, nil
+}
+
+// ListTeams lists all teams in the account.
+func (c *PDClient) ListTeams() ([]pagerduty.Team, error) {
+	var teams []pagerduty.Team
+	opts := pagerduty.ListTeamOptions{}
+	for {
+		res, err := c.Client.ListTeams(opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "PagerDuty API request failed: ListTeams")
+		}
+		teams = append(teams, res.Teams...)
+
+		if !res.More {
+			break
+		}
+		opts.Offset += uint(len(res.Teams))
+	}
+
+	return teams, nil
+}
+
+// ListEscalationPolicies lists all escalation policies in the account.
+// Each policy has references to its teams.
+func (c *PDClient) ListEscalationPolicies() ([]pagerduty.EscalationPolicy, error) {
+	var policies []pagerduty.EscalationPolicy
+	opts := pagerduty.ListEscalationPoliciesOptions{}
+	for {
+		res, err := c.Client.ListEscalationPolicies(opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "PagerDuty API request failed: ListEscalationPolicies")
+		}
+		policies = append(policies, res.EscalationPolicies...)
+
+		if !res.More {
+			break
+		}
+		opts.Offset += uint(len(res.EscalationPolicies))
+	}
+
+	return policies, nil
+}
+
+// ListIncidents returns the incidents assigned to the given user on the
+// given services which have one of the given statuses.
+func (c *PDClient) ListIncidents(userID string, serviceIDs, statuses []string) ([]pagerduty.Incident, error) {
+	var incidents []pagerduty.Incident
+	opts := pagerduty.ListIncidentsOptions{
+		UserIDs:    []string{userID},
+		ServiceIDs: serviceIDs,
+		Statuses:   statuses,
+	}
+	for {
+		res, err := c.Client.ListIncidents(opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "PagerDuty API request failed: ListIncidents")
+		}
+		incidents = append(incidents, res.Incidents...)
+
+		if !res.More {
+			break
+		}
+		opts.Offset += uint(len(res.Incidents))
+	}
+
+	return incidents, nil
+}
+
 // IsNotFound returns true if err inplements notfound interface
 // and NotFound returns true.
+func IsNotFound(err error) bool {
+	return isNotFound(err)
+}
+
 func isNotFound(err error) bool {
-	i, ok := err.(notfound)
+	i, ok := errors.Cause(err).(notfound)
 	return ok && i.NotFound()
 }
 
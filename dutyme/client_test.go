@@ -2,6 +2,9 @@ package dutyme
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -38,14 +41,41 @@ const (
 	testScheduleName2 = "Dutyme secondary"
 
 	testOverrideID = "PEYSGVF"
+
+	testServiceID   = "PSVC123"
+	testServiceName = "checkout-api"
+
+	testPolicyID   = "PEP4567"
+	testPolicyName = "Checkout escalation"
+
+	testIncidentID = "PINC777"
+
+	testTeamID   = "PTEAM89"
+	testTeamName = "Checkout team"
+
+	// testScheduleID3 is schedule which test user doesn't belong to.
+	testScheduleID3   = "PI3DH03"
+	testScheduleName3 = "Payments primary"
 )
 
 type testPDClient struct {
+	// deleted is the IDs of the overrides deleted by DeleteOverride.
+	deleted []string
+
+	// inProgress is the overrides already started. They are not
+	// editable, so only GetAllOverrides returns them.
+	inProgress []pagerduty.Override
+
+	// err is returned by GetOverrides and GetAllOverrides when it's set.
+	err error
+
+	// deleteErr is returned by DeleteOverride when it's set.
+	deleteErr error
 }
 
 func (c *testPDClient) GetUser(email string) (*User, error) {
 	if email != testEmail {
-		return nil, errors.Errorf("user %s doesn't exist", email)
+		return nil, &errNotFound{fmt.Sprintf("user %s doesn't exist", email)}
 	}
 	return &User{
 		Email: testEmail,
@@ -59,6 +89,23 @@ func (c *testPDClient) GetUser(email string) (*User, error) {
 	}, nil
 }
 
+func (c *testPDClient) GetUserByID(userID string) (*User, error) {
+	emails := map[string]string{
+		testUserID: testEmail,
+		"PALICE1":  "alice@example.com",
+		"PBOB123":  "bob@example.com",
+	}
+
+	email, ok := emails[userID]
+	if !ok {
+		return nil, &errNotFound{fmt.Sprintf("user %s doesn't exist", userID)}
+	}
+	return &User{
+		Email: email,
+		Obj:   &pagerduty.APIObject{ID: userID},
+	}, nil
+}
+
 func (c *testPDClient) GetSchedules(name string) ([]pagerduty.Schedule, error) {
 	schedules := []pagerduty.Schedule{
 		{
@@ -100,12 +147,205 @@ func (c *testPDClient) Override(scheduleID string, user *User, start, end time.T
 }
 
 func (c *testPDClient) DeleteOverride(scheduleID, overrideID string) error {
-	// Currently not used
+	if c.deleteErr != nil {
+		return c.deleteErr
+	}
+	c.deleted = append(c.deleted, overrideID)
 	return nil
 }
 
 func (c *testPDClient) GetOverrides(scheduleID string, since, until time.Time) ([]pagerduty.Override, error) {
-	// Currently not used
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	if scheduleID != testScheduleID1 {
+		return nil, &errNotFound{"no overrides are found"}
+	}
+
+	return []pagerduty.Override{
+		{
+			ID:    testOverrideID,
+			Start: "2017-01-01T11:00:00Z",
+			End:   "2017-01-01T11:30:00Z",
+			User:  pagerduty.APIObject{ID: "PCAROL1", Summary: "Carol"},
+		},
+	}, nil
+}
+
+func (c *testPDClient) GetAllOverrides(scheduleID string, since, until time.Time) ([]pagerduty.Override, error) {
+	overrides, err := c.GetOverrides(scheduleID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	return append(overrides, c.inProgress...), nil
+}
+
+func (c *testPDClient) GetServices(name string) ([]pagerduty.Service, error) {
+	if name != testServiceName {
+		return nil, errors.Errorf("service %s doesn't exist", name)
+	}
+
+	service, _ := c.GetService(testServiceID)
+	return []pagerduty.Service{*service}, nil
+}
+
+func (c *testPDClient) GetService(serviceID string) (*pagerduty.Service, error) {
+	if serviceID != testServiceID {
+		return nil, errors.Errorf("service %s doesn't exist", serviceID)
+	}
+
+	return &pagerduty.Service{
+		APIObject: pagerduty.APIObject{
+			ID: testServiceID,
+		},
+		Name: testServiceName,
+		EscalationPolicy: pagerduty.EscalationPolicy{
+			APIObject: pagerduty.APIObject{
+				ID:   testPolicyID,
+				Type: "escalation_policy_reference",
+			},
+		},
+	}, nil
+}
+
+func (c *testPDClient) GetEscalationPolicy(policyID string) (*pagerduty.EscalationPolicy, error) {
+	if policyID != testPolicyID {
+		return nil, errors.Errorf("escalation policy %s doesn't exist", policyID)
+	}
+
+	return &pagerduty.EscalationPolicy{
+		APIObject: pagerduty.APIObject{
+			ID: testPolicyID,
+		},
+		Name: testPolicyName,
+		Services: []pagerduty.APIReference{
+			{ID: testServiceID, Type: "service_reference"},
+		},
+		EscalationRules: []pagerduty.EscalationRule{
+			{
+				Targets: []pagerduty.APIObject{
+					{ID: testScheduleID1, Type: "schedule_reference", Summary: testScheduleName1},
+					{ID: testUserID, Type: "user_reference"},
+				},
+			},
+			{
+				Targets: []pagerduty.APIObject{
+					{ID: testUserID, Type: "user_reference"},
+				},
+			},
+		},
+	}, nil
+}
+
+func (c *testPDClient) GetSchedule(scheduleID string, since, until time.Time) (*pagerduty.Schedule, error) {
+	schedules, _ := c.ListSchedules()
+	for _, schedule := range schedules {
+		if schedule.ID == scheduleID {
+			return &schedule, nil
+		}
+	}
+	return nil, &errNotFound{fmt.Sprintf("schedule %s doesn't exist", scheduleID)}
+}
+
+func (c *testPDClient) ListSchedules() ([]pagerduty.Schedule, error) {
+	return []pagerduty.Schedule{
+		{
+			APIObject: pagerduty.APIObject{
+				ID: testScheduleID1,
+			},
+			Name: testScheduleName1,
+			Users: []pagerduty.APIObject{
+				{ID: testUserID},
+			},
+			FinalSchedule: pagerduty.ScheduleLayer{
+				RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{
+					{
+						Start: "2017-01-01T00:00:00Z",
+						End:   "2017-01-01T12:00:00Z",
+						User:  pagerduty.APIObject{ID: "PALICE1", Summary: "Alice"},
+					},
+					{
+						Start: "2017-01-01T12:00:00Z",
+						End:   "2017-01-02T00:00:00Z",
+						User:  pagerduty.APIObject{ID: "PBOB123", Summary: "Bob"},
+					},
+				},
+			},
+			EscalationPolicies: []pagerduty.APIObject{
+				{ID: testPolicyID},
+			},
+		},
+		{
+			APIObject: pagerduty.APIObject{
+				ID: testScheduleID2,
+			},
+			Name: testScheduleName2,
+			ScheduleLayers: []pagerduty.ScheduleLayer{
+				{
+					Users: []pagerduty.UserReference{
+						{User: pagerduty.APIObject{ID: testUserID}},
+					},
+				},
+			},
+		},
+		{
+			APIObject: pagerduty.APIObject{
+				ID: testScheduleID3,
+			},
+			Name: testScheduleName3,
+			Users: []pagerduty.APIObject{
+				{ID: "PANOTHR"},
+			},
+			EscalationPolicies: []pagerduty.APIObject{
+				{ID: testPolicyID},
+			},
+		},
+	}, nil
+}
+
+func (c *testPDClient) ListTeams() ([]pagerduty.Team, error) {
+	return []pagerduty.Team{
+		{
+			APIObject: pagerduty.APIObject{
+				ID: testTeamID,
+			},
+			Name: testTeamName,
+		},
+	}, nil
+}
+
+func (c *testPDClient) ListEscalationPolicies() ([]pagerduty.EscalationPolicy, error) {
+	return []pagerduty.EscalationPolicy{
+		{
+			APIObject: pagerduty.APIObject{
+				ID: testPolicyID,
+			},
+			Name: testPolicyName,
+			Teams: []pagerduty.APIReference{
+				{ID: testTeamID},
+			},
+		},
+	}, nil
+}
+
+func (c *testPDClient) ListIncidents(userID string, serviceIDs, statuses []string) ([]pagerduty.Incident, error) {
+	if userID != testUserID {
+		return nil, nil
+	}
+
+	for _, id := range serviceIDs {
+		if id == testServiceID {
+			return []pagerduty.Incident{
+				{
+					APIObject:      pagerduty.APIObject{ID: testIncidentID},
+					IncidentNumber: 42,
+					Service:        pagerduty.APIObject{ID: testServiceID},
+					Status:         "acknowledged",
+				},
+			}, nil
+		}
+	}
 	return nil, nil
 }
 
@@ -121,6 +361,75 @@ func testNewClient(t *testing.T, token string) PagerDuty {
 	return client
 }
 
+// testTransport records the requests to PagerDuty API and returns the
+// given response body.
+type testTransport struct {
+	body  string
+	query url.Values
+}
+
+func (tr *testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tr.query = req.URL.Query()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(tr.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestPDClient_GetSchedule_timeFormat(t *testing.T) {
+	tr := &testTransport{body: `{"schedule": {"id": "PI7DH85", "name": "Dutyme primary"}}`}
+	defaultTransport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = tr
+	defer func() { http.DefaultClient.Transport = defaultTransport }()
+
+	client, err := NewPDClient("secret")
+	if err != nil {
+		t.Fatal("NewPDClient failed:", err)
+	}
+
+	// time.Now has monotonic clock reading which must not be sent.
+	since := time.Now()
+	if _, err := client.GetSchedule(testScheduleID1, since, since.Add(time.Hour)); err != nil {
+		t.Fatal("GetSchedule failed:", err)
+	}
+
+	for _, key := range []string{"since", "until"} {
+		if _, err := time.Parse(time.RFC3339, tr.query.Get(key)); err != nil {
+			t.Fatalf("%s = %q, want RFC3339: %s", key, tr.query.Get(key), err)
+		}
+	}
+}
+
+func TestPDClient_GetAllOverrides(t *testing.T) {
+	tr := &testTransport{body: `{"overrides": [{"id": "PEYSGVF"}]}`}
+	defaultTransport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = tr
+	defer func() { http.DefaultClient.Transport = defaultTransport }()
+
+	client, err := NewPDClient("secret")
+	if err != nil {
+		t.Fatal("NewPDClient failed:", err)
+	}
+
+	since := time.Now()
+	if _, err := client.GetAllOverrides(testScheduleID1, since, since.Add(time.Hour)); err != nil {
+		t.Fatal("GetAllOverrides failed:", err)
+	}
+
+	// Overrides in progress are not editable, so editable must not be sent.
+	if got := tr.query.Get("editable"); got != "" {
+		t.Fatalf("editable = %q, want it not to be sent", got)
+	}
+
+	for _, key := range []string{"since", "until"} {
+		if _, err := time.Parse(time.RFC3339, tr.query.Get(key)); err != nil {
+			t.Fatalf("%s = %q, want RFC3339: %s", key, tr.query.Get(key), err)
+		}
+	}
+}
+
 func TestFGetUser(t *testing.T) {
 	client := testNewClient(t, TestToken)
 
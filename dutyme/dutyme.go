@@ -7,6 +7,10 @@ import (
 
 	"github.com/PagerDuty/go-pagerduty"
 	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/finder"
+	"github.com/tcnksm/dutyme/hook"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/notify"
 	"github.com/tcnksm/go-gitconfig"
 	"github.com/tcnksm/go-input"
 )
@@ -14,6 +18,39 @@ import (
 type Dutyme struct {
 	PD PagerDuty
 	UI *input.UI
+
+	// Finder is used for selection prompts when it's available.
+	// If nil or not available, numbered list by UI is used.
+	Finder *finder.Finder
+
+	// Journal records the overrides created by dutyme.
+	// If nil, nothing is recorded.
+	Journal *journal.Journal
+
+	// Notifiers are notified after overriding and stopping (e.g., to
+	// tell the displaced on-call person). If empty, nothing is notified.
+	Notifiers []notify.Notifier
+
+	// Hooks executes user's commands around overriding and stopping.
+	// If nil, nothing is executed.
+	Hooks *hook.Runner
+
+	// Reason and Ticket are why user overrides (e.g., "deploy v1.2.0"
+	// and "OPS-123"). They are recorded on journal and passed to hooks
+	// and notifiers of overriding.
+	Reason string
+	Ticket string
+
+	// ReasonPolicy decides which overrides require reason. If nil,
+	// reason is optional.
+	ReasonPolicy *ReasonPolicy
+
+	// Policy is guardrails of overriding. If nil, nothing is limited.
+	Policy *Policy
+
+	// Force is true when user explicitly forces overriding (-force).
+	// It's required to override protected schedules.
+	Force bool
 }
 
 func (d *Dutyme) GetUser(defaultEmail string) (*User, error) {
@@ -58,27 +95,20 @@ func (d *Dutyme) GetSchedule(defaultQuery string) (string, string, error) {
 
 	// API may return multiple schedules. Then ask to user.
 	if len(schedules) > 1 {
-		targets := make([]string, 0, len(schedules))
+		items := make([]finder.Item, 0, len(schedules))
 		for _, schedule := range schedules {
-			targets = append(targets, schedule.Name)
+			items = append(items, finder.Item{Label: schedule.Name})
 		}
+		d.describeSchedules(schedules, items)
 
 		query := "Found multiple schedules. Select one."
-		target, err := d.UI.Select(query, targets, &input.Options{
-			Default: targets[0],
-			Loop:    true,
-		})
-
+		i, err := d.selectItem(query, items)
 		if err != nil {
 			return "", "", errors.Wrap(err, "failed to ask schedule from the given list")
 		}
 
-		for _, schedule := range schedules {
-			if target == schedule.Name {
-				name = schedule.Name
-				ID = schedule.ID
-			}
-		}
+		name = schedules[i].Name
+		ID = schedules[i].ID
 	} else {
 		name = schedules[0].Name
 		ID = schedules[0].ID
@@ -88,32 +118,147 @@ func (d *Dutyme) GetSchedule(defaultQuery string) (string, string, error) {
 }
 
 func (d *Dutyme) Override(scheduleID string, user *User, start, end time.Time, force bool) (*pagerduty.Override, error) {
+	if err := d.CheckReason(scheduleID); err != nil {
+		return nil, err
+	}
+
+	if err := d.CheckPolicy(scheduleID, start, end); err != nil {
+		return nil, err
+	}
 
 	if !force {
-		query := fmt.Sprintf("OK to override? [Y/n]")
-		ans, err := d.UI.Ask(query, &input.Options{
-			Default:     "Y",
-			Loop:        true,
-			HideOrder:   true,
-			HideDefault: true,
-			ValidateFunc: func(s string) error {
-				if s != "Y" && s != "y" && s != "N" && s != "n" {
-					return fmt.Errorf("input must be Y or n")
-				}
-				return nil
-			},
-		})
+		// Show the preview of the final schedule with confirmation.
+		// Failing to preview should not block overriding.
+		query := "OK to override? [Y/n]"
+		if preview, err := d.Preview(scheduleID, user, start, end); err == nil {
+			query = preview.String() + "\n" + query
+		}
 
+		ok, err := d.confirm(query, true)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to ask")
+			return nil, err
 		}
 
-		if ans == "N" || ans == "n" {
+		if !ok {
 			return nil, &errCancel{}
 		}
 	}
 
-	return d.PD.Override(scheduleID, user, start, end)
+	// Who is displaced must be checked before overriding.
+	var (
+		scheduleName string
+		displaced    []string
+		displacedIDs []string
+	)
+	if len(d.Notifiers) != 0 || d.Hooks != nil {
+		scheduleName, displaced, displacedIDs = d.rotation(scheduleID, user, start, end)
+	}
+
+	e := journal.Event{
+		Action:       journal.ActionStart,
+		ScheduleID:   scheduleID,
+		ScheduleName: scheduleName,
+		Start:        start,
+		End:          end,
+		Reason:       d.Reason,
+		Ticket:       d.Ticket,
+	}
+
+	payload := hookPayload(e, user)
+	if err := d.Hooks.Run(hook.PreStart, payload); err != nil {
+		return nil, errors.Wrap(err, "overriding is aborted")
+	}
+
+	override, err := d.PD.Override(scheduleID, user, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	e.OverrideID = override.ID
+	d.record(e, user)
+
+	payload.OverrideID = override.ID
+	d.runHook(hook.PostStart, payload)
+
+	if len(d.Notifiers) != 0 {
+		d.notify(&notify.Notification{
+			Action:          notify.ActionStart,
+			OverrideID:      override.ID,
+			ScheduleID:      scheduleID,
+			ScheduleName:    scheduleName,
+			Start:           start,
+			End:             end,
+			User:            userName(user),
+			Displaced:       displaced,
+			DisplacedEmails: d.emails(displacedIDs),
+			Reason:          d.Reason,
+			Ticket:          d.Ticket,
+		})
+	}
+
+	return override, nil
+}
+
+// selectItem asks user to select one of the given items and returns its
+// index. It uses fuzzy finder if it's available, otherwise it falls back
+// to the numbered list (then item detail is not displayed).
+func (d *Dutyme) selectItem(query string, items []finder.Item) (int, error) {
+	if d.Finder.Available() {
+		i, err := d.Finder.Find(query, items)
+		if err == finder.ErrInterrupted {
+			return -1, &errCancel{}
+		}
+		return i, err
+	}
+
+	targets := make([]string, 0, len(items))
+	for _, item := range items {
+		targets = append(targets, item.Label)
+	}
+
+	target, err := d.UI.Select(query, targets, &input.Options{
+		Default: targets[0],
+		Loop:    true,
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	for i, t := range targets {
+		if t == target {
+			return i, nil
+		}
+	}
+
+	return -1, errors.Errorf("no such item: %s", target)
+}
+
+// confirm asks the given yes/no query and returns true if user answers yes.
+// defaultYes is used when user inputs nothing.
+func (d *Dutyme) confirm(query string, defaultYes bool) (bool, error) {
+	defaultAns := "N"
+	if defaultYes {
+		defaultAns = "Y"
+	}
+
+	ans, err := d.UI.Ask(query, &input.Options{
+		Default:     defaultAns,
+		Loop:        true,
+		HideOrder:   true,
+		HideDefault: true,
+		ValidateFunc: func(s string) error {
+			if s != "Y" && s != "y" && s != "N" && s != "n" {
+				return fmt.Errorf("input must be Y or n")
+			}
+			return nil
+		},
+	})
+
+	if err != nil {
+		return false, errors.Wrap(err, "failed to ask")
+	}
+
+	return ans == "Y" || ans == "y", nil
 }
 
 func (d *Dutyme) GetOverride(scheduleID string, user *User, since, until time.Time) (string, error) {
@@ -122,29 +267,25 @@ func (d *Dutyme) GetOverride(scheduleID string, user *User, since, until time.Ti
 		return "", err
 	}
 
-	targets := make([]string, 0, len(overrides))
+	items := make([]finder.Item, 0, len(overrides))
 	for _, override := range overrides {
 		// Filter override by user
 		if override.User.ID == user.Obj.ID {
 			s := fmt.Sprintf("%s: %s - %s", override.ID, override.Start, override.End)
-			targets = append(targets, s)
+			items = append(items, finder.Item{Label: s})
 		}
 	}
 
 	var target string
-	if len(targets) > 1 {
-		var err error
+	if len(items) > 1 {
 		query := "Found multiple overrides. Select one."
-		target, err = d.UI.Select(query, targets, &input.Options{
-			Default: targets[0],
-			Loop:    true,
-		})
+		i, err := d.selectItem(query, items)
 		if err != nil {
 			return "", errors.Wrap(err, "failed to select override from the given list")
 		}
-
+		target = items[i].Label
 	} else {
-		target = targets[0]
+		target = items[0].Label
 	}
 
 	return target[:strings.Index(target, ":")], nil
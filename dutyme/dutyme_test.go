@@ -4,9 +4,17 @@ import (
 	"bytes"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/hook"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/notify"
 	"github.com/tcnksm/go-input"
 )
 
@@ -107,6 +115,585 @@ func TestDutyme_Override_cancel(t *testing.T) {
 	}
 }
 
+func TestDutyme_GetService(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	service, err := d.GetService(testServiceName)
+	if err != nil {
+		t.Fatal("GetService failed:", err)
+	}
+
+	if got, want := service.ID, testServiceID; got != want {
+		t.Fatalf("GetService ID = %q, want %q", got, want)
+	}
+}
+
+func TestDutyme_ResolveService(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	chain, err := d.ResolveService(testServiceID, 1)
+	if err != nil {
+		t.Fatal("ResolveService failed:", err)
+	}
+
+	if got, want := chain.EscalationPolicy.ID, testPolicyID; got != want {
+		t.Fatalf("ResolveService policy ID = %q, want %q", got, want)
+	}
+
+	if got, want := len(chain.Schedules), 1; got != want {
+		t.Fatalf("ResolveService schedules number = %d, want %d", got, want)
+	}
+
+	if got, want := chain.Schedules[0].ID, testScheduleID1; got != want {
+		t.Fatalf("ResolveService schedule ID = %q, want %q", got, want)
+	}
+}
+
+func TestDutyme_ResolveService_invalidLevel(t *testing.T) {
+	cases := []int{0, 2, 3}
+	for _, level := range cases {
+		d := testNewDutyme(t, "", "")
+		if _, err := d.ResolveService(testServiceID, level); err == nil {
+			t.Fatalf("ResolveService(level=%d) expects to fail", level)
+		}
+	}
+}
+
+func TestDutyme_MySchedules(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	user, _ := d.PD.GetUser(testEmail)
+	groups, err := d.MySchedules(user)
+	if err != nil {
+		t.Fatal("MySchedules failed:", err)
+	}
+
+	if got, want := len(groups), 2; got != want {
+		t.Fatalf("MySchedules groups number = %d, want %d", got, want)
+	}
+
+	cases := []struct {
+		team       string
+		scheduleID string
+	}{
+		{testTeamName, testScheduleID1},
+		{NoTeam, testScheduleID2},
+	}
+
+	for i, tc := range cases {
+		group := groups[i]
+		if got, want := group.Team.Summary, tc.team; got != want {
+			t.Fatalf("MySchedules team = %q, want %q", got, want)
+		}
+
+		if got, want := len(group.Schedules), 1; got != want {
+			t.Fatalf("MySchedules schedules number = %d, want %d", got, want)
+		}
+
+		if got, want := group.Schedules[0].ID, tc.scheduleID; got != want {
+			t.Fatalf("MySchedules schedule ID = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestDutyme_SelectMySchedule(t *testing.T) {
+	d := testNewDutyme(t, "", "2\n")
+	user, _ := d.PD.GetUser(testEmail)
+	name, id, err := d.SelectMySchedule(user)
+	if err != nil {
+		t.Fatal("SelectMySchedule failed:", err)
+	}
+
+	if want := testScheduleID2; id != want {
+		t.Fatalf("SelectMySchedule ID = %q, want %q", id, want)
+	}
+
+	if want := testScheduleName2; name != want {
+		t.Fatalf("SelectMySchedule name = %q, want %q", name, want)
+	}
+}
+
+func TestDutyme_ConfirmMember(t *testing.T) {
+	cases := []struct {
+		scheduleID string
+		input      string
+		force      bool
+		cancel     bool
+	}{
+		{testScheduleID1, "", false, false},
+		{testScheduleID2, "", false, false},
+		{testScheduleID3, "y\n", false, false},
+		{testScheduleID3, "\n", false, true},
+		{testScheduleID3, "", true, false},
+	}
+
+	for i, tc := range cases {
+		d := testNewDutyme(t, "", tc.input)
+		user, _ := d.PD.GetUser(testEmail)
+		err := d.ConfirmMember(tc.scheduleID, user, tc.force)
+		if tc.cancel {
+			if c, ok := err.(*errCancel); !(ok && c.IsCancel()) {
+				t.Fatalf("#%d ConfirmMember must be canceled: %v", i, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("#%d ConfirmMember failed: %s", i, err)
+		}
+	}
+}
+
+func TestDutyme_OpenIncidents(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	user, _ := d.PD.GetUser(testEmail)
+
+	incidents, err := d.OpenIncidents(testScheduleID1, user)
+	if err != nil {
+		t.Fatal("OpenIncidents failed:", err)
+	}
+
+	if len(incidents) != 1 || incidents[0].ID != testIncidentID {
+		t.Fatalf("OpenIncidents = %v, want incident %s", incidents, testIncidentID)
+	}
+
+	// Schedule which isn't used by any escalation policy has no services.
+	incidents, err = d.OpenIncidents(testScheduleID2, user)
+	if err != nil {
+		t.Fatal("OpenIncidents failed:", err)
+	}
+
+	if len(incidents) != 0 {
+		t.Fatalf("OpenIncidents = %v, want no incidents", incidents)
+	}
+}
+
+func TestDutyme_CheckExclusive(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := time.Date(2017, 1, 1, 11, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	err := d.CheckExclusive(testScheduleID1, user, start, end)
+	busy, ok := err.(*BusyError)
+	if !ok {
+		t.Fatalf("expect BusyError: %v", err)
+	}
+
+	if got, want := busy.Overrides[0].User.Summary, "Carol"; got != want {
+		t.Fatalf("holder = %q, want %q", got, want)
+	}
+
+	// Override which already ended doesn't hold the schedule.
+	if err := d.CheckExclusive(testScheduleID1, user, end, end.Add(time.Hour)); err != nil {
+		t.Fatal("CheckExclusive failed:", err)
+	}
+
+	if err := d.CheckExclusive(testScheduleID2, user, start, end); err != nil {
+		t.Fatal("CheckExclusive failed:", err)
+	}
+}
+
+func TestDutyme_ConfirmExclusive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dutyme")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	client := &testPDClient{}
+	d := Dutyme{PD: client, Journal: &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}}
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := time.Date(2017, 1, 1, 11, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	// Carol took the schedule after the check, so back off.
+	override := &pagerduty.Override{ID: "PMINE01"}
+	if _, ok := d.ConfirmExclusive(testScheduleID1, user, override, start, end).(*BusyError); !ok {
+		t.Fatal("expect BusyError")
+	}
+
+	if got, want := client.deleted, []string{"PMINE01"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("deleted = %v, want %v", got, want)
+	}
+
+	// Backing off is recorded as stop.
+	events, err := d.Journal.Events()
+	if err != nil {
+		t.Fatal("Events failed:", err)
+	}
+
+	if len(events) != 1 || events[0].Action != journal.ActionStop || events[0].OverrideID != "PMINE01" {
+		t.Fatalf("events = %v, want stop of PMINE01", events)
+	}
+
+	if err := d.ConfirmExclusive(testScheduleID2, user, override, start, end); err != nil {
+		t.Fatal("ConfirmExclusive failed:", err)
+	}
+
+	// Carol started at the same time but has the higher ID, so keep it.
+	first := &pagerduty.Override{ID: "PAAAAAA", Start: "2017-01-01T11:00:00Z"}
+	if err := d.ConfirmExclusive(testScheduleID1, user, first, start, end); err != nil {
+		t.Fatal("ConfirmExclusive failed:", err)
+	}
+
+	// Carol started later, so keep it.
+	earlier := &pagerduty.Override{ID: "PMINE02", Start: "2017-01-01T10:59:00Z"}
+	if err := d.ConfirmExclusive(testScheduleID1, user, earlier, start, end); err != nil {
+		t.Fatal("ConfirmExclusive failed:", err)
+	}
+
+	if got, want := len(client.deleted), 1; got != want {
+		t.Fatalf("deleted number = %d, want %d", got, want)
+	}
+
+	// The override is deleted when it can't be confirmed.
+	client.err = errors.New("network is unreachable")
+	if err := d.ConfirmExclusive(testScheduleID1, user, override, start, end); err == nil {
+		t.Fatal("expect ConfirmExclusive to fail")
+	}
+
+	if got, want := len(client.deleted), 2; got != want {
+		t.Fatalf("deleted number = %d, want %d", got, want)
+	}
+}
+
+func TestDutyme_WaitExclusive(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	user, _ := d.PD.GetUser(testEmail)
+
+	// Fake override on schedule1 ended in 2017, so it's free now.
+	if err := d.WaitExclusive(testScheduleID1, user, time.Hour, time.Second, 10*time.Millisecond, nil); err != nil {
+		t.Fatal("WaitExclusive failed:", err)
+	}
+}
+
+func TestDutyme_ReplaceOverride(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	start := time.Now()
+	end := start.Add(1 * time.Hour)
+	override, err := d.ReplaceOverride(testScheduleID1, testOverrideID, &User{}, start, end)
+	if err != nil {
+		t.Fatal("ReplaceOverride failed:", err)
+	}
+
+	if got, want := override.ID, testOverrideID; got != want {
+		t.Fatalf("ReplaceOverride ID = %q, want %q", got, want)
+	}
+
+	if _, err := d.ReplaceOverride(testScheduleID1, testOverrideID, &User{}, end, start); err == nil {
+		t.Fatal("ReplaceOverride expects to fail when end is before start")
+	}
+}
+
+func TestDutyme_Journal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dutyme")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := testNewDutyme(t, "", "")
+	d.Journal = &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := time.Now()
+	end := start.Add(1 * time.Hour)
+	if _, err := d.Override(testScheduleID1, user, start, end, true); err != nil {
+		t.Fatal("Override failed:", err)
+	}
+
+	active, err := d.ActiveOverrides()
+	if err != nil {
+		t.Fatal("ActiveOverrides failed:", err)
+	}
+
+	if got, want := len(active), 1; got != want {
+		t.Fatalf("active overrides number = %d, want %d", got, want)
+	}
+
+	if got, want := active[0].ScheduleName, testScheduleName1; got != want {
+		t.Fatalf("recorded schedule name = %q, want %q", got, want)
+	}
+
+	if _, _, err := d.Extend(active[0], user, 30*time.Minute); err != nil {
+		t.Fatal("Extend failed:", err)
+	}
+
+	events, err := d.Journal.Events()
+	if err != nil {
+		t.Fatal("Events failed:", err)
+	}
+
+	if got, want := events[1].Action, journal.ActionExtend; got != want {
+		t.Fatalf("recorded action = %q, want %q", got, want)
+	}
+
+	if got, want := events[1].End, end.Add(30*time.Minute); !got.Equal(want) {
+		t.Fatalf("extended end = %s, want %s", got, want)
+	}
+
+	if err := d.DeleteOverride(testScheduleID1, testOverrideID); err != nil {
+		t.Fatal("DeleteOverride failed:", err)
+	}
+
+	active, err = d.ActiveOverrides()
+	if err != nil {
+		t.Fatal("ActiveOverrides failed:", err)
+	}
+
+	if got, want := len(active), 0; got != want {
+		t.Fatalf("active overrides number = %d, want %d", got, want)
+	}
+}
+
+func TestDutyme_Reason(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dutyme")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := testNewDutyme(t, "", "")
+	d.Journal = &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	d.ReasonPolicy = &ReasonPolicy{
+		Schedules:     []string{testScheduleID1},
+		TicketPattern: regexp.MustCompile(`^OPS-[0-9]+$`),
+	}
+	user, _ := d.PD.GetUser(testEmail)
+
+	if err := d.CheckReason(testScheduleID2); err != nil {
+		t.Fatalf("expects schedule %s not to require reason: %s", testScheduleID2, err)
+	}
+
+	start := time.Now()
+	end := start.Add(1 * time.Hour)
+	_, err = d.Override(testScheduleID1, user, start, end, true)
+	if _, ok := err.(*ReasonError); !ok {
+		t.Fatalf("expects ReasonError without reason: %v", err)
+	}
+
+	d.Ticket = "123"
+	if err := d.CheckReason(testScheduleID1); err == nil {
+		t.Fatal("expects ticket not matching the pattern to be invalid")
+	}
+
+	d.Ticket = "OPS-123"
+	if _, err := d.Override(testScheduleID1, user, start, end, true); err != nil {
+		t.Fatal("Override failed:", err)
+	}
+
+	active, err := d.ActiveOverrides()
+	if err != nil {
+		t.Fatal("ActiveOverrides failed:", err)
+	}
+
+	if _, _, err := d.WithReason("", "").Extend(active[0], user, 30*time.Minute); err != nil {
+		t.Fatal("Extend failed:", err)
+	}
+
+	events, err := d.Journal.Events()
+	if err != nil {
+		t.Fatal("Events failed:", err)
+	}
+
+	// Extension inherits the ticket of the original override.
+	for _, e := range events {
+		if got, want := e.Ticket, "OPS-123"; got != want {
+			t.Fatalf("recorded ticket of %s = %q, want %q", e.Action, got, want)
+		}
+	}
+}
+
+func TestDutyme_StopFor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dutyme")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := testNewDutyme(t, "", "")
+	d.Journal = &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := time.Now()
+	if _, err := d.Override(testScheduleID1, user, start, start.Add(time.Hour), true); err != nil {
+		t.Fatal("Override failed:", err)
+	}
+
+	// Others overrides are not stopped.
+	other := &User{Email: "other@dutyme.com"}
+	stopped, err := d.StopFor(other, "")
+	if err != nil {
+		t.Fatal("StopFor failed:", err)
+	}
+
+	if got, want := len(stopped), 0; got != want {
+		t.Fatalf("stopped overrides number = %d, want %d", got, want)
+	}
+
+	stopped, err = d.StopFor(user, testScheduleID1)
+	if err != nil {
+		t.Fatal("StopFor failed:", err)
+	}
+
+	if got, want := len(stopped), 1; got != want {
+		t.Fatalf("stopped overrides number = %d, want %d", got, want)
+	}
+}
+
+func TestDutyme_CheckStale(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	user, _ := d.PD.GetUser(testEmail)
+
+	stale, err := d.CheckStale(user, testScheduleID1, testScheduleName1)
+	if err != nil {
+		t.Fatal("CheckStale failed:", err)
+	}
+
+	if len(stale) != 0 {
+		t.Fatalf("expects no stale setting: %v", stale)
+	}
+
+	renamed := &User{Email: testEmail, Obj: &pagerduty.APIObject{ID: testUserID, Summary: "Old name"}}
+	stale, err = d.CheckStale(renamed, "PDELETED", "")
+	if err != nil {
+		t.Fatal("CheckStale failed:", err)
+	}
+
+	var keys []string
+	for _, s := range stale {
+		keys = append(keys, s.Key)
+	}
+
+	if got, want := keys, []string{"user.Obj.summary", "schedule_id"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("stale keys = %v, want %v", got, want)
+	}
+
+	stale, err = d.CheckStale(&User{Email: "deleted@dutyme.com"}, testScheduleID1, "Old schedule")
+	if err != nil {
+		t.Fatal("CheckStale failed:", err)
+	}
+
+	if got, want := len(stale), 2; got != want {
+		t.Fatalf("stale settings number = %d, want %d", got, want)
+	}
+}
+
+type testNotifier struct {
+	notifications []*notify.Notification
+}
+
+func (n *testNotifier) Notify(notification *notify.Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func TestDutyme_Notify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dutyme")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	notifier := &testNotifier{}
+	d := testNewDutyme(t, "", "")
+	d.Journal = &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	d.Notifiers = []notify.Notifier{notifier}
+	d.Reason = "deploy v1.2.0"
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	if _, err := d.Override(testScheduleID1, user, start, start.Add(4*time.Hour), true); err != nil {
+		t.Fatal("Override failed:", err)
+	}
+
+	if got, want := len(notifier.notifications), 1; got != want {
+		t.Fatalf("notifications number = %d, want %d", got, want)
+	}
+
+	n := notifier.notifications[0]
+	if got, want := n.Action, notify.ActionStart; got != want {
+		t.Fatalf("notified action = %q, want %q", got, want)
+	}
+
+	if got, want := n.ScheduleName, testScheduleName1; got != want {
+		t.Fatalf("notified schedule name = %q, want %q", got, want)
+	}
+
+	if got, want := n.Displaced, []string{"Alice", "Bob"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("displaced = %v, want %v", got, want)
+	}
+
+	if got, want := n.DisplacedEmails, []string{"alice@example.com", "bob@example.com"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("displaced emails = %v, want %v", got, want)
+	}
+
+	if got, want := n.Reason, d.Reason; got != want {
+		t.Fatalf("notified reason = %q, want %q", got, want)
+	}
+
+	if err := d.DeleteOverride(testScheduleID1, testOverrideID); err != nil {
+		t.Fatal("DeleteOverride failed:", err)
+	}
+
+	if got, want := len(notifier.notifications), 2; got != want {
+		t.Fatalf("notifications number = %d, want %d", got, want)
+	}
+
+	n = notifier.notifications[1]
+	if got, want := n.Action, notify.ActionStop; got != want {
+		t.Fatalf("notified action = %q, want %q", got, want)
+	}
+
+	if got, want := n.User, testEmail; got != want {
+		t.Fatalf("notified user = %q, want %q", got, want)
+	}
+}
+
+func TestDutyme_Hooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dutyme")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := testNewDutyme(t, "", "")
+	d.Journal = &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	d.Hooks = &hook.Runner{
+		Commands: map[hook.Point]string{
+			hook.PreStart: "exit 1",
+			hook.PreStop:  "exit 1",
+		},
+	}
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := time.Now()
+	if _, err := d.Override(testScheduleID1, user, start, start.Add(time.Hour), true); err == nil {
+		t.Fatal("expects overriding to be aborted by pre_start hook")
+	}
+
+	if err := d.DeleteOverride(testScheduleID1, testOverrideID); err == nil {
+		t.Fatal("expects stopping to be aborted by pre_stop hook")
+	}
+
+	events, err := d.Journal.Events()
+	if err != nil {
+		t.Fatal("Events failed:", err)
+	}
+
+	if got, want := len(events), 0; got != want {
+		t.Fatalf("recorded events number = %d, want %d", got, want)
+	}
+
+	// Failing post hook doesn't fail overriding.
+	d.Hooks.Commands = map[hook.Point]string{
+		hook.PostStart: "exit 1",
+	}
+	if _, err := d.Override(testScheduleID1, user, start, start.Add(time.Hour), true); err != nil {
+		t.Fatal("Override failed:", err)
+	}
+}
+
 func TestGetOverride(t *testing.T) {
 	token := os.Getenv(EnvTestToken)
 	email := os.Getenv(EnvTestEmail)
@@ -236,3 +823,24 @@ func TestGetOverride_withoutAsking(t *testing.T) {
 		t.Fatalf("expect %q to be eq %q", overrideID, override.ID)
 	}
 }
+
+func TestDutyme_Schedules(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+
+	schedules, err := d.Schedules([]string{testScheduleID1, testScheduleID2})
+	if err != nil {
+		t.Fatal("Schedules failed:", err)
+	}
+
+	want := []pagerduty.APIObject{
+		{ID: testScheduleID1, Summary: testScheduleName1},
+		{ID: testScheduleID2, Summary: testScheduleName2},
+	}
+	if !reflect.DeepEqual(schedules, want) {
+		t.Fatalf("Schedules = %#v, want %#v", schedules, want)
+	}
+
+	if _, err := d.Schedules([]string{testScheduleID1, "PDELETED"}); !IsNotFound(err) {
+		t.Fatalf("Schedules with deleted schedule = %v, want not found", err)
+	}
+}
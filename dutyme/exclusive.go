@@ -0,0 +1,143 @@
+package dutyme
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+)
+
+// DefaultPollInterval is default interval of checking the schedule
+// while waiting for it to be free.
+const DefaultPollInterval = 30 * time.Second
+
+// BusyError is returned when other users hold overrides on the schedule.
+type BusyError struct {
+	ScheduleID string
+	Overrides  []pagerduty.Override
+}
+
+func (e *BusyError) Error() string {
+	holders := make([]string, 0, len(e.Overrides))
+	for _, o := range e.Overrides {
+		name := o.User.Summary
+		if len(name) == 0 {
+			name = o.User.ID
+		}
+		holders = append(holders, fmt.Sprintf("%s until %s (override %s)", name, o.End, o.ID))
+	}
+	return fmt.Sprintf("schedule %s is held by %s", e.ScheduleID, strings.Join(holders, ", "))
+}
+
+// CheckExclusive checks that no other users hold overrides on the given
+// schedule between start and end. If others do, it returns *BusyError.
+func (d *Dutyme) CheckExclusive(scheduleID string, user *User, start, end time.Time) error {
+	overrides, err := d.PD.GetAllOverrides(scheduleID, start, end)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	var others []pagerduty.Override
+	for _, o := range overrides {
+		if user != nil && user.Obj != nil && o.User.ID == user.Obj.ID {
+			continue
+		}
+
+		// Overrides which already ended are not holding the schedule.
+		if oEnd, err := time.Parse(time.RFC3339, o.End); err == nil && !oEnd.After(start) {
+			continue
+		}
+		others = append(others, o)
+	}
+
+	if len(others) > 0 {
+		return &BusyError{ScheduleID: scheduleID, Overrides: others}
+	}
+	return nil
+}
+
+// ConfirmExclusive checks again that no other users hold overrides
+// on the given schedule after the override (created by Override between
+// start and end) is created. Other users may have taken the schedule
+// between CheckExclusive and Override. When both took it at the same
+// time, the override which started first (the lower ID if they started
+// at the same time) is kept, so exactly one of them backs off. If the
+// override must back off, it deletes the override and returns
+// *BusyError. The override is also deleted when the check fails.
+func (d *Dutyme) ConfirmExclusive(scheduleID string, user *User, override *pagerduty.Override, start, end time.Time) error {
+	err := d.CheckExclusive(scheduleID, user, start, end)
+	if err == nil {
+		return nil
+	}
+
+	if busy, ok := err.(*BusyError); ok {
+		var winners []pagerduty.Override
+		for _, o := range busy.Overrides {
+			if precedes(o, *override, start) {
+				winners = append(winners, o)
+			}
+		}
+		if len(winners) == 0 {
+			return nil
+		}
+		err = &BusyError{ScheduleID: scheduleID, Overrides: winners}
+	}
+
+	if derr := d.DeleteOverride(scheduleID, override.ID); derr != nil {
+		return errors.Wrapf(derr, "failed to delete override %s (%s)", override.ID, err)
+	}
+	return err
+}
+
+// precedes returns true if the override o holds the schedule before
+// the override mine: o started earlier or, at the same time, o has the
+// lower ID. start is used when the start of mine is unknown.
+func precedes(o, mine pagerduty.Override, start time.Time) bool {
+	if t, err := time.Parse(time.RFC3339, mine.Start); err == nil {
+		start = t
+	}
+
+	oStart, err := time.Parse(time.RFC3339, o.Start)
+	if err != nil {
+		return true
+	}
+
+	if !oStart.Equal(start) {
+		return oStart.Before(start)
+	}
+	return o.ID < mine.ID
+}
+
+// WaitExclusive waits until no other users hold overrides on the given
+// schedule for the working time from now, checking every interval. It
+// gives up after timeout and returns the last *BusyError. notify is
+// called with the *BusyError on each check while waiting (it can be nil).
+func (d *Dutyme) WaitExclusive(scheduleID string, user *User, working, timeout, interval time.Duration, notify func(error)) error {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		now := time.Now()
+		err := d.CheckExclusive(scheduleID, user, now, now.Add(working))
+		busy, ok := err.(*BusyError)
+		if !ok {
+			return err
+		}
+
+		if !now.Add(interval).Before(deadline) {
+			return busy
+		}
+
+		if notify != nil {
+			notify(busy)
+		}
+		time.Sleep(interval)
+	}
+}
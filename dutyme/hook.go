@@ -0,0 +1,41 @@
+package dutyme
+
+import (
+	"fmt"
+
+	"github.com/tcnksm/dutyme/hook"
+	"github.com/tcnksm/dutyme/journal"
+)
+
+// hookPayload returns the payload of the hook for the given event.
+// user is used when it's not recorded on the event.
+func hookPayload(e journal.Event, user *User) hook.Payload {
+	p := hook.Payload{
+		OverrideID:   e.OverrideID,
+		ScheduleID:   e.ScheduleID,
+		ScheduleName: e.ScheduleName,
+		UserID:       e.UserID,
+		UserEmail:    e.UserEmail,
+		Start:        e.Start,
+		End:          e.End,
+		Reason:       e.Reason,
+		Ticket:       e.Ticket,
+	}
+
+	if user != nil {
+		p.UserEmail = user.Email
+		if user.Obj != nil {
+			p.UserID = user.Obj.ID
+		}
+	}
+
+	return p
+}
+
+// runHook executes the post hook. The action is already done on
+// PagerDuty, so failing the hook doesn't fail the action but it's warned.
+func (d *Dutyme) runHook(point hook.Point, p hook.Payload) {
+	if err := d.Hooks.Run(point, p); err != nil && d.UI != nil && d.UI.Writer != nil {
+		fmt.Fprintf(d.UI.Writer, "WARNING: %s\n", err)
+	}
+}
@@ -0,0 +1,59 @@
+package dutyme
+
+import (
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+)
+
+// OpenStatuses are the statuses of incidents which are not resolved yet.
+var OpenStatuses = []string{"triggered", "acknowledged"}
+
+// ScheduleServices returns IDs of the services whose escalation policies
+// use the given schedule.
+func (d *Dutyme) ScheduleServices(scheduleID string) ([]string, error) {
+	schedule, err := d.PD.GetSchedule(scheduleID, time.Now(), time.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get schedule")
+	}
+
+	seen := make(map[string]bool)
+	var serviceIDs []string
+	for _, ref := range schedule.EscalationPolicies {
+		policy, err := d.PD.GetEscalationPolicy(ref.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get escalation policy")
+		}
+
+		for _, service := range policy.Services {
+			if seen[service.ID] {
+				continue
+			}
+			seen[service.ID] = true
+			serviceIDs = append(serviceIDs, service.ID)
+		}
+	}
+
+	return serviceIDs, nil
+}
+
+// OpenIncidents returns the triggered or acknowledged incidents assigned
+// to the given user on the services of the given schedule.
+func (d *Dutyme) OpenIncidents(scheduleID string, user *User) ([]pagerduty.Incident, error) {
+	if user == nil || user.Obj == nil {
+		return nil, errors.New("user is not resolved")
+	}
+
+	serviceIDs, err := d.ScheduleServices(scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without service IDs, incidents on all services are listed.
+	if len(serviceIDs) == 0 {
+		return nil, nil
+	}
+
+	return d.PD.ListIncidents(user.Obj.ID, serviceIDs, OpenStatuses)
+}
@@ -0,0 +1,236 @@
+package dutyme
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/hook"
+	"github.com/tcnksm/dutyme/journal"
+)
+
+// DeleteOverride deletes the given override, records it on journal,
+// executes hooks and notifies notifiers.
+func (d *Dutyme) DeleteOverride(scheduleID, overrideID string) error {
+	// Details of the override (who and until when) are only on journal.
+	var e *journal.Event
+	if len(d.Notifiers) != 0 || d.Hooks != nil {
+		e = d.recorded(overrideID)
+	}
+
+	payload := hook.Payload{OverrideID: overrideID, ScheduleID: scheduleID}
+	if e != nil {
+		payload = hookPayload(*e, nil)
+	}
+
+	if err := d.Hooks.Run(hook.PreStop, payload); err != nil {
+		return errors.Wrap(err, "stopping override is aborted")
+	}
+
+	if err := d.PD.DeleteOverride(scheduleID, overrideID); err != nil {
+		return err
+	}
+
+	d.record(journal.Event{
+		Action:     journal.ActionStop,
+		OverrideID: overrideID,
+		ScheduleID: scheduleID,
+	}, nil)
+	d.runHook(hook.PostStop, payload)
+
+	if len(d.Notifiers) != 0 {
+		d.notify(d.stopNotification(scheduleID, overrideID, e))
+	}
+
+	return nil
+}
+
+// ActiveOverrides returns the overrides created by dutyme which are
+// not stopped and not ended yet (sorted by end time).
+func (d *Dutyme) ActiveOverrides() ([]journal.Event, error) {
+	if d.Journal == nil {
+		return nil, errors.New("journal is not configured")
+	}
+
+	events, err := d.Journal.Events()
+	if err != nil {
+		return nil, err
+	}
+
+	return journal.Active(events, time.Now()), nil
+}
+
+// FindActive returns the active override created by dutyme which has
+// the given ID. If ID is empty, it returns the one which ends first.
+func (d *Dutyme) FindActive(overrideID string) (*journal.Event, error) {
+	active, err := d.ActiveOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, e := range active {
+		if overrideID == "" || e.OverrideID == overrideID {
+			return &active[i], nil
+		}
+	}
+
+	if overrideID == "" {
+		return nil, &errNotFound{"no active override created by dutyme is found"}
+	}
+	return nil, &errNotFound{fmt.Sprintf("no such active override: %s", overrideID)}
+}
+
+// Stop stops the active override created by dutyme which has the given
+// ID (or the one which ends first if ID is empty). If the override has
+// already started, PagerDuty truncates it to end now.
+func (d *Dutyme) Stop(overrideID string) (*journal.Event, error) {
+	e, err := d.FindActive(overrideID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.DeleteOverride(e.ScheduleID, e.OverrideID); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// ActiveOverridesFor returns the active overrides created by dutyme
+// for the given user.
+func (d *Dutyme) ActiveOverridesFor(user *User) ([]journal.Event, error) {
+	active, err := d.ActiveOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	var mine []journal.Event
+	for _, e := range active {
+		if SameUser(e, user) {
+			mine = append(mine, e)
+		}
+	}
+	return mine, nil
+}
+
+// StopFor stops all active overrides created by dutyme for the given
+// user on the given schedule (on all schedules if scheduleID is empty).
+// It returns the stopped overrides.
+func (d *Dutyme) StopFor(user *User, scheduleID string) ([]journal.Event, error) {
+	active, err := d.ActiveOverridesFor(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var stopped []journal.Event
+	for _, e := range active {
+		if scheduleID != "" && e.ScheduleID != scheduleID {
+			continue
+		}
+
+		if err := d.DeleteOverride(e.ScheduleID, e.OverrideID); err != nil {
+			return stopped, err
+		}
+		stopped = append(stopped, e)
+	}
+
+	return stopped, nil
+}
+
+// recorded returns the latest event of the given override recorded on
+// journal. It returns nil if it's not found (or journal is not set).
+func (d *Dutyme) recorded(overrideID string) *journal.Event {
+	if d.Journal == nil {
+		return nil
+	}
+
+	events, err := d.Journal.Events()
+	if err != nil {
+		return nil
+	}
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].OverrideID == overrideID && events[i].Action != journal.ActionStop {
+			return &events[i]
+		}
+	}
+	return nil
+}
+
+// SameUser returns true if the event is recorded for the given user.
+func SameUser(e journal.Event, user *User) bool {
+	if user == nil {
+		return false
+	}
+
+	if user.Obj != nil && len(e.UserID) != 0 {
+		return e.UserID == user.Obj.ID
+	}
+	return len(e.UserEmail) != 0 && e.UserEmail == user.Email
+}
+
+// Extend extends the override recorded on journal by the given duration.
+// The part of the override which is already past is not re-created.
+// The total duration since the first override started (extending
+// repeatedly) is capped by MaxDuration of policy.
+func (d *Dutyme) Extend(e journal.Event, user *User, by time.Duration) (*pagerduty.Override, time.Time, error) {
+	if by <= 0 {
+		return nil, time.Time{}, errors.New("extending duration must be positive")
+	}
+
+	start := e.Start
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+	end := e.End.Add(by)
+
+	// The event itself knows when the user went on call even if it's
+	// not recorded on journal (e.g., the segment of hold).
+	since := e.Since
+	if since.IsZero() {
+		since = e.Start
+	}
+
+	override, err := d.replaceOverride(e.ScheduleID, e.OverrideID, user, d.since(e.OverrideID, since), start, end)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return override, end, nil
+}
+
+// record records the given event on journal if it's set. Journal is
+// best effort: the override is already changed on PagerDuty, so failing
+// to record doesn't fail the operation but it's warned.
+func (d *Dutyme) record(e journal.Event, user *User) {
+	if d.Journal == nil {
+		return
+	}
+
+	if len(e.ScheduleName) == 0 && e.Action != journal.ActionStop {
+		now := time.Now()
+		if schedule, err := d.PD.GetSchedule(e.ScheduleID, now, now); err == nil {
+			e.ScheduleName = schedule.Name
+		}
+	}
+
+	// Extending keeps the reason of the extended override.
+	if e.Action == journal.ActionExtend && len(e.Reason) == 0 && len(e.Ticket) == 0 {
+		if prev := d.recorded(e.PreviousID); prev != nil {
+			e.Reason, e.Ticket = prev.Reason, prev.Ticket
+		}
+	}
+
+	if user != nil {
+		e.UserEmail = user.Email
+		if user.Obj != nil {
+			e.UserID = user.Obj.ID
+		}
+	}
+
+	if err := d.Journal.Record(e); err != nil && d.UI != nil && d.UI.Writer != nil {
+		fmt.Fprintf(d.UI.Writer, "WARNING: failed to record override %s on journal: %s\n",
+			e.OverrideID, err)
+	}
+}
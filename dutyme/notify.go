@@ -0,0 +1,105 @@
+package dutyme
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/notify"
+)
+
+// rotation returns the schedule name and the users (names and IDs) who
+// are on call between start and end except the given user. It's used
+// only for notification, so failing to get the schedule returns nothing.
+func (d *Dutyme) rotation(scheduleID string, user *User, start, end time.Time) (string, []string, []string) {
+	if !start.Before(end) {
+		return "", nil, nil
+	}
+
+	t, err := d.Timeline(scheduleID, start, end)
+	if err != nil {
+		return "", nil, nil
+	}
+
+	me := userName(user)
+	var users []string
+	for _, u := range OnCall(t.Entries, start, end) {
+		if len(u) == 0 || u == me {
+			continue
+		}
+		users = append(users, u)
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, segment := range t.Entries {
+		if !segment.overlaps(start, end) || len(segment.UserID) == 0 || segment.User == me || seen[segment.UserID] {
+			continue
+		}
+		seen[segment.UserID] = true
+		ids = append(ids, segment.UserID)
+	}
+
+	return t.ScheduleName, users, ids
+}
+
+// emails returns the emails of the given users. It's used only for
+// notification, so the users who can't be got are skipped.
+func (d *Dutyme) emails(userIDs []string) []string {
+	var emails []string
+	for _, id := range userIDs {
+		user, err := d.PD.GetUserByID(id)
+		if err != nil || len(user.Email) == 0 {
+			continue
+		}
+		emails = append(emails, user.Email)
+	}
+	return emails
+}
+
+// stopNotification returns the notification of stopping the given
+// override. e is the override recorded on journal, it may be nil
+// (then the details of the override are unknown). It must be called
+// after the override is deleted to get who is back on call.
+func (d *Dutyme) stopNotification(scheduleID, overrideID string, e *journal.Event) *notify.Notification {
+	now := time.Now()
+	n := &notify.Notification{
+		Action:     notify.ActionStop,
+		OverrideID: overrideID,
+		ScheduleID: scheduleID,
+		Start:      now,
+		End:        now,
+	}
+
+	if e == nil {
+		return n
+	}
+
+	n.ScheduleName = e.ScheduleName
+	n.User = e.UserEmail
+	n.Reason = e.Reason
+	n.Ticket = e.Ticket
+	if e.End.After(now) {
+		n.End = e.End
+		name, users, ids := d.rotation(scheduleID, &User{Email: e.UserEmail}, now, e.End)
+		if len(n.ScheduleName) == 0 {
+			n.ScheduleName = name
+		}
+		n.Displaced = users
+		n.DisplacedEmails = d.emails(ids)
+	}
+
+	return n
+}
+
+// notify sends the given notification to all notifiers. Notifying is
+// best effort: the override is already changed on PagerDuty, so failing
+// to notify doesn't fail the operation but it's warned.
+func (d *Dutyme) notify(n *notify.Notification) {
+	for _, notifier := range d.Notifiers {
+		if err := notifier.Notify(n); err != nil && d.UI != nil && d.UI.Writer != nil {
+			fmt.Fprintf(d.UI.Writer, "WARNING: failed to notify %s of override %s: %s\n",
+				n.Action, n.OverrideID, err)
+		}
+	}
+}
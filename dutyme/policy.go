@@ -0,0 +1,229 @@
+package dutyme
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Policy is guardrails of overriding (e.g., shipped by organization in
+// the system configuration). It's checked by Override and
+// ReplaceOverride, so every entry point obeys it.
+type Policy struct {
+	// MaxDuration is the longest override. Extending can't keep the
+	// user on call longer than it in total. If zero, it's unlimited.
+	MaxDuration time.Duration
+
+	// Hours is the time of day when overrides are allowed. Overrides
+	// must start and end in it. If nil, it's any time.
+	Hours *Hours
+
+	// Weekdays are the days when overrides are allowed. If empty,
+	// it's every day.
+	Weekdays []time.Weekday
+
+	// Location is the time zone of Hours and Weekdays. If nil, local
+	// time zone is used.
+	Location *time.Location
+
+	// Protected are IDs of the schedules which can be overridden only
+	// by force (-force) with reason.
+	Protected []string
+}
+
+// Hours is the window of time of day in minutes from midnight. If From
+// is after To, the window is overnight (e.g., 22:00-06:00).
+type Hours struct {
+	From int
+	To   int
+}
+
+// ParseHours parses the window like "09:00-18:00".
+func ParseHours(s string) (*Hours, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return nil, errors.Errorf("hours must be like 09:00-18:00: %q", s)
+	}
+
+	from, err := parseClock(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parseClock(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if from == to || from == 24*60 {
+		return nil, errors.Errorf("hours must not be empty: %q", s)
+	}
+
+	return &Hours{From: from, To: to}, nil
+}
+
+// parseClock parses time of day like "09:00" as minutes from midnight.
+// "24:00" is allowed as the end of the day.
+func parseClock(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Errorf("invalid time of day (must be like 09:00): %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (h *Hours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", h.From/60, h.From%60, h.To/60, h.To%60)
+}
+
+// overnight returns true if the window crosses midnight.
+func (h *Hours) overnight() bool {
+	return h.From > h.To
+}
+
+// contains returns true if the given minutes from midnight are in the
+// window.
+func (h *Hours) contains(min int) bool {
+	if h.overnight() {
+		return min >= h.From || min < h.To
+	}
+	return min >= h.From && min < h.To
+}
+
+// ParseWeekday parses the day of week like "mon" or "Monday" (case
+// insensitive).
+func ParseWeekday(s string) (time.Weekday, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, nil
+		}
+	}
+	return 0, errors.Errorf("invalid weekday (must be like mon): %q", s)
+}
+
+// PolicyError is returned when overriding violates the policy.
+type PolicyError struct {
+	msg string
+}
+
+func (e *PolicyError) Error() string {
+	return e.msg
+}
+
+func policyErrorf(format string, args ...interface{}) error {
+	return &PolicyError{fmt.Sprintf(format, args...)}
+}
+
+// Protected returns true if the given schedule is protected by the
+// policy.
+func (d *Dutyme) Protected(scheduleID string) bool {
+	if d.Policy == nil {
+		return false
+	}
+
+	for _, id := range d.Policy.Protected {
+		if id == scheduleID {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPolicy checks overriding the schedule from start to end obeys the
+// policy. Protected schedules require Force and reason (or ticket).
+func (d *Dutyme) CheckPolicy(scheduleID string, start, end time.Time) error {
+	if d.Policy == nil {
+		return nil
+	}
+
+	if err := d.Policy.checkTime(start, end); err != nil {
+		return err
+	}
+
+	if d.Protected(scheduleID) && (!d.Force || (len(d.Reason) == 0 && len(d.Ticket) == 0)) {
+		return policyErrorf("schedule %s is protected by policy: it can be overridden only with -force and -reason (or -ticket)", scheduleID)
+	}
+	return nil
+}
+
+// checkSpan checks that the user is not on call longer than MaxDuration
+// from since (when the first override started) to end. Extending
+// repeatedly must not keep the user on call forever.
+func (p *Policy) checkSpan(since, end time.Time) error {
+	if d := end.Sub(since); p.MaxDuration > 0 && d > p.MaxDuration {
+		d = d / time.Second * time.Second
+		return policyErrorf("on call for %s since %s exceeds the maximum duration %s allowed by policy",
+			d, since.Local().Format("01/02 15:04"), p.MaxDuration)
+	}
+	return nil
+}
+
+// checkTime checks the duration and the time of the override.
+func (p *Policy) checkTime(start, end time.Time) error {
+	if d := end.Sub(start); p.MaxDuration > 0 && d > p.MaxDuration {
+		return policyErrorf("override for %s exceeds the maximum duration %s allowed by policy", d, p.MaxDuration)
+	}
+
+	loc := p.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	start = start.In(loc)
+
+	// day is the day when the window which the override starts in
+	// opens. It's the previous day after midnight of overnight window.
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+
+	if h := p.Hours; h != nil {
+		min := start.Hour()*60 + start.Minute()
+		if !h.contains(min) {
+			return policyErrorf("overriding is allowed only during %s (%s) by policy, but it starts at %s",
+				h, loc, start.Format("15:04"))
+		}
+
+		closing := day
+		if h.overnight() {
+			if min < h.To {
+				day = day.AddDate(0, 0, -1)
+			} else {
+				closing = closing.AddDate(0, 0, 1)
+			}
+		}
+		closing = closing.Add(time.Duration(h.To) * time.Minute)
+
+		if end.After(closing) {
+			return policyErrorf("overriding is allowed only during %s (%s) by policy, but it ends at %s (it must end by %s)",
+				h, loc, end.In(loc).Format("Mon 15:04"), closing.Format("Mon 15:04"))
+		}
+	}
+
+	if len(p.Weekdays) != 0 && !containsWeekday(p.Weekdays, day.Weekday()) {
+		names := make([]string, 0, len(p.Weekdays))
+		for _, w := range p.Weekdays {
+			names = append(names, w.String()[:3])
+		}
+		return policyErrorf("overriding is allowed only on %s (%s) by policy, but it starts on %s",
+			strings.Join(names, ", "), loc, day.Weekday())
+	}
+
+	return nil
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+package dutyme
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/journal"
+)
+
+func TestParseHours(t *testing.T) {
+	cases := map[string]string{
+		"09:00-18:00":  "09:00-18:00",
+		"22:00-06:00":  "22:00-06:00",
+		"9:30 - 24:00": "09:30-24:00",
+	}
+	for s, want := range cases {
+		h, err := ParseHours(s)
+		if err != nil {
+			t.Fatalf("ParseHours(%q) failed: %s", s, err)
+		}
+		if got := h.String(); got != want {
+			t.Fatalf("ParseHours(%q)=%s, want=%s", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"", "09:00", "09:00-09:00", "9am-6pm", "24:00-06:00"} {
+		if _, err := ParseHours(s); err == nil {
+			t.Fatalf("expects ParseHours(%q) to fail", s)
+		}
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	cases := map[string]time.Weekday{
+		"mon":      time.Monday,
+		"Saturday": time.Saturday,
+		" SUN ":    time.Sunday,
+	}
+	for s, want := range cases {
+		got, err := ParseWeekday(s)
+		if err != nil {
+			t.Fatalf("ParseWeekday(%q) failed: %s", s, err)
+		}
+		if got != want {
+			t.Fatalf("ParseWeekday(%q)=%s, want=%s", s, got, want)
+		}
+	}
+
+	if _, err := ParseWeekday("mo"); err == nil {
+		t.Fatal("expects ParseWeekday to fail")
+	}
+}
+
+func TestDutyme_CheckPolicy(t *testing.T) {
+	hours, _ := ParseHours("09:00-18:00")
+	night, _ := ParseHours("22:00-06:00")
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+	// 2017-01-02 is Monday.
+	monday := func(clock string) time.Time {
+		return testTime(t, "2017-01-02T"+clock+":00Z")
+	}
+
+	cases := []struct {
+		policy     Policy
+		start, end time.Time
+		violation  string
+	}{
+		{
+			policy: Policy{MaxDuration: 8 * time.Hour},
+			start:  monday("10:00"),
+			end:    monday("18:00"),
+		},
+		{
+			policy:    Policy{MaxDuration: 8 * time.Hour},
+			start:     monday("10:00"),
+			end:       monday("10:00").Add(200 * time.Hour),
+			violation: "exceeds the maximum duration 8h0m0s",
+		},
+		{
+			policy: Policy{Hours: hours, Weekdays: weekdays, Location: time.UTC},
+			start:  monday("09:00"),
+			end:    monday("18:00"),
+		},
+		{
+			policy:    Policy{Hours: hours, Location: time.UTC},
+			start:     monday("08:30"),
+			end:       monday("10:00"),
+			violation: "it starts at 08:30",
+		},
+		{
+			policy:    Policy{Hours: hours, Location: time.UTC},
+			start:     monday("17:00"),
+			end:       monday("19:00"),
+			violation: "it must end by Mon 18:00",
+		},
+		{
+			policy:    Policy{Weekdays: weekdays, Location: time.UTC},
+			start:     monday("10:00").AddDate(0, 0, -2),
+			end:       monday("11:00").AddDate(0, 0, -2),
+			violation: "it starts on Saturday",
+		},
+		{
+			// Overnight window after midnight belongs to the previous day.
+			policy: Policy{Hours: night, Weekdays: weekdays, Location: time.UTC},
+			start:  monday("23:00").AddDate(0, 0, 1),
+			end:    monday("05:00").AddDate(0, 0, 2),
+		},
+		{
+			policy:    Policy{Hours: night, Weekdays: weekdays, Location: time.UTC},
+			start:     monday("02:00"),
+			end:       monday("05:00"),
+			violation: "it starts on Sunday",
+		},
+		{
+			policy:    Policy{Hours: night, Location: time.UTC},
+			start:     monday("23:00"),
+			end:       monday("07:00").AddDate(0, 0, 1),
+			violation: "it must end by Tue 06:00",
+		},
+	}
+
+	for i, tc := range cases {
+		d := &Dutyme{Policy: &tc.policy}
+		err := d.CheckPolicy(testScheduleID1, tc.start, tc.end)
+		if len(tc.violation) == 0 {
+			if err != nil {
+				t.Fatalf("#%d CheckPolicy failed: %s", i, err)
+			}
+			continue
+		}
+
+		if _, ok := err.(*PolicyError); !ok {
+			t.Fatalf("#%d expects PolicyError: %v", i, err)
+		}
+
+		if !strings.Contains(err.Error(), tc.violation) {
+			t.Fatalf("#%d error %q should contain %q", i, err, tc.violation)
+		}
+	}
+}
+
+func TestDutyme_CheckPolicy_protected(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	d.Policy = &Policy{Protected: []string{testScheduleID1}}
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := time.Now()
+	end := start.Add(time.Hour)
+
+	if err := d.CheckPolicy(testScheduleID2, start, end); err != nil {
+		t.Fatalf("expects schedule %s not to be protected: %s", testScheduleID2, err)
+	}
+
+	if _, err := d.Override(testScheduleID1, user, start, end, true); err == nil {
+		t.Fatal("expects protected schedule not to be overridden without force")
+	}
+
+	d.Force = true
+	if err := d.CheckPolicy(testScheduleID1, start, end); err == nil {
+		t.Fatal("expects protected schedule not to be overridden without reason")
+	}
+
+	d.Reason = "hotfix"
+	if _, err := d.Override(testScheduleID1, user, start, end, true); err != nil {
+		t.Fatal("Override failed:", err)
+	}
+}
+
+func TestDutyme_Extend_maxDuration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dutyme")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := testNewDutyme(t, "", "")
+	d.Journal = &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")}
+	d.Policy = &Policy{MaxDuration: 2 * time.Hour}
+	user, _ := d.PD.GetUser(testEmail)
+
+	// On call for 90 minutes and 20 minutes left.
+	now := time.Now()
+	e := journal.Event{
+		Action:     journal.ActionStart,
+		OverrideID: "PFIRST1",
+		ScheduleID: testScheduleID1,
+		Start:      now.Add(-90 * time.Minute),
+		End:        now.Add(20 * time.Minute),
+	}
+	if err := d.Journal.Record(e); err != nil {
+		t.Fatal("Record failed:", err)
+	}
+
+	// Each extension is short but the total exceeds the max.
+	if _, _, err := d.Extend(e, user, 20*time.Minute); !isPolicyError(err) {
+		t.Fatalf("expects PolicyError: %v", err)
+	}
+
+	_, end, err := d.Extend(e, user, 5*time.Minute)
+	if err != nil {
+		t.Fatal("Extend failed:", err)
+	}
+
+	// Extended override keeps when the first one started.
+	extended := d.recorded(testOverrideID)
+	if extended == nil || !extended.Since.Equal(e.Start) {
+		t.Fatalf("extended override = %#v, want since %s", extended, e.Start)
+	}
+
+	if _, _, err := d.Extend(*extended, user, 10*time.Minute); !isPolicyError(err) {
+		t.Fatalf("expects PolicyError: %v", err)
+	}
+
+	// Override which replaces the one failed to be deleted also keeps it.
+	d.PD.(*testPDClient).deleteErr = errors.New("network is unreachable")
+	if _, _, err := d.Extend(*extended, user, 5*time.Minute); err == nil {
+		t.Fatal("expects Extend to fail")
+	}
+
+	if replaced := d.recorded(testOverrideID); replaced == nil || !replaced.Since.Equal(e.Start) {
+		t.Fatalf("replaced override = %#v, want since %s", replaced, e.Start)
+	}
+	d.PD.(*testPDClient).deleteErr = nil
+
+	if _, _, err := d.Extend(*extended, user, e.Start.Add(2*time.Hour).Sub(end)); err != nil {
+		t.Fatal("Extend failed:", err)
+	}
+}
+
+func isPolicyError(err error) bool {
+	_, ok := err.(*PolicyError)
+	return ok
+}
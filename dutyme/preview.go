@@ -0,0 +1,305 @@
+package dutyme
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PreviewWidth is the number of columns of preview timeline.
+	PreviewWidth = 60
+
+	// previewTimeFmt is used for displaying time on preview.
+	previewTimeFmt = "01/02 15:04"
+
+	// previewYou is the mark of the new on-call on timeline.
+	previewYou = '#'
+
+	// previewNobody is the mark of the gap on timeline.
+	previewNobody = '.'
+)
+
+// Segment is a period when a user is on call.
+type Segment struct {
+	Start time.Time
+	End   time.Time
+
+	// User is the name (summary) of the on-call user and UserID is
+	// its ID. They are empty when nobody is on call (gap).
+	User   string
+	UserID string
+}
+
+func (s Segment) overlaps(start, end time.Time) bool {
+	return s.Start.Before(end) && start.Before(s.End)
+}
+
+// Preview is the final schedule around the override window,
+// before and after overriding.
+type Preview struct {
+	ScheduleName string
+	ScheduleID   string
+
+	// Since and Until is the displayed range.
+	Since time.Time
+	Until time.Time
+
+	// Start and End is the override window.
+	Start time.Time
+	End   time.Time
+
+	// User is the new on-call.
+	User string
+
+	// Current is the current final schedule and Result is the
+	// final schedule after overriding.
+	Current []Segment
+	Result  []Segment
+
+	// Superseded is the existing overrides which overlap with
+	// the override window.
+	Superseded []OverrideSegment
+
+	// Gaps is the periods when nobody is on call after overriding.
+	Gaps []Segment
+}
+
+// Preview computes the final schedule around the given override window.
+// The displayed range is extended by the window duration on both sides.
+func (d *Dutyme) Preview(scheduleID string, user *User, start, end time.Time) (*Preview, error) {
+	if !start.Before(end) {
+		return nil, errors.New("end time must be after start time")
+	}
+
+	margin := end.Sub(start)
+	since, until := start.Add(-margin), end.Add(margin)
+
+	t, err := d.Timeline(scheduleID, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Preview{
+		ScheduleName: t.ScheduleName,
+		ScheduleID:   scheduleID,
+		Since:        since,
+		Until:        until,
+		Start:        start,
+		End:          end,
+		User:         userName(user),
+		Current:      t.Entries,
+	}
+
+	for _, override := range t.Overrides {
+		if override.overlaps(start, end) {
+			p.Superseded = append(p.Superseded, override)
+		}
+	}
+
+	// Result is current schedule where the override window
+	// is replaced with the new on-call.
+	for _, segment := range p.Current {
+		if segment.Start.Before(start) {
+			if s, ok := clip(segment, segment.Start, start); ok {
+				p.Result = append(p.Result, s)
+			}
+		}
+		if segment.End.After(end) {
+			if s, ok := clip(segment, end, segment.End); ok {
+				p.Result = append(p.Result, s)
+			}
+		}
+	}
+	p.Result = append(p.Result, Segment{Start: start, End: end, User: p.User})
+	sort.Sort(bySegmentStart(p.Result))
+
+	cursor := since
+	for _, segment := range p.Result {
+		if segment.Start.After(cursor) {
+			p.Gaps = append(p.Gaps, Segment{Start: cursor, End: segment.Start})
+		}
+		if segment.End.After(cursor) {
+			cursor = segment.End
+		}
+	}
+	if cursor.Before(until) {
+		p.Gaps = append(p.Gaps, Segment{Start: cursor, End: until})
+	}
+
+	return p, nil
+}
+
+// OnCall returns the users who are on call in the given segments
+// between start and end (in order, without duplication).
+func OnCall(segments []Segment, start, end time.Time) []string {
+	var users []string
+	seen := make(map[string]bool)
+	for _, segment := range segments {
+		if !segment.overlaps(start, end) || seen[segment.User] {
+			continue
+		}
+		seen[segment.User] = true
+		users = append(users, segment.User)
+	}
+	return users
+}
+
+// String returns the rendered preview.
+func (p *Preview) String() string {
+	var buf bytes.Buffer
+	p.Render(&buf, PreviewWidth)
+	return buf.String()
+}
+
+// Render writes ASCII timeline of the current and the result schedule
+// with the given width and the summary of the change.
+func (p *Preview) Render(w io.Writer, width int) {
+	marks := make(map[string]rune)
+	var legend []string
+	markOf := func(user string) rune {
+		if user == p.User {
+			return previewYou
+		}
+		if m, ok := marks[user]; ok {
+			return m
+		}
+		m := rune('A' + len(marks)%26)
+		marks[user] = m
+		legend = append(legend, fmt.Sprintf("%c: %s", m, user))
+		return m
+	}
+
+	line := func(segments []Segment) string {
+		step := p.Until.Sub(p.Since) / time.Duration(width)
+		cols := make([]rune, width)
+		for i := range cols {
+			cols[i] = previewNobody
+			t := p.Since.Add(step*time.Duration(i) + step/2)
+			for _, segment := range segments {
+				if !t.Before(segment.Start) && t.Before(segment.End) {
+					cols[i] = markOf(segment.User)
+				}
+			}
+		}
+		return string(cols)
+	}
+
+	current, result := line(p.Current), line(p.Result)
+
+	// Put markers at the start and the end of the override window.
+	col := func(t time.Time) int {
+		c := int(float64(width) * float64(t.Sub(p.Since)) / float64(p.Until.Sub(p.Since)))
+		if c >= width {
+			c = width - 1
+		}
+		return c
+	}
+	axis := []rune(strings.Repeat(" ", width))
+	startCol, endCol := col(p.Start), col(p.End)
+	if endCol > startCol {
+		endCol--
+	}
+	axis[startCol], axis[endCol] = '^', '^'
+
+	fmt.Fprintf(w, "Schedule %q (%s)\n\n", p.ScheduleName, p.ScheduleID)
+	pad := width - 2*len(previewTimeFmt)
+	if pad < 1 {
+		pad = 1
+	}
+	fmt.Fprintf(w, "  %-9s %s%s%s\n", "", p.Since.Format(previewTimeFmt),
+		strings.Repeat(" ", pad), p.Until.Format(previewTimeFmt))
+	fmt.Fprintf(w, "  %-9s %s\n", "current", current)
+	fmt.Fprintf(w, "  %-9s %s\n", "override", result)
+	fmt.Fprintf(w, "  %-9s %s\n", "", string(axis))
+	fmt.Fprintf(w, "  %-9s from %s to %s\n\n", "",
+		p.Start.Format(previewTimeFmt), p.End.Format(previewTimeFmt))
+
+	legend = append(legend, fmt.Sprintf("%c: %s (new)", previewYou, p.User))
+	legend = append(legend, fmt.Sprintf("%c: nobody", previewNobody))
+	fmt.Fprintf(w, "  %s\n\n", strings.Join(legend, "  "))
+
+	onCall := func(users []string) string {
+		if len(users) == 0 {
+			return "nobody"
+		}
+		return strings.Join(users, ", ")
+	}
+	fmt.Fprintf(w, "  before: %s\n", onCall(OnCall(p.Current, p.Start.Add(-time.Second), p.Start)))
+	fmt.Fprintf(w, "  during: %s -> %s\n", onCall(OnCall(p.Current, p.Start, p.End)), p.User)
+	fmt.Fprintf(w, "  after:  %s\n", onCall(OnCall(p.Current, p.End, p.End.Add(time.Second))))
+
+	if len(p.Superseded) > 0 {
+		fmt.Fprintf(w, "  superseded overrides:\n")
+		for _, o := range p.Superseded {
+			fmt.Fprintf(w, "    %s: %s (%s - %s)\n", o.ID, o.User,
+				o.Start.Format(previewTimeFmt), o.End.Format(previewTimeFmt))
+		}
+	}
+
+	if len(p.Gaps) > 0 {
+		fmt.Fprintf(w, "  gaps (nobody is on call):\n")
+		for _, g := range p.Gaps {
+			fmt.Fprintf(w, "    %s - %s\n", g.Start.Format(previewTimeFmt), g.End.Format(previewTimeFmt))
+		}
+	}
+}
+
+// bySegmentStart implements sort.Interface for sorting segments by start time.
+type bySegmentStart []Segment
+
+func (s bySegmentStart) Len() int           { return len(s) }
+func (s bySegmentStart) Less(i, j int) bool { return s[i].Start.Before(s[j].Start) }
+func (s bySegmentStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// parseSegment parses start and end time and the user returned by API.
+func parseSegment(start, end string, user pagerduty.APIObject) (Segment, error) {
+	s, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return Segment{}, errors.Wrapf(err, "failed to parse time %q", start)
+	}
+
+	e, err := time.Parse(time.RFC3339, end)
+	if err != nil {
+		return Segment{}, errors.Wrapf(err, "failed to parse time %q", end)
+	}
+
+	return Segment{Start: s, End: e, User: user.Summary, UserID: user.ID}, nil
+}
+
+// clip clips the segment with the given range. It returns false
+// if the segment is out of the range.
+func clip(segment Segment, since, until time.Time) (Segment, bool) {
+	if !segment.overlaps(since, until) {
+		return Segment{}, false
+	}
+
+	if segment.Start.Before(since) {
+		segment.Start = since
+	}
+
+	if segment.End.After(until) {
+		segment.End = until
+	}
+
+	return segment, true
+}
+
+// userName returns the name of the user which is displayed on preview.
+func userName(user *User) string {
+	if user == nil {
+		return ""
+	}
+
+	if user.Obj != nil && len(user.Obj.Summary) != 0 {
+		return user.Obj.Summary
+	}
+
+	return user.Email
+}
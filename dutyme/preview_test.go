@@ -0,0 +1,105 @@
+package dutyme
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func testTime(t *testing.T, s string) time.Time {
+	tm, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal("Parse failed:", err)
+	}
+	return tm
+}
+
+func TestDutyme_Preview(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := testTime(t, "2017-01-01T10:00:00Z")
+	end := testTime(t, "2017-01-01T14:00:00Z")
+	p, err := d.Preview(testScheduleID1, user, start, end)
+	if err != nil {
+		t.Fatal("Preview failed:", err)
+	}
+
+	if got, want := OnCall(p.Current, start, end), []string{"Alice", "Bob"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("current on-call = %v, want %v", got, want)
+	}
+
+	if got, want := OnCall(p.Result, p.Since, p.Until), []string{"Alice", "Taichi Nakashima", "Bob"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("result on-call = %v, want %v", got, want)
+	}
+
+	if got, want := len(p.Superseded), 1; got != want {
+		t.Fatalf("superseded number = %d, want %d", got, want)
+	}
+
+	if got, want := len(p.Gaps), 0; got != want {
+		t.Fatalf("gaps number = %d, want %d", got, want)
+	}
+
+	out := p.String()
+	for _, want := range []string{"before: Alice", "during: Alice, Bob -> Taichi Nakashima", "after:  Bob", testOverrideID} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expect %q to contain %q", out, want)
+		}
+	}
+}
+
+func TestDutyme_Preview_inProgress(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	d.PD.(*testPDClient).inProgress = []pagerduty.Override{
+		{
+			ID:    "PINPROG",
+			Start: "2017-01-01T09:00:00Z",
+			End:   "2017-01-01T10:30:00Z",
+			User:  pagerduty.APIObject{ID: "PDAVE01", Summary: "Dave"},
+		},
+	}
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := testTime(t, "2017-01-01T10:00:00Z")
+	end := testTime(t, "2017-01-01T14:00:00Z")
+	p, err := d.Preview(testScheduleID1, user, start, end)
+	if err != nil {
+		t.Fatal("Preview failed:", err)
+	}
+
+	var ids []string
+	for _, o := range p.Superseded {
+		ids = append(ids, o.ID)
+	}
+	if got, want := ids, []string{testOverrideID, "PINPROG"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("superseded = %v, want %v", got, want)
+	}
+}
+
+func TestDutyme_Preview_gaps(t *testing.T) {
+	d := testNewDutyme(t, "", "")
+	user, _ := d.PD.GetUser(testEmail)
+
+	start := testTime(t, "2017-01-01T20:00:00Z")
+	end := testTime(t, "2017-01-02T02:00:00Z")
+	p, err := d.Preview(testScheduleID1, user, start, end)
+	if err != nil {
+		t.Fatal("Preview failed:", err)
+	}
+
+	if got, want := len(p.Superseded), 0; got != want {
+		t.Fatalf("superseded number = %d, want %d", got, want)
+	}
+
+	if got, want := len(p.Gaps), 1; got != want {
+		t.Fatalf("gaps number = %d, want %d", got, want)
+	}
+
+	if got, want := p.Gaps[0].Start, end; !got.Equal(want) {
+		t.Fatalf("gap start = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package dutyme
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tcnksm/go-input"
+)
+
+// ReasonPolicy decides which overrides require reason (why user pulls
+// the pager, e.g., for compliance) and how ticket IDs look.
+type ReasonPolicy struct {
+	// Required requires reason for all schedules.
+	Required bool
+
+	// Schedules are IDs of the schedules which require reason.
+	Schedules []string
+
+	// TicketPattern validates ticket IDs if it's set.
+	TicketPattern *regexp.Regexp
+}
+
+// ReasonError is returned when reason of overriding is missing or
+// ticket ID is invalid.
+type ReasonError struct {
+	msg string
+}
+
+func (e *ReasonError) Error() string {
+	return e.msg
+}
+
+// ReasonRequired returns true if overriding the given schedule requires
+// reason.
+func (d *Dutyme) ReasonRequired(scheduleID string) bool {
+	p := d.ReasonPolicy
+	if p == nil {
+		return false
+	}
+
+	if p.Required {
+		return true
+	}
+
+	for _, id := range p.Schedules {
+		if id == scheduleID {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckReason checks the reason (or ticket) is given when any of the
+// given schedules require it and the ticket ID is valid. Either reason
+// or ticket satisfies the requirement.
+func (d *Dutyme) CheckReason(scheduleIDs ...string) error {
+	if len(d.Ticket) != 0 && d.ReasonPolicy != nil && d.ReasonPolicy.TicketPattern != nil {
+		if pattern := d.ReasonPolicy.TicketPattern; !pattern.MatchString(d.Ticket) {
+			return &ReasonError{fmt.Sprintf("invalid ticket %q: it must match %s", d.Ticket, pattern)}
+		}
+	}
+
+	if len(d.Reason) != 0 || len(d.Ticket) != 0 {
+		return nil
+	}
+
+	for _, id := range scheduleIDs {
+		if d.ReasonRequired(id) {
+			return &ReasonError{fmt.Sprintf("reason is required to override schedule %s (-reason or -ticket)", id)}
+		}
+	}
+	return nil
+}
+
+// AskReason asks reason when any of the given schedules require it and
+// neither reason nor ticket is given. It must be used only when user
+// can answer (e.g., stdin is terminal).
+func (d *Dutyme) AskReason(scheduleIDs ...string) error {
+	if len(d.Reason) != 0 || len(d.Ticket) != 0 {
+		return nil
+	}
+
+	required := false
+	for _, id := range scheduleIDs {
+		required = required || d.ReasonRequired(id)
+	}
+
+	if !required {
+		return nil
+	}
+
+	reason, err := d.UI.Ask("Reason for overriding (e.g., deploy v1.2.0)", &input.Options{
+		Required:  true,
+		Loop:      true,
+		HideOrder: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.Reason = strings.TrimSpace(reason)
+	return nil
+}
+
+// WithReason returns the copy of Dutyme which overrides with the given
+// reason and ticket. It's used when Dutyme is shared (e.g., servers).
+func (d *Dutyme) WithReason(reason, ticket string) *Dutyme {
+	copied := *d
+	copied.Reason = reason
+	copied.Ticket = ticket
+	return &copied
+}
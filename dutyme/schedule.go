@@ -0,0 +1,278 @@
+package dutyme
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/finder"
+)
+
+// NoTeam is the team name used for schedules which belong to no team.
+const NoTeam = "No team"
+
+// TeamSchedules is a list of schedules grouped by team.
+type TeamSchedules struct {
+	Team      pagerduty.APIObject
+	Schedules []pagerduty.Schedule
+}
+
+// MySchedules lists schedules which the given user belongs to
+// (the user appears in the schedule users or in a layer users)
+// and groups them by team. The team of a schedule is decided by
+// the teams of the escalation policies which use the schedule.
+// Schedules which belong to no team are grouped at the last.
+func (d *Dutyme) MySchedules(user *User) ([]*TeamSchedules, error) {
+	if user == nil || user.Obj == nil {
+		return nil, errors.New("missing user")
+	}
+
+	schedules, err := d.PD.ListSchedules()
+	if err != nil {
+		return nil, err
+	}
+
+	teams, scheduleTeams, err := d.scheduleTeams(schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*TeamSchedules, len(teams))
+	for _, team := range teams {
+		groups[team.ID] = &TeamSchedules{
+			Team: pagerduty.APIObject{
+				ID:      team.ID,
+				Summary: team.Name,
+			},
+		}
+	}
+	noTeam := &TeamSchedules{
+		Team: pagerduty.APIObject{Summary: NoTeam},
+	}
+
+	for _, schedule := range schedules {
+		if !isMember(&schedule, user.Obj.ID) {
+			continue
+		}
+
+		teamIDs := scheduleTeams[schedule.ID]
+		for _, teamID := range teamIDs {
+			group := groups[teamID]
+			group.Schedules = append(group.Schedules, schedule)
+		}
+
+		if len(teamIDs) == 0 {
+			noTeam.Schedules = append(noTeam.Schedules, schedule)
+		}
+	}
+
+	result := make([]*TeamSchedules, 0, len(teams)+1)
+	for _, team := range teams {
+		if group := groups[team.ID]; len(group.Schedules) > 0 {
+			result = append(result, group)
+		}
+	}
+
+	if len(noTeam.Schedules) > 0 {
+		result = append(result, noTeam)
+	}
+
+	return result, nil
+}
+
+// SelectMySchedule asks user to select a schedule from the schedules
+// which the user belongs to. It returns the name and ID of the schedule.
+func (d *Dutyme) SelectMySchedule(user *User) (string, string, error) {
+	groups, err := d.MySchedules(user)
+	if err != nil {
+		return "", "", err
+	}
+
+	var (
+		items     []finder.Item
+		schedules []pagerduty.Schedule
+	)
+	for _, group := range groups {
+		for _, schedule := range group.Schedules {
+			items = append(items, finder.Item{
+				Label: fmt.Sprintf("[%s] %s", group.Team.Summary, schedule.Name),
+			})
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	if len(items) == 0 {
+		return "", "", errors.Errorf("user %s does not belong to any schedules", user.Email)
+	}
+
+	if len(items) == 1 {
+		return schedules[0].Name, schedules[0].ID, nil
+	}
+	d.describeSchedules(schedules, items)
+
+	query := "Found your schedules. Select one."
+	i, err := d.selectItem(query, items)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to ask schedule from the given list")
+	}
+
+	return schedules[i].Name, schedules[i].ID, nil
+}
+
+// describeSchedules sets timezone, team and current on-call of each
+// schedule to the item detail. Since it requests API for each schedule,
+// it does nothing when finder is not available (detail is not displayed).
+// Failing to get details is not critical, so errors are ignored.
+func (d *Dutyme) describeSchedules(schedules []pagerduty.Schedule, items []finder.Item) {
+	if !d.Finder.Available() {
+		return
+	}
+
+	teamNames := make(map[string]string)
+	teams, scheduleTeams, err := d.scheduleTeams(schedules)
+	if err == nil {
+		for _, team := range teams {
+			teamNames[team.ID] = team.Name
+		}
+	}
+
+	now := time.Now()
+	for i, schedule := range schedules {
+		names := make([]string, 0, len(scheduleTeams[schedule.ID]))
+		for _, teamID := range scheduleTeams[schedule.ID] {
+			names = append(names, teamNames[teamID])
+		}
+
+		team := NoTeam
+		if len(names) > 0 {
+			team = strings.Join(names, ",")
+		}
+
+		onCall := "-"
+		if s, err := d.PD.GetSchedule(schedule.ID, now, now.Add(time.Minute)); err == nil {
+			if entries := s.FinalSchedule.RenderedScheduleEntries; len(entries) > 0 {
+				onCall = entries[0].User.Summary
+			}
+		}
+
+		items[i].Detail = fmt.Sprintf("%s | %s | on-call: %s", schedule.TimeZone, team, onCall)
+	}
+}
+
+// scheduleTeams lists teams and returns them with the team IDs of each
+// given schedule (keyed by schedule ID). The teams of a schedule are the
+// teams of the escalation policies which use the schedule.
+func (d *Dutyme) scheduleTeams(schedules []pagerduty.Schedule) ([]pagerduty.Team, map[string][]string, error) {
+	teams, err := d.PD.ListTeams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policies, err := d.PD.ListEscalationPolicies()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	known := make(map[string]bool, len(teams))
+	for _, team := range teams {
+		known[team.ID] = true
+	}
+
+	policyTeams := make(map[string][]string, len(policies))
+	for _, policy := range policies {
+		for _, team := range policy.Teams {
+			policyTeams[policy.ID] = append(policyTeams[policy.ID], team.ID)
+		}
+	}
+
+	result := make(map[string][]string, len(schedules))
+	for _, schedule := range schedules {
+		found := make(map[string]bool)
+		for _, policy := range schedule.EscalationPolicies {
+			for _, teamID := range policyTeams[policy.ID] {
+				if !known[teamID] || found[teamID] {
+					continue
+				}
+				result[schedule.ID] = append(result[schedule.ID], teamID)
+				found[teamID] = true
+			}
+		}
+	}
+
+	return teams, result, nil
+}
+
+// Schedules returns the schedules of the given IDs (e.g., schedules of
+// preset) with their names.
+func (d *Dutyme) Schedules(ids []string) ([]pagerduty.APIObject, error) {
+	now := time.Now()
+	schedules := make([]pagerduty.APIObject, 0, len(ids))
+	for _, id := range ids {
+		schedule, err := d.PD.GetSchedule(id, now, now)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get schedule %s", id)
+		}
+
+		schedules = append(schedules, pagerduty.APIObject{ID: schedule.ID, Summary: schedule.Name})
+	}
+
+	return schedules, nil
+}
+
+// ConfirmMember checks the given user belongs to the schedule.
+// If not, it warns and asks user to continue. If force is true,
+// it does nothing.
+func (d *Dutyme) ConfirmMember(scheduleID string, user *User, force bool) error {
+	if force {
+		return nil
+	}
+
+	if user == nil || user.Obj == nil {
+		return errors.New("missing user")
+	}
+
+	now := time.Now()
+	schedule, err := d.PD.GetSchedule(scheduleID, now, now)
+	if err != nil {
+		return err
+	}
+
+	if isMember(schedule, user.Obj.ID) {
+		return nil
+	}
+
+	query := fmt.Sprintf("WARNING: you are not a member of schedule %q. Override anyway? [y/N]",
+		schedule.Name)
+	ok, err := d.confirm(query, false)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return &errCancel{}
+	}
+
+	return nil
+}
+
+// isMember returns true if the given user appears in the schedule users
+// or in its layer users.
+func isMember(schedule *pagerduty.Schedule, userID string) bool {
+	for _, user := range schedule.Users {
+		if user.ID == userID {
+			return true
+		}
+	}
+
+	for _, layer := range schedule.ScheduleLayers {
+		for _, ref := range layer.Users {
+			if ref.User.ID == userID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+package dutyme
+
+import (
+	"fmt"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/finder"
+	"github.com/tcnksm/go-input"
+)
+
+const (
+	// TargetTypeSchedule is escalation rule target type which
+	// references schedule.
+	TargetTypeSchedule = "schedule_reference"
+)
+
+// ServiceChain is the resolved chain from a service to the schedules
+// which are on the given escalation level.
+type ServiceChain struct {
+	Service          pagerduty.APIObject
+	EscalationPolicy pagerduty.APIObject
+	Level            int
+
+	Schedules []pagerduty.APIObject
+}
+
+// String returns human readable chain (used for dry-run).
+func (c *ServiceChain) String() string {
+	s := fmt.Sprintf("Service %q (%s)\n", c.Service.Summary, c.Service.ID)
+	s += fmt.Sprintf("  -> Escalation policy %q (%s)\n", c.EscalationPolicy.Summary, c.EscalationPolicy.ID)
+	s += fmt.Sprintf("  -> Level %d\n", c.Level)
+	for _, schedule := range c.Schedules {
+		s += fmt.Sprintf("    -> Schedule %q (%s)\n", schedule.Summary, schedule.ID)
+	}
+	return s
+}
+
+// GetService finds a service by the given query. If query is empty,
+// it asks the service name. If API returns multiple services, it asks
+// user to select one.
+func (d *Dutyme) GetService(query string) (*pagerduty.Service, error) {
+	if len(query) == 0 {
+		var err error
+		q := "Input PagerDuty service name which you want to take on-call"
+		query, err = d.UI.Ask(q, &input.Options{
+			Required:  true,
+			Loop:      true,
+			HideOrder: true,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to ask service name")
+		}
+	}
+
+	services, err := d.PD.GetServices(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(services) == 1 {
+		return &services[0], nil
+	}
+
+	items := make([]finder.Item, 0, len(services))
+	for _, service := range services {
+		items = append(items, finder.Item{
+			Label:  service.Name,
+			Detail: service.EscalationPolicy.Summary,
+		})
+	}
+
+	q := "Found multiple services. Select one."
+	i, err := d.selectItem(q, items)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to ask service from the given list")
+	}
+
+	return &services[i], nil
+}
+
+// ResolveService resolves the given service to its escalation policy
+// and returns the schedules on the given escalation level (1-origin).
+func (d *Dutyme) ResolveService(serviceID string, level int) (*ServiceChain, error) {
+	if level < 1 {
+		return nil, errors.Errorf("invalid escalation level %d: must be positive", level)
+	}
+
+	service, err := d.PD.GetService(serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(service.EscalationPolicy.ID) == 0 {
+		return nil, errors.Errorf("service %q has no escalation policy", service.Name)
+	}
+
+	policy, err := d.PD.GetEscalationPolicy(service.EscalationPolicy.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := policy.EscalationRules
+	if level > len(rules) {
+		return nil, errors.Errorf("escalation policy %q has only %d level(s)",
+			policy.Name, len(rules))
+	}
+
+	chain := &ServiceChain{
+		Service: pagerduty.APIObject{
+			ID:      service.ID,
+			Summary: service.Name,
+		},
+		EscalationPolicy: pagerduty.APIObject{
+			ID:      policy.ID,
+			Summary: policy.Name,
+		},
+		Level: level,
+	}
+
+	for _, target := range rules[level-1].Targets {
+		if target.Type == TargetTypeSchedule {
+			chain.Schedules = append(chain.Schedules, target)
+		}
+	}
+
+	if len(chain.Schedules) == 0 {
+		return nil, errors.Errorf("no schedules are found on level %d of escalation policy %q",
+			level, policy.Name)
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,163 @@
+package dutyme
+
+import (
+	"sort"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/journal"
+)
+
+// OverrideSegment is a period which is overridden.
+type OverrideSegment struct {
+	Segment
+
+	ID string
+}
+
+// Timeline is the final schedule and the overrides in a time range.
+type Timeline struct {
+	ScheduleName string
+	ScheduleID   string
+
+	Since time.Time
+	Until time.Time
+
+	Entries   []Segment
+	Overrides []OverrideSegment
+}
+
+// Mine returns the overrides which are assigned to the given user.
+func (t *Timeline) Mine(user *User) []OverrideSegment {
+	if user == nil || user.Obj == nil {
+		return nil
+	}
+
+	var overrides []OverrideSegment
+	for _, o := range t.Overrides {
+		if o.UserID == user.Obj.ID {
+			overrides = append(overrides, o)
+		}
+	}
+	return overrides
+}
+
+// Timeline gets the final schedule and the overrides between since and until.
+func (d *Dutyme) Timeline(scheduleID string, since, until time.Time) (*Timeline, error) {
+	schedule, err := d.PD.GetSchedule(scheduleID, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	// Overrides in progress are not editable but they are on the timeline.
+	overrides, err := d.PD.GetAllOverrides(scheduleID, since, until)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+
+	t := &Timeline{
+		ScheduleName: schedule.Name,
+		ScheduleID:   scheduleID,
+		Since:        since,
+		Until:        until,
+	}
+
+	for _, entry := range schedule.FinalSchedule.RenderedScheduleEntries {
+		segment, err := parseSegment(entry.Start, entry.End, entry.User)
+		if err != nil {
+			return nil, err
+		}
+
+		if segment, ok := clip(segment, since, until); ok {
+			t.Entries = append(t.Entries, segment)
+		}
+	}
+	sort.Sort(bySegmentStart(t.Entries))
+
+	for _, override := range overrides {
+		segment, err := parseSegment(override.Start, override.End, override.User)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Overrides = append(t.Overrides, OverrideSegment{
+			Segment: segment,
+			ID:      override.ID,
+		})
+	}
+
+	return t, nil
+}
+
+// ReplaceOverride replaces the given override with new one which has
+// the given start and end time (API doesn't support updating override).
+// New override is created before deleting the old one so that there is
+// no moment when the schedule is not overridden.
+func (d *Dutyme) ReplaceOverride(scheduleID, overrideID string, user *User, start, end time.Time) (*pagerduty.Override, error) {
+	return d.replaceOverride(scheduleID, overrideID, user, d.since(overrideID, start), start, end)
+}
+
+// since returns when the user went on call by the given override. The
+// override may be the extended one, then it's when the first override
+// started. If it's not recorded on journal, it returns fallback.
+func (d *Dutyme) since(overrideID string, fallback time.Time) time.Time {
+	prev := d.recorded(overrideID)
+	if prev == nil {
+		return fallback
+	}
+
+	if !prev.Since.IsZero() {
+		return prev.Since
+	}
+	return prev.Start
+}
+
+// replaceOverride replaces the override which keeps the user on call
+// since the given time. The total duration from since is capped by
+// MaxDuration of policy.
+func (d *Dutyme) replaceOverride(scheduleID, overrideID string, user *User, since, start, end time.Time) (*pagerduty.Override, error) {
+	if !start.Before(end) {
+		return nil, errors.New("end time must be after start time")
+	}
+
+	// Protected schedule is already allowed when it's overridden first.
+	if d.Policy != nil {
+		if err := d.Policy.checkTime(start, end); err != nil {
+			return nil, err
+		}
+
+		if err := d.Policy.checkSpan(since, end); err != nil {
+			return nil, err
+		}
+	}
+
+	override, err := d.PD.Override(scheduleID, user, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.PD.DeleteOverride(scheduleID, overrideID); err != nil {
+		d.record(journal.Event{
+			Action:     journal.ActionStart,
+			OverrideID: override.ID,
+			ScheduleID: scheduleID,
+			Start:      start,
+			End:        end,
+			Since:      since,
+		}, user)
+		return override, errors.Wrapf(err, "created new override %s but failed to delete old one", override.ID)
+	}
+
+	d.record(journal.Event{
+		Action:     journal.ActionExtend,
+		OverrideID: override.ID,
+		PreviousID: overrideID,
+		ScheduleID: scheduleID,
+		Start:      start,
+		End:        end,
+		Since:      since,
+	}, user)
+
+	return override, nil
+}
@@ -0,0 +1,61 @@
+package dutyme
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stale is the saved setting which doesn't match PagerDuty anymore.
+type Stale struct {
+	// Key is the configuration key of the setting.
+	Key     string
+	Message string
+}
+
+func (s Stale) String() string {
+	return fmt.Sprintf("%s: %s", s.Key, s.Message)
+}
+
+// CheckStale resolves the saved user and schedule against PagerDuty
+// and returns the stale ones (e.g., deleted schedule or renamed user).
+// Failing to request PagerDuty API (other than not found) is returned
+// as error.
+func (d *Dutyme) CheckStale(user *User, scheduleID, scheduleName string) ([]Stale, error) {
+	var stale []Stale
+
+	if user != nil {
+		current, err := d.PD.GetUser(user.Email)
+		switch {
+		case isNotFound(err):
+			stale = append(stale, Stale{"user.Email",
+				fmt.Sprintf("user %s is not found (deleted or email changed)", user.Email)})
+		case err != nil:
+			return nil, err
+		case user.Obj == nil:
+			stale = append(stale, Stale{"user", "user is not resolved (run `dutyme start -update`)"})
+		case current.Obj.ID != user.Obj.ID:
+			stale = append(stale, Stale{"user.Obj.id",
+				fmt.Sprintf("%s belongs to user %s now (saved %s)", user.Email, current.Obj.ID, user.Obj.ID)})
+		case current.Obj.Summary != user.Obj.Summary:
+			stale = append(stale, Stale{"user.Obj.summary",
+				fmt.Sprintf("user is renamed from %q to %q", user.Obj.Summary, current.Obj.Summary)})
+		}
+	}
+
+	if len(scheduleID) != 0 {
+		now := time.Now()
+		schedule, err := d.PD.GetSchedule(scheduleID, now, now)
+		switch {
+		case isNotFound(err):
+			stale = append(stale, Stale{"schedule_id",
+				fmt.Sprintf("schedule %s is not found (deleted?)", scheduleID)})
+		case err != nil:
+			return nil, err
+		case len(scheduleName) != 0 && schedule.Name != scheduleName:
+			stale = append(stale, Stale{"schedule_name",
+				fmt.Sprintf("schedule is renamed from %q to %q", scheduleName, schedule.Name)})
+		}
+	}
+
+	return stale, nil
+}
@@ -0,0 +1,275 @@
+// Package finder provides incremental fuzzy finder on terminal.
+//
+// It reads key input in raw mode and filters the given items while
+// user types query. Items can be selected by arrow keys (or Ctrl-P
+// and Ctrl-N) and Enter. When the terminal doesn't support raw mode,
+// Available returns false and caller should fall back to other way.
+package finder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tcnksm/dutyme/term"
+)
+
+// DefaultMaxLines is default number of items displayed at once.
+const DefaultMaxLines = 10
+
+// ErrInterrupted is returned when user cancels selection by Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// ErrNoMatch is returned when user hits Enter while no item matches.
+var ErrNoMatch = errors.New("no item matches the query")
+
+// Item is a selectable item. Label is used for matching and Detail is
+// displayed next to Label (e.g., schedule timezone or team).
+type Item struct {
+	Label  string
+	Detail string
+}
+
+// Finder is incremental fuzzy finder.
+type Finder struct {
+	// In is used for reading key input. If it's terminal,
+	// it's put into raw mode while finding.
+	In io.Reader
+
+	// Out is used for rendering query and items.
+	Out io.Writer
+
+	// MaxLines is max number of items displayed at once.
+	// If it's zero, DefaultMaxLines is used.
+	MaxLines int
+}
+
+// Available returns true if finder can be used, i.e., In is the terminal
+// which supports raw mode.
+func (f *Finder) Available() bool {
+	if f == nil {
+		return false
+	}
+
+	file, ok := f.In.(*os.File)
+	if !ok {
+		return false
+	}
+
+	return term.IsTerminal(file)
+}
+
+// Find shows the given prompt and items and asks user to select
+// one of them. It returns the index of the selected item.
+func (f *Finder) Find(prompt string, items []Item) (int, error) {
+	if len(items) == 0 {
+		return -1, errors.New("no items to select")
+	}
+
+	if file, ok := f.In.(*os.File); ok && term.IsTerminal(file) {
+		restore, err := term.MakeRaw(file)
+		if err != nil {
+			return -1, err
+		}
+		defer restore()
+	}
+
+	s := &state{
+		prompt:   prompt,
+		items:    items,
+		maxLines: f.MaxLines,
+	}
+	if s.maxLines <= 0 {
+		s.maxLines = DefaultMaxLines
+	}
+	s.filter()
+
+	rd := bufio.NewReader(f.In)
+	for {
+		lines := s.render(f.Out)
+
+		r, err := term.ReadKey(rd)
+		if err != nil {
+			clear(f.Out, lines)
+			if err == io.EOF {
+				return -1, ErrInterrupted
+			}
+			return -1, err
+		}
+
+		switch r {
+		case term.KeyCtrlC:
+			clear(f.Out, lines)
+			return -1, ErrInterrupted
+		case term.KeyEnter, term.KeyNewline:
+			clear(f.Out, lines)
+			if len(s.matches) == 0 {
+				return -1, ErrNoMatch
+			}
+			return s.matches[s.cursor], nil
+		case term.KeyCtrlP, term.KeyUp:
+			s.up()
+		case term.KeyCtrlN, term.KeyDown:
+			s.down()
+		case term.KeyCtrlU:
+			s.query = ""
+			s.filter()
+		case term.KeyBackspace, term.KeyDelete:
+			if len(s.query) > 0 {
+				_, size := utf8.DecodeLastRuneInString(s.query)
+				s.query = s.query[:len(s.query)-size]
+				s.filter()
+			}
+		default:
+			if unicode.IsPrint(r) {
+				s.query += string(r)
+				s.filter()
+			}
+		}
+
+		clear(f.Out, lines)
+	}
+}
+
+// state is the state of a finding session.
+type state struct {
+	prompt   string
+	query    string
+	items    []Item
+	maxLines int
+
+	// matches is the indexes of items which match query
+	// and cursor is the position on matches.
+	matches []int
+	cursor  int
+}
+
+type match struct {
+	index int
+	score int
+}
+
+// byScore implements sort.Interface for sorting matches by score.
+type byScore []match
+
+func (m byScore) Len() int           { return len(m) }
+func (m byScore) Less(i, j int) bool { return m[i].score < m[j].score }
+func (m byScore) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+
+func (s *state) filter() {
+	matches := make([]match, 0, len(s.items))
+	for i, item := range s.items {
+		if score, ok := Match(s.query, item.Label); ok {
+			matches = append(matches, match{index: i, score: score})
+		}
+	}
+	sort.Stable(byScore(matches))
+
+	s.matches = s.matches[:0]
+	for _, m := range matches {
+		s.matches = append(s.matches, m.index)
+	}
+	s.cursor = 0
+}
+
+func (s *state) up() {
+	if s.cursor > 0 {
+		s.cursor--
+	}
+}
+
+func (s *state) down() {
+	if s.cursor < len(s.matches)-1 {
+		s.cursor++
+	}
+}
+
+// render writes prompt line and matched items, and returns the number
+// of lines written below prompt line. Lines are terminated by CRLF since
+// terminal is in raw mode.
+func (s *state) render(w io.Writer) int {
+	fmt.Fprintf(w, "%s\r\n", s.prompt)
+
+	// Scroll window so that cursor is always displayed.
+	offset := 0
+	if s.cursor >= s.maxLines {
+		offset = s.cursor - s.maxLines + 1
+	}
+
+	width := 0
+	for _, i := range s.matches {
+		if n := utf8.RuneCountInString(s.items[i].Label); n > width {
+			width = n
+		}
+	}
+
+	lines := 1
+	for pos := offset; pos < len(s.matches) && pos < offset+s.maxLines; pos++ {
+		item := s.items[s.matches[pos]]
+
+		marker := "  "
+		if pos == s.cursor {
+			marker = "> "
+		}
+
+		label := item.Label
+		if len(item.Detail) != 0 {
+			label += strings.Repeat(" ", width-utf8.RuneCountInString(label))
+			label += "  " + item.Detail
+		}
+		fmt.Fprintf(w, "%s%s\r\n", marker, label)
+		lines++
+	}
+
+	fmt.Fprintf(w, "  %d/%d\r\n", len(s.matches), len(s.items))
+	lines++
+
+	fmt.Fprintf(w, "Query> %s", s.query)
+	return lines
+}
+
+// clear moves cursor back to the prompt line and clears screen below.
+func clear(w io.Writer, lines int) {
+	fmt.Fprintf(w, "\r\x1b[%dA\x1b[J", lines)
+}
+
+// Match reports whether all characters in query appear in s in order
+// (case-insensitive). Score is smaller when the matched characters are
+// closer to each other and to the beginning of s.
+func Match(query, s string) (int, bool) {
+	if len(query) == 0 {
+		return 0, true
+	}
+
+	q := []rune(strings.ToLower(query))
+	target := []rune(strings.ToLower(s))
+
+	qi, first, last := 0, -1, -1
+	for i, r := range target {
+		if r != q[qi] {
+			continue
+		}
+
+		if first < 0 {
+			first = i
+		}
+		last = i
+
+		qi++
+		if qi == len(q) {
+			break
+		}
+	}
+
+	if qi != len(q) {
+		return 0, false
+	}
+
+	return (last - first) + first, true
+}
@@ -0,0 +1,114 @@
+package finder
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		query string
+		s     string
+		ok    bool
+	}{
+		{"", "Dutyme primary", true},
+		{"dp", "Dutyme primary", true},
+		{"DUTY", "Dutyme primary", true},
+		{"prim", "Dutyme primary", true},
+		{"pd", "Dutyme primary", false},
+		{"secondary", "Dutyme primary", false},
+	}
+
+	for _, tc := range cases {
+		if _, ok := Match(tc.query, tc.s); ok != tc.ok {
+			t.Fatalf("Match(%q, %q) = %v, want %v", tc.query, tc.s, ok, tc.ok)
+		}
+	}
+}
+
+func TestMatch_score(t *testing.T) {
+	close, _ := Match("prim", "Dutyme primary")
+	far, _ := Match("prim", "Dutyme payments rim")
+	if close >= far {
+		t.Fatalf("expect score %d to be less than %d", close, far)
+	}
+}
+
+func TestFinder_Find(t *testing.T) {
+	items := []Item{
+		{Label: "Dutyme primary", Detail: "Asia/Tokyo"},
+		{Label: "Dutyme secondary", Detail: "Asia/Tokyo"},
+		{Label: "Payments primary", Detail: "UTC"},
+	}
+
+	cases := []struct {
+		input string
+		want  int
+	}{
+		{"\r", 0},
+		{"\x1b[B\r", 1},
+		{"\x1b[B\x1b[B\x1b[B\x1b[A\r", 1},
+		{"\x0e\x0e\x10\r", 1},
+		{"pay\r", 2},
+		{"second\r", 1},
+		{"secx\x7f\r", 1},
+		{"zzz\x15pay\r", 2},
+	}
+
+	for _, tc := range cases {
+		f := &Finder{
+			In:  bytes.NewBufferString(tc.input),
+			Out: ioutil.Discard,
+		}
+
+		got, err := f.Find("Select one.", items)
+		if err != nil {
+			t.Fatalf("Find(%q) failed: %s", tc.input, err)
+		}
+
+		if got != tc.want {
+			t.Fatalf("Find(%q) = %d, want %d", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestFinder_Find_error(t *testing.T) {
+	items := []Item{
+		{Label: "Dutyme primary"},
+	}
+
+	cases := []struct {
+		input string
+		want  error
+	}{
+		{"\x03", ErrInterrupted},
+		{"", ErrInterrupted},
+		{"zzz\r", ErrNoMatch},
+	}
+
+	for _, tc := range cases {
+		f := &Finder{
+			In:  bytes.NewBufferString(tc.input),
+			Out: ioutil.Discard,
+		}
+
+		if _, err := f.Find("Select one.", items); err != tc.want {
+			t.Fatalf("Find(%q) error = %v, want %v", tc.input, err, tc.want)
+		}
+	}
+}
+
+func TestFinder_Available(t *testing.T) {
+	var f *Finder
+	if f.Available() {
+		t.Fatal("nil finder must not be available")
+	}
+
+	f = &Finder{
+		In: bytes.NewBufferString(""),
+	}
+	if f.Available() {
+		t.Fatal("finder reading from buffer must not be available")
+	}
+}
@@ -0,0 +1,120 @@
+package githook
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultWorking is the default duration of the override taken on push.
+const DefaultWorking = 1 * time.Hour
+
+// Git config keys (in repository local config) of the hook.
+const (
+	keyBranches = "dutyme.branches"
+	keySchedule = "dutyme.schedule"
+	keyWorking  = "dutyme.working"
+)
+
+// Config is the configuration of the hook for the repository. It's
+// stored in the repository local git config (dutyme.* keys).
+type Config struct {
+	// Branches is the patterns of deploy branches (e.g., release/*).
+	Branches []string
+
+	// ScheduleID is the schedule to override. If empty, the schedule
+	// saved in dutyme configuration file is used.
+	ScheduleID string
+
+	// Working is the duration of the override.
+	Working time.Duration
+}
+
+// HooksDir returns the hooks directory of the git repository which
+// the given directory belongs to (core.hooksPath is respected).
+func HooksDir(repo string) (string, error) {
+	out, err := git(repo, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", errors.Wrap(err, "not a git repository")
+	}
+
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(repo, out)
+	}
+	return out, nil
+}
+
+// ReadConfig reads the hook configuration of the repository.
+func ReadConfig(repo string) (*Config, error) {
+	c := &Config{Working: DefaultWorking}
+
+	if v, _ := git(repo, "config", "--local", "--get", keyBranches); len(v) != 0 {
+		for _, b := range strings.Split(v, ",") {
+			if b = strings.TrimSpace(b); len(b) != 0 {
+				c.Branches = append(c.Branches, b)
+			}
+		}
+	}
+
+	c.ScheduleID, _ = git(repo, "config", "--local", "--get", keySchedule)
+
+	if v, _ := git(repo, "config", "--local", "--get", keyWorking); len(v) != 0 {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", keyWorking)
+		}
+		c.Working = d
+	}
+
+	return c, nil
+}
+
+// WriteConfig writes the hook configuration to the repository.
+func WriteConfig(repo string, c *Config) error {
+	values := [][2]string{
+		{keyBranches, strings.Join(c.Branches, ",")},
+		{keySchedule, c.ScheduleID},
+		{keyWorking, c.Working.String()},
+	}
+
+	for _, kv := range values {
+		if len(kv[1]) == 0 {
+			git(repo, "config", "--local", "--unset", kv[0])
+			continue
+		}
+
+		if _, err := git(repo, "config", "--local", kv[0], kv[1]); err != nil {
+			return errors.Wrapf(err, "failed to set %s", kv[0])
+		}
+	}
+
+	return nil
+}
+
+// RemoveConfig removes the hook configuration from the repository.
+// Keys which are not set are ignored.
+func RemoveConfig(repo string) {
+	for _, key := range []string{keyBranches, keySchedule, keyWorking} {
+		git(repo, "config", "--local", "--unset", key)
+	}
+}
+
+// git executes git command in the given directory and returns its
+// trimmed output.
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "git %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,200 @@
+// Package githook installs git pre-push hook which takes on-call by
+// dutyme when pushing to deploy branches (e.g., release/*).
+package githook
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// HookName is the name of git hook which dutyme installs.
+	HookName = "pre-push"
+
+	// marker is written in the hook installed by dutyme to
+	// distinguish it from user's own hook.
+	marker = "# Installed by dutyme git-hook."
+
+	// backupSuffix is the suffix of user's own hook which is moved
+	// aside on install. It's executed by dutyme hook and restored
+	// on uninstall.
+	backupSuffix = ".dutyme-orig"
+)
+
+// ErrNotInstalled is returned when uninstalling the hook which is
+// not installed by dutyme.
+var ErrNotInstalled = errors.New("pre-push hook is not installed by dutyme")
+
+// script is the pre-push hook. pre-push hook receives pushed refs on
+// stdin, so they are saved and passed to both dutyme and the original
+// hook. If dutyme fails, push is aborted (use `git push --no-verify`
+// to push without taking on-call).
+const script = `#!/bin/sh
+%s Remove it by ` + "`dutyme git-hook uninstall`" + `.
+# It takes on-call by dutyme when pushing to the deploy branches
+# (see ` + "`git config --get-regexp '^dutyme\\.'`" + `).
+refs=$(cat)
+
+printf '%%s\n' "$refs" | %s git-hook run "$@" || exit $?
+
+if [ -x "$0%s" ]; then
+	printf '%%s\n' "$refs" | "$0%s" "$@"
+fi
+`
+
+// Script returns the content of the hook which executes the given
+// dutyme command.
+func Script(command string) string {
+	return fmt.Sprintf(script, marker, shellQuote(command), backupSuffix, backupSuffix)
+}
+
+// Installed returns true if the hook installed by dutyme is in the
+// given hooks directory.
+func Installed(dir string) bool {
+	buf, err := ioutil.ReadFile(filepath.Join(dir, HookName))
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(buf, []byte(marker))
+}
+
+// Install writes the hook to the given hooks directory. If there is
+// user's own hook, it's moved aside and executed by dutyme hook. If
+// dutyme hook is already installed, it's updated.
+func Install(dir, command string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create hooks directory")
+	}
+
+	hookPath := filepath.Join(dir, HookName)
+	backupPath := hookPath + backupSuffix
+
+	if _, err := os.Stat(hookPath); err == nil && !Installed(dir) {
+		if _, err := os.Stat(backupPath); err == nil {
+			return errors.Errorf("both %s and %s exist, don't know which one to keep", hookPath, backupPath)
+		}
+
+		if err := os.Rename(hookPath, backupPath); err != nil {
+			return errors.Wrap(err, "failed to move existing hook aside")
+		}
+	}
+
+	if err := ioutil.WriteFile(hookPath, []byte(Script(command)), 0755); err != nil {
+		return errors.Wrap(err, "failed to write hook")
+	}
+
+	// WriteFile doesn't change the permission of the existing file.
+	if err := os.Chmod(hookPath, 0755); err != nil {
+		return errors.Wrap(err, "failed to make hook executable")
+	}
+
+	return nil
+}
+
+// Uninstall removes dutyme hook from the given hooks directory and
+// restores user's own hook if it was moved aside on install.
+func Uninstall(dir string) error {
+	if !Installed(dir) {
+		return ErrNotInstalled
+	}
+
+	hookPath := filepath.Join(dir, HookName)
+	if err := os.Remove(hookPath); err != nil {
+		return errors.Wrap(err, "failed to remove hook")
+	}
+
+	backupPath := hookPath + backupSuffix
+	if _, err := os.Stat(backupPath); err == nil {
+		if err := os.Rename(backupPath, hookPath); err != nil {
+			return errors.Wrap(err, "failed to restore original hook")
+		}
+	}
+
+	return nil
+}
+
+// Ref is the ref which is pushed. pre-push hook receives them
+// on stdin line by line.
+type Ref struct {
+	LocalRef  string
+	LocalSHA  string
+	RemoteRef string
+	RemoteSHA string
+}
+
+// Deleted returns true if the push deletes the remote ref.
+func (r Ref) Deleted() bool {
+	return strings.Trim(r.LocalSHA, "0") == ""
+}
+
+// Branch returns the name of the remote branch. If the remote ref is
+// not a branch, it returns empty string.
+func (r Ref) Branch() string {
+	if !strings.HasPrefix(r.RemoteRef, "refs/heads/") {
+		return ""
+	}
+	return strings.TrimPrefix(r.RemoteRef, "refs/heads/")
+}
+
+// ParseRefs parses the pushed refs given to pre-push hook.
+func ParseRefs(rd io.Reader) ([]Ref, error) {
+	var refs []Ref
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 4 {
+			return nil, errors.Errorf("invalid pushed ref: %q", scanner.Text())
+		}
+
+		refs = append(refs, Ref{
+			LocalRef:  fields[0],
+			LocalSHA:  fields[1],
+			RemoteRef: fields[2],
+			RemoteSHA: fields[3],
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read pushed refs")
+	}
+
+	return refs, nil
+}
+
+// Match returns the first pushed branch which matches one of the given
+// patterns (path.Match syntax like "release/*"). Deleting branch never
+// matches.
+func Match(patterns []string, refs []Ref) (string, bool) {
+	for _, ref := range refs {
+		branch := ref.Branch()
+		if ref.Deleted() || len(branch) == 0 {
+			continue
+		}
+
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, branch); ok {
+				return branch, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// shellQuote quotes the given string for sh.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
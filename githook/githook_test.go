@@ -0,0 +1,186 @@
+package githook
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "githook")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestInstall(t *testing.T) {
+	dir, cleanup := testTempDir(t)
+	defer cleanup()
+
+	// User's own hook must be kept.
+	own := "#!/bin/sh\necho own\n"
+	hookPath := filepath.Join(dir, HookName)
+	if err := ioutil.WriteFile(hookPath, []byte(own), 0755); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+
+	if err := Install(dir, "/usr/local/bin/dutyme"); err != nil {
+		t.Fatal("Install failed:", err)
+	}
+
+	if !Installed(dir) {
+		t.Fatal("expects hook to be installed")
+	}
+
+	buf, err := ioutil.ReadFile(hookPath)
+	if err != nil {
+		t.Fatal("ReadFile failed:", err)
+	}
+
+	if !strings.Contains(string(buf), "'/usr/local/bin/dutyme' git-hook run") {
+		t.Fatalf("hook doesn't execute dutyme:\n%s", buf)
+	}
+
+	// Installing again updates the hook (original is still kept).
+	if err := Install(dir, "dutyme"); err != nil {
+		t.Fatal("Install failed:", err)
+	}
+
+	if err := Uninstall(dir); err != nil {
+		t.Fatal("Uninstall failed:", err)
+	}
+
+	buf, err = ioutil.ReadFile(hookPath)
+	if err != nil {
+		t.Fatal("ReadFile failed:", err)
+	}
+
+	if got, want := string(buf), own; got != want {
+		t.Fatalf("restored hook = %q, want %q", got, want)
+	}
+
+	if err := Uninstall(dir); err != ErrNotInstalled {
+		t.Fatalf("Uninstall error = %v, want %v", err, ErrNotInstalled)
+	}
+}
+
+func TestParseRefs(t *testing.T) {
+	input := `refs/heads/release/v1 1111111111111111111111111111111111111111 refs/heads/release/v1 0000000000000000000000000000000000000000
+refs/heads/feature 2222222222222222222222222222222222222222 refs/heads/feature 3333333333333333333333333333333333333333
+
+`
+	refs, err := ParseRefs(strings.NewReader(input))
+	if err != nil {
+		t.Fatal("ParseRefs failed:", err)
+	}
+
+	if got, want := len(refs), 2; got != want {
+		t.Fatalf("refs number = %d, want %d", got, want)
+	}
+
+	if got, want := refs[0].Branch(), "release/v1"; got != want {
+		t.Fatalf("branch = %q, want %q", got, want)
+	}
+
+	if _, err := ParseRefs(strings.NewReader("broken line\n")); err == nil {
+		t.Fatal("expects to fail with invalid line")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	sha := "1111111111111111111111111111111111111111"
+	zero := "0000000000000000000000000000000000000000"
+
+	cases := []struct {
+		refs   []Ref
+		branch string
+		ok     bool
+	}{
+		{
+			refs:   []Ref{{LocalSHA: sha, RemoteRef: "refs/heads/release/v1"}},
+			branch: "release/v1",
+			ok:     true,
+		},
+		{
+			refs: []Ref{{LocalSHA: sha, RemoteRef: "refs/heads/feature/x"}},
+		},
+		{
+			// Deleting deploy branch doesn't deploy.
+			refs: []Ref{{LocalSHA: zero, RemoteRef: "refs/heads/release/v1"}},
+		},
+		{
+			refs: []Ref{{LocalSHA: sha, RemoteRef: "refs/tags/release/v1"}},
+		},
+		{
+			refs: []Ref{
+				{LocalSHA: sha, RemoteRef: "refs/heads/feature"},
+				{LocalSHA: sha, RemoteRef: "refs/heads/main"},
+			},
+			branch: "main",
+			ok:     true,
+		},
+	}
+
+	for i, tc := range cases {
+		branch, ok := Match([]string{"release/*", "main"}, tc.refs)
+		if branch != tc.branch || ok != tc.ok {
+			t.Fatalf("#%d Match = %q, %v, want %q, %v", i, branch, ok, tc.branch, tc.ok)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo, cleanup := testTempDir(t)
+	defer cleanup()
+
+	if _, err := git(repo, "init"); err != nil {
+		t.Fatal("git init failed:", err)
+	}
+
+	dir, err := HooksDir(repo)
+	if err != nil {
+		t.Fatal("HooksDir failed:", err)
+	}
+
+	if got, want := filepath.Base(dir), "hooks"; got != want {
+		t.Fatalf("hooks directory = %q, want %q", got, want)
+	}
+
+	want := &Config{
+		Branches:   []string{"release/*", "main"},
+		ScheduleID: "PI7DH85",
+		Working:    2 * time.Hour,
+	}
+	if err := WriteConfig(repo, want); err != nil {
+		t.Fatal("WriteConfig failed:", err)
+	}
+
+	got, err := ReadConfig(repo)
+	if err != nil {
+		t.Fatal("ReadConfig failed:", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadConfig = %#v, want %#v", got, want)
+	}
+
+	RemoveConfig(repo)
+	got, err = ReadConfig(repo)
+	if err != nil {
+		t.Fatal("ReadConfig failed:", err)
+	}
+
+	if len(got.Branches) != 0 || got.Working != DefaultWorking {
+		t.Fatalf("ReadConfig after remove = %#v", got)
+	}
+}
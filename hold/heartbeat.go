@@ -0,0 +1,66 @@
+package hold
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tcnksm/dutyme/state"
+)
+
+// DefaultStale is default duration after which heartbeat file is
+// regarded as stale.
+const DefaultStale = 5 * time.Minute
+
+// Heartbeat tells whether holding should continue.
+type Heartbeat interface {
+	Alive() (bool, error)
+	String() string
+}
+
+// ProcessHeartbeat is alive while the process of PID exists.
+type ProcessHeartbeat struct {
+	PID int
+}
+
+// Alive returns true if the process exists.
+func (p *ProcessHeartbeat) Alive() (bool, error) {
+	return state.ProcessExists(p.PID)
+}
+
+func (p *ProcessHeartbeat) String() string {
+	return fmt.Sprintf("process %d", p.PID)
+}
+
+// FileHeartbeat is alive while the file keeps being touched
+// (its modification time is within Stale).
+type FileHeartbeat struct {
+	Path string
+
+	// Stale is the duration after which the file is regarded as
+	// stale. If it's zero, DefaultStale is used.
+	Stale time.Duration
+}
+
+// Alive returns true if the file is modified recently. If the file
+// doesn't exist, heartbeat is lost.
+func (f *FileHeartbeat) Alive() (bool, error) {
+	info, err := os.Stat(f.Path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	stale := f.Stale
+	if stale <= 0 {
+		stale = DefaultStale
+	}
+
+	return time.Since(info.ModTime()) <= stale, nil
+}
+
+func (f *FileHeartbeat) String() string {
+	return fmt.Sprintf("file %s", f.Path)
+}
@@ -0,0 +1,286 @@
+// Package hold keeps user on call by rolling short overrides while a
+// heartbeat continues (dead-man's switch).
+//
+// Holder creates a short override (segment) and renews it ahead of its
+// end as long as the heartbeat is alive. When the heartbeat is lost (or
+// the holding process itself dies), it stops renewing and on-call goes
+// back to the rotation when the last segment ends.
+package hold
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/state"
+)
+
+const (
+	// DefaultSegment is default duration of one override segment.
+	DefaultSegment = 15 * time.Minute
+
+	// DefaultAhead is default duration before the segment ends
+	// when it's renewed.
+	DefaultAhead = 5 * time.Minute
+
+	// DefaultInterval is default interval of checking heartbeat.
+	DefaultInterval = 30 * time.Second
+)
+
+// ErrHeartbeatLost is returned by Run when heartbeat is lost.
+var ErrHeartbeatLost = errors.New("heartbeat is lost")
+
+// Holder holds the schedule by rolling overrides.
+type Holder struct {
+	Dutyme     *dutyme.Dutyme
+	User       *dutyme.User
+	ScheduleID string
+
+	// ScheduleName is only used for describing the session.
+	ScheduleName string
+
+	// Segment is the duration of one override segment.
+	Segment time.Duration
+
+	// Ahead is how long before the segment ends it's renewed.
+	// It must be shorter than Segment.
+	Ahead time.Duration
+
+	// Interval is the interval of checking heartbeat.
+	Interval time.Duration
+
+	// Heartbeat decides to continue holding. If it's nil, holding
+	// continues while the holding process itself is alive.
+	Heartbeat Heartbeat
+
+	// Log is used for logging renewals and errors.
+	Log io.Writer
+
+	// Now returns current time. It's replaced in test.
+	Now func() time.Time
+
+	// State persists the held override as the session of this process,
+	// so the override can be cleaned up when this process is killed.
+	// If it's nil, the session is not persisted.
+	State *state.State
+
+	// current is the override which is currently held.
+	current *journal.Event
+}
+
+func (h *Holder) setDefault() {
+	if h.Segment <= 0 {
+		h.Segment = DefaultSegment
+	}
+
+	if h.Ahead <= 0 || h.Ahead >= h.Segment {
+		h.Ahead = DefaultAhead
+		if h.Ahead >= h.Segment {
+			h.Ahead = h.Segment / 3
+		}
+	}
+
+	if h.Interval <= 0 {
+		h.Interval = DefaultInterval
+	}
+
+	if h.Log == nil {
+		h.Log = ioutil.Discard
+	}
+
+	if h.Now == nil {
+		h.Now = time.Now
+	}
+}
+
+// Current returns the override which is currently held.
+// If nothing is held, it returns nil.
+func (h *Holder) Current() *journal.Event {
+	return h.current
+}
+
+// Start creates the first segment from now.
+func (h *Holder) Start() error {
+	h.setDefault()
+
+	start := h.Now()
+	end := start.Add(h.Segment)
+
+	override, err := h.Dutyme.Override(h.ScheduleID, h.User, start, end, true)
+	if err != nil {
+		return err
+	}
+
+	h.current = &journal.Event{
+		OverrideID: override.ID,
+		ScheduleID: h.ScheduleID,
+		Start:      start,
+		End:        end,
+	}
+	h.save()
+	fmt.Fprintf(h.Log, "Hold schedule until %s (override %s)\n",
+		end.Local().Format(time.Kitchen), override.ID)
+
+	return nil
+}
+
+// Check checks heartbeat and renews the segment when it ends within
+// Ahead. It returns ErrHeartbeatLost when heartbeat is lost and
+// *dutyme.PolicyError when renewing violates the policy (then the
+// segment is not renewed and ends as scheduled).
+func (h *Holder) Check() error {
+	h.setDefault()
+
+	if h.current == nil {
+		return h.Start()
+	}
+
+	now := h.Now()
+	if h.current.End.Sub(now) > h.Ahead {
+		return nil
+	}
+
+	if h.Heartbeat != nil {
+		alive, err := h.Heartbeat.Alive()
+		if err != nil {
+			fmt.Fprintf(h.Log, "Failed to check heartbeat: %s\n", err)
+		}
+
+		if !alive {
+			fmt.Fprintf(h.Log, "Heartbeat is lost (%s). Stop renewing, override %s ends at %s\n",
+				h.Heartbeat, h.current.OverrideID, h.current.End.Local().Format(time.Kitchen))
+
+			// The last segment is intentionally left to end.
+			h.forget()
+			return ErrHeartbeatLost
+		}
+	}
+
+	// Renewal failed (e.g., network is down) for too long and the
+	// segment is already ended. Then start holding again.
+	if !h.current.End.After(now) {
+		return h.Start()
+	}
+
+	override, end, err := h.Dutyme.Extend(*h.current, h.User, h.Segment)
+	if _, ok := err.(*dutyme.PolicyError); ok {
+		// Holding reached the limit (e.g., max_duration). Starting
+		// holding again would get around it.
+		fmt.Fprintf(h.Log, "Stop renewing, override %s ends at %s: %s\n",
+			h.current.OverrideID, h.current.End.Local().Format(time.Kitchen), err)
+		h.forget()
+		return err
+	}
+
+	if err != nil {
+		// Retry on next check. If it keeps failing, the segment
+		// ends and on-call goes back to the rotation.
+		fmt.Fprintf(h.Log, "Failed to renew override %s: %s\n", h.current.OverrideID, err)
+		return nil
+	}
+
+	start := h.current.Start
+	if start.Before(now) {
+		start = now
+	}
+
+	// Since is kept so that the total holding is capped by policy.
+	since := h.current.Since
+	if since.IsZero() {
+		since = h.current.Start
+	}
+
+	h.current = &journal.Event{
+		OverrideID: override.ID,
+		ScheduleID: h.ScheduleID,
+		Start:      start,
+		End:        end,
+		Since:      since,
+	}
+	h.save()
+	fmt.Fprintf(h.Log, "Renew holding until %s (override %s)\n",
+		end.Local().Format(time.Kitchen), override.ID)
+
+	return nil
+}
+
+// Release stops the currently held override (on-call goes back to the
+// rotation now).
+func (h *Holder) Release() error {
+	if h.current == nil {
+		return nil
+	}
+
+	if err := h.Dutyme.DeleteOverride(h.current.ScheduleID, h.current.OverrideID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(h.Log, "Released override %s\n", h.current.OverrideID)
+	h.current = nil
+	h.forget()
+	return nil
+}
+
+// save persists the currently held override as the session of
+// this process. Failing to persist doesn't stop holding.
+func (h *Holder) save() {
+	if h.State == nil || h.current == nil {
+		return
+	}
+
+	session := state.NewSession("hold")
+	session.OverrideID = h.current.OverrideID
+	session.ScheduleID = h.current.ScheduleID
+	session.ScheduleName = h.ScheduleName
+	session.Start = h.current.Start
+	session.End = h.current.End
+
+	if err := h.State.Put(session); err != nil {
+		fmt.Fprintf(h.Log, "WARNING: failed to save session: %s\n", err)
+	}
+}
+
+// forget removes the session of this process.
+func (h *Holder) forget() {
+	if h.State == nil {
+		return
+	}
+
+	session := state.NewSession("hold")
+	if err := h.State.Remove(session.Host, session.PID); err != nil {
+		fmt.Fprintf(h.Log, "WARNING: failed to remove session: %s\n", err)
+	}
+}
+
+// Run starts holding and checks every interval until stop is closed
+// (then the held override is released) or heartbeat is lost (then
+// ErrHeartbeatLost is returned and the last segment is left to end).
+// Renewing which violates the policy stops holding in the same way.
+func (h *Holder) Run(stop <-chan struct{}) error {
+	h.setDefault()
+
+	if err := h.Start(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := h.Check(); err != nil {
+				if _, ok := err.(*dutyme.PolicyError); ok || err == ErrHeartbeatLost {
+					return err
+				}
+				fmt.Fprintf(h.Log, "Failed to hold schedule: %s\n", err)
+			}
+		case <-stop:
+			return h.Release()
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package hold
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/pdtest"
+)
+
+const (
+	testScheduleID = "PI7DH85"
+	testUserID     = "PXPGF42"
+)
+
+type testHeartbeat struct {
+	alive bool
+}
+
+func (h *testHeartbeat) Alive() (bool, error) { return h.alive, nil }
+func (h *testHeartbeat) String() string       { return "test" }
+
+func testNewHolder(heartbeat Heartbeat) (*Holder, *pdtest.PD) {
+	pd := &pdtest.PD{}
+	return &Holder{
+		Dutyme: &dutyme.Dutyme{PD: pd},
+		User: &dutyme.User{
+			Email: "taichi.nakashima@dutyme.com",
+			Obj:   &pagerduty.APIObject{ID: testUserID},
+		},
+		ScheduleID: testScheduleID,
+		Heartbeat:  heartbeat,
+	}, pd
+}
+
+func TestHolder_Check(t *testing.T) {
+	heartbeat := &testHeartbeat{alive: true}
+	h, pd := testNewHolder(heartbeat)
+
+	now := time.Now()
+	h.Now = func() time.Time { return now }
+
+	if err := h.Start(); err != nil {
+		t.Fatal("Start failed:", err)
+	}
+	first := h.Current()
+
+	// Segment doesn't end soon, nothing is renewed.
+	if err := h.Check(); err != nil {
+		t.Fatal("Check failed:", err)
+	}
+
+	if got, want := h.Current().OverrideID, first.OverrideID; got != want {
+		t.Fatalf("current override = %s, want %s", got, want)
+	}
+
+	// Segment ends within Ahead, it's renewed.
+	now = first.End.Add(-h.Ahead)
+	if err := h.Check(); err != nil {
+		t.Fatal("Check failed:", err)
+	}
+
+	if got, want := h.Current().End, first.End.Add(DefaultSegment); !got.Equal(want) {
+		t.Fatalf("renewed end = %s, want %s", got, want)
+	}
+
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	// Heartbeat is lost, it's not renewed.
+	second := h.Current()
+	heartbeat.alive = false
+	now = second.End.Add(-time.Minute)
+	if err := h.Check(); err != ErrHeartbeatLost {
+		t.Fatalf("Check returns %v, want %v", err, ErrHeartbeatLost)
+	}
+
+	if got, want := h.Current().OverrideID, second.OverrideID; got != want {
+		t.Fatalf("current override = %s, want %s", got, want)
+	}
+}
+
+func TestHolder_Check_maxDuration(t *testing.T) {
+	h, pd := testNewHolder(&testHeartbeat{alive: true})
+	h.Dutyme.Policy = &dutyme.Policy{MaxDuration: 40 * time.Minute}
+
+	now := time.Now()
+	h.Now = func() time.Time { return now }
+
+	if err := h.Start(); err != nil {
+		t.Fatal("Start failed:", err)
+	}
+	first := h.Current()
+
+	// Each segment is short but holding is capped in total (15m + 15m
+	// is allowed, but 15m + 15m + 15m isn't).
+	now = first.End.Add(-h.Ahead)
+	if err := h.Check(); err != nil {
+		t.Fatal("Check failed:", err)
+	}
+
+	second := h.Current()
+	now = second.End.Add(-h.Ahead)
+	if _, ok := h.Check().(*dutyme.PolicyError); !ok {
+		t.Fatal("expects PolicyError")
+	}
+
+	if got, want := h.Current().OverrideID, second.OverrideID; got != want {
+		t.Fatalf("current override = %s, want %s", got, want)
+	}
+
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+}
+
+func TestHolder_Release(t *testing.T) {
+	h, pd := testNewHolder(nil)
+
+	if err := h.Start(); err != nil {
+		t.Fatal("Start failed:", err)
+	}
+
+	if err := h.Release(); err != nil {
+		t.Fatal("Release failed:", err)
+	}
+
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	if h.Current() != nil {
+		t.Fatal("expect nothing to be held")
+	}
+}
+
+func TestFileHeartbeat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dutyme-hold")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "heartbeat")
+	h := &FileHeartbeat{Path: path, Stale: time.Minute}
+
+	if alive, _ := h.Alive(); alive {
+		t.Fatal("expect not to be alive when file doesn't exist")
+	}
+
+	if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+
+	if alive, _ := h.Alive(); !alive {
+		t.Fatal("expect to be alive when file is touched")
+	}
+
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal("Chtimes failed:", err)
+	}
+
+	if alive, _ := h.Alive(); alive {
+		t.Fatal("expect not to be alive when file is stale")
+	}
+}
+
+func TestProcessHeartbeat(t *testing.T) {
+	h := &ProcessHeartbeat{PID: os.Getpid()}
+	if alive, err := h.Alive(); err != nil || !alive {
+		t.Fatalf("expect own process to be alive: %v", err)
+	}
+}
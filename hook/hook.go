@@ -0,0 +1,126 @@
+// Package hook executes user's shell commands around dutyme actions
+// (e.g., post a message to deploy log when user takes on-call).
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Point is where hook is executed.
+type Point string
+
+const (
+	// PreStart is executed before overriding. If it fails,
+	// overriding is aborted.
+	PreStart Point = "pre_start"
+
+	// PostStart is executed after overriding.
+	PostStart Point = "post_start"
+
+	// PreStop is executed before stopping override. If it fails,
+	// stopping is aborted.
+	PreStop Point = "pre_stop"
+
+	// PostStop is executed after stopping override.
+	PostStop Point = "post_stop"
+
+	// OnExpireSoon is executed when daemon fires reminder
+	// before override expires.
+	OnExpireSoon Point = "on_expire_soon"
+)
+
+// Pre returns true if the hook is executed before the action
+// (then failing the hook aborts the action).
+func (p Point) Pre() bool {
+	return p == PreStart || p == PreStop
+}
+
+// Payload is the details of the override which are passed to hook
+// as JSON on stdin and DUTYME_HOOK* environment variables. They are not
+// DUTYME_* because those set configuration values of dutyme executed
+// by the hook (e.g., DUTYME_SCHEDULE_ID).
+type Payload struct {
+	Hook Point `json:"hook"`
+
+	// OverrideID is empty on pre_start.
+	OverrideID   string `json:"override_id,omitempty"`
+	ScheduleID   string `json:"schedule_id"`
+	ScheduleName string `json:"schedule_name,omitempty"`
+
+	UserID    string `json:"user_id,omitempty"`
+	UserEmail string `json:"user_email,omitempty"`
+
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	Reason string `json:"reason,omitempty"`
+	Ticket string `json:"ticket,omitempty"`
+}
+
+// EnvPrefix is the prefix of environment variables of the payload.
+const EnvPrefix = "DUTYME_HOOK_"
+
+// Env returns DUTYME_HOOK* environment variables of the payload.
+func (p *Payload) Env() []string {
+	return []string{
+		"DUTYME_HOOK=" + string(p.Hook),
+		EnvPrefix + "OVERRIDE_ID=" + p.OverrideID,
+		EnvPrefix + "SCHEDULE_ID=" + p.ScheduleID,
+		EnvPrefix + "SCHEDULE_NAME=" + p.ScheduleName,
+		EnvPrefix + "USER_ID=" + p.UserID,
+		EnvPrefix + "USER_EMAIL=" + p.UserEmail,
+		EnvPrefix + "START=" + p.Start.Format(time.RFC3339),
+		EnvPrefix + "END=" + p.End.Format(time.RFC3339),
+		EnvPrefix + "REASON=" + p.Reason,
+		EnvPrefix + "TICKET=" + p.Ticket,
+	}
+}
+
+// Runner executes hooks.
+type Runner struct {
+	// Commands is shell commands keyed by hook point.
+	Commands map[Point]string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Has returns true if the hook is configured for the given point.
+func (r *Runner) Has(point Point) bool {
+	return r != nil && len(r.Commands[point]) != 0
+}
+
+// Run executes the hook of the given point with the payload. If no hook
+// is configured for the point, it does nothing. It returns error when
+// the hook exits with non-zero status.
+func (r *Runner) Run(point Point, p Payload) error {
+	if !r.Has(point) {
+		return nil
+	}
+
+	p.Hook = point
+	buf, err := json.Marshal(&p)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode hook payload")
+	}
+
+	command := r.Commands[point]
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(append(buf, '\n'))
+	cmd.Stdout = r.Stdout
+	cmd.Stderr = r.Stderr
+	cmd.Env = append(os.Environ(), p.Env()...)
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "%s hook %q failed", point, command)
+	}
+
+	return nil
+}
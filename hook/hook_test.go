@@ -0,0 +1,71 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner_Run(t *testing.T) {
+	var stdout bytes.Buffer
+	r := &Runner{
+		Commands: map[Point]string{
+			PostStart: `echo "$DUTYME_HOOK $DUTYME_HOOK_SCHEDULE_ID $DUTYME_HOOK_TICKET $DUTYME_SCHEDULE_ID"; cat`,
+		},
+		Stdout: &stdout,
+	}
+
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	err := r.Run(PostStart, Payload{
+		OverrideID: "PEYSGVF",
+		ScheduleID: "PI7DH85",
+		Ticket:     "OPS-123",
+		Start:      start,
+		End:        start.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal("Run failed:", err)
+	}
+
+	lines := strings.SplitN(stdout.String(), "\n", 2)
+	if got, want := lines[0], "post_start PI7DH85 OPS-123 "; got != want {
+		t.Fatalf("env output = %q, want %q", got, want)
+	}
+
+	var p Payload
+	if err := json.Unmarshal([]byte(lines[1]), &p); err != nil {
+		t.Fatal("Unmarshal stdin failed:", err)
+	}
+
+	if got, want := p.Hook, PostStart; got != want {
+		t.Fatalf("hook = %q, want %q", got, want)
+	}
+
+	if got, want := p.OverrideID, "PEYSGVF"; got != want {
+		t.Fatalf("override ID = %q, want %q", got, want)
+	}
+}
+
+func TestRunner_Run_fail(t *testing.T) {
+	r := &Runner{
+		Commands: map[Point]string{
+			PreStart: "exit 1",
+		},
+	}
+
+	if err := r.Run(PreStart, Payload{}); err == nil {
+		t.Fatal("expects to fail when hook exits with non-zero status")
+	}
+
+	// Not configured hook does nothing.
+	if err := r.Run(PreStop, Payload{}); err != nil {
+		t.Fatal("Run failed:", err)
+	}
+
+	var nilRunner *Runner
+	if err := nilRunner.Run(PreStart, Payload{}); err != nil {
+		t.Fatal("Run failed:", err)
+	}
+}
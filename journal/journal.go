@@ -0,0 +1,191 @@
+// Package journal records overrides which are created by dutyme.
+//
+// Journal is append-only JSON lines file. Each line is an event of
+// override (started, extended or stopped). The current state of the
+// overrides is computed by replaying the events.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Actions of events.
+const (
+	ActionStart  = "start"
+	ActionExtend = "extend"
+	ActionStop   = "stop"
+)
+
+// Event is an event of override created by dutyme.
+type Event struct {
+	Action string    `json:"action"`
+	Time   time.Time `json:"time"`
+
+	OverrideID string `json:"override_id"`
+
+	// PreviousID is the ID of the override which is replaced
+	// by this override (only for extend action).
+	PreviousID string `json:"previous_id,omitempty"`
+
+	ScheduleID   string `json:"schedule_id"`
+	ScheduleName string `json:"schedule_name,omitempty"`
+
+	UserID    string `json:"user_id,omitempty"`
+	UserEmail string `json:"user_email,omitempty"`
+
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Since is when user went on call by the first override which is
+	// extended to this override (only for extend action, or start action
+	// when the old override couldn't be deleted on extending). It's used
+	// for capping the total duration of extending repeatedly.
+	Since time.Time `json:"since"`
+
+	// Reason and Ticket are why user overrides (given by -reason and
+	// -ticket). Extending keeps them.
+	Reason string `json:"reason,omitempty"`
+	Ticket string `json:"ticket,omitempty"`
+}
+
+// Journal is the journal file.
+type Journal struct {
+	Path string
+}
+
+// Record appends the given event to the journal file.
+func (j *Journal) Record(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.Path), 0700); err != nil {
+		return errors.Wrap(err, "failed to create journal directory")
+	}
+
+	f, err := os.OpenFile(j.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open journal file")
+	}
+	defer f.Close()
+
+	// Each event is written by single write call so that
+	// events from concurrent processes are not interleaved.
+	buf, err := json.Marshal(&e)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode event")
+	}
+
+	if _, err := f.Write(append(buf, '\n')); err != nil {
+		return errors.Wrap(err, "failed to write event")
+	}
+
+	return nil
+}
+
+// Events reads all events from the journal file. If the file
+// doesn't exist, it returns no events.
+func (j *Journal) Events() ([]Event, error) {
+	f, err := os.Open(j.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open journal file")
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, errors.Wrap(err, "failed to decode event")
+		}
+		events = append(events, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read journal file")
+	}
+
+	return events, nil
+}
+
+// Active returns the overrides which are not stopped (or replaced) and
+// not ended at the given time. Each override is represented by the
+// event which creates it. They are sorted by end time.
+func Active(events []Event, now time.Time) []Event {
+	current := make(map[string]Event)
+	for _, e := range events {
+		switch e.Action {
+		case ActionStart:
+			current[e.OverrideID] = e
+		case ActionExtend:
+			delete(current, e.PreviousID)
+			current[e.OverrideID] = e
+		case ActionStop:
+			delete(current, e.OverrideID)
+		}
+	}
+
+	active := make([]Event, 0, len(current))
+	for _, e := range current {
+		if e.End.After(now) {
+			active = append(active, e)
+		}
+	}
+	sort.Sort(byEnd(active))
+
+	return active
+}
+
+// Latest returns the ID of the override which the given override is
+// replaced by (extended to) at last. If it's not replaced, it returns
+// the given ID.
+func Latest(events []Event, overrideID string) string {
+	for _, e := range events {
+		if e.Action == ActionExtend && e.PreviousID == overrideID {
+			overrideID = e.OverrideID
+		}
+	}
+	return overrideID
+}
+
+// First returns the event which started the first override of the
+// extended chain which the given override belongs to. If it's not
+// recorded, it returns nil.
+func First(events []Event, overrideID string) *Event {
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		if e.OverrideID != overrideID {
+			continue
+		}
+
+		switch e.Action {
+		case ActionStart:
+			return &events[i]
+		case ActionExtend:
+			overrideID = e.PreviousID
+		}
+	}
+	return nil
+}
+
+// byEnd implements sort.Interface for sorting events by end time.
+type byEnd []Event
+
+func (e byEnd) Len() int           { return len(e) }
+func (e byEnd) Less(i, j int) bool { return e[i].End.Before(e[j].End) }
+func (e byEnd) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
@@ -0,0 +1,72 @@
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testNewJournal(t *testing.T) (*Journal, func()) {
+	dir, err := ioutil.TempDir("", "dutyme-journal")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+
+	return &Journal{
+		Path: filepath.Join(dir, "sub", "journal.jsonl"),
+	}, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	j, cleanup := testNewJournal(t)
+	defer cleanup()
+
+	events, err := j.Events()
+	if err != nil {
+		t.Fatal("Events failed:", err)
+	}
+
+	if got, want := len(events), 0; got != want {
+		t.Fatalf("events number = %d, want %d", got, want)
+	}
+
+	now := time.Now()
+	records := []Event{
+		{Action: ActionStart, OverrideID: "P1", Start: now, End: now.Add(time.Hour)},
+		{Action: ActionStart, OverrideID: "P2", Start: now, End: now.Add(2 * time.Hour)},
+		{Action: ActionExtend, OverrideID: "P3", PreviousID: "P1", Start: now, End: now.Add(3 * time.Hour)},
+		{Action: ActionStart, OverrideID: "P4", Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)},
+		{Action: ActionStart, OverrideID: "P5", Start: now, End: now.Add(time.Hour)},
+		{Action: ActionStop, OverrideID: "P5"},
+	}
+
+	for _, e := range records {
+		if err := j.Record(e); err != nil {
+			t.Fatal("Record failed:", err)
+		}
+	}
+
+	events, err = j.Events()
+	if err != nil {
+		t.Fatal("Events failed:", err)
+	}
+
+	if got, want := len(events), len(records); got != want {
+		t.Fatalf("events number = %d, want %d", got, want)
+	}
+
+	active := Active(events, now)
+	if got, want := len(active), 2; got != want {
+		t.Fatalf("active number = %d, want %d", got, want)
+	}
+
+	for i, want := range []string{"P2", "P3"} {
+		if got := active[i].OverrideID; got != want {
+			t.Fatalf("active[%d] = %q, want %q", i, got, want)
+		}
+	}
+}
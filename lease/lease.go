@@ -0,0 +1,327 @@
+// Package lease lets multiple dutyme processes (e.g., parallel deploy
+// jobs on the same CI runner) share one override.
+//
+// Processes which request an override for the same user and schedule
+// join the existing override as lease holders instead of stacking their
+// own overrides. The end of the override is extended to the latest
+// requested one and the override is released only when the last holder
+// leaves. Leases are coordinated by the lease file which is read and
+// written while holding an exclusive lock.
+//
+// When all holders are killed (e.g., by SIGKILL) and can't release the
+// lease, the group is left on the lease file as orphaned until its
+// override ends. It's adopted by the next Acquire or stopped by gc.
+package lease
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/state"
+)
+
+// Holder is the process which holds the lease.
+type Holder struct {
+	Host string `json:"host"`
+	PID  int    `json:"pid"`
+
+	// End is the end which the holder requested.
+	End time.Time `json:"end"`
+}
+
+// Group is the override shared by lease holders.
+type Group struct {
+	OverrideID string `json:"override_id"`
+	ScheduleID string `json:"schedule_id"`
+	UserID     string `json:"user_id"`
+
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	Holders []Holder `json:"holders"`
+}
+
+// Lease is the lease held by the current process.
+type Lease struct {
+	Group
+
+	// Joined is true when the lease joined the existing override.
+	Joined bool
+}
+
+// Manager manages leases on the lease file.
+type Manager struct {
+	Path   string
+	Dutyme *dutyme.Dutyme
+}
+
+// Acquire joins the active override for the given user and schedule
+// (extending its end when the given end is later), or creates new
+// override from now to end when no one holds it.
+func (m *Manager) Acquire(scheduleID string, user *dutyme.User, end time.Time) (*Lease, error) {
+	if user == nil || user.Obj == nil {
+		return nil, errors.New("user is not resolved")
+	}
+
+	var lease *Lease
+	var replaceErr error
+	err := m.update(func(groups []Group) ([]Group, error) {
+		now := time.Now()
+		holder := currentHolder(end)
+
+		for i := range groups {
+			g := &groups[i]
+			if g.ScheduleID != scheduleID || g.UserID != user.Obj.ID {
+				continue
+			}
+
+			if g.End.After(end) || g.End.Equal(end) {
+				g.Holders = append(g.Holders, holder)
+				lease = &Lease{Group: *g, Joined: true}
+				return groups, nil
+			}
+
+			start := g.Start
+			if start.Before(now) {
+				start = now
+			}
+
+			override, err := m.Dutyme.ReplaceOverride(scheduleID, g.OverrideID, user, start, end)
+			if override == nil {
+				return nil, errors.Wrap(err, "failed to extend shared override")
+			}
+			g.OverrideID = override.ID
+			g.Start, g.End = start, end
+
+			// The new override is created but the old one is not deleted.
+			// Keep the new one in the group, so it's stopped by the other
+			// holders (the current process doesn't join it).
+			if err != nil {
+				replaceErr = errors.Wrap(err, "failed to extend shared override")
+				return groups, nil
+			}
+
+			g.Holders = append(g.Holders, holder)
+			lease = &Lease{Group: *g, Joined: true}
+			return groups, nil
+		}
+
+		override, err := m.Dutyme.Override(scheduleID, user, now, end, true)
+		if err != nil {
+			return nil, err
+		}
+
+		g := Group{
+			OverrideID: override.ID,
+			ScheduleID: scheduleID,
+			UserID:     user.Obj.ID,
+			Start:      now,
+			End:        end,
+			Holders:    []Holder{holder},
+		}
+		lease = &Lease{Group: g}
+		return append(groups, g), nil
+	})
+	if replaceErr != nil {
+		return nil, replaceErr
+	}
+
+	return lease, err
+}
+
+// Release leaves the lease of the current process. When the current
+// process is the last holder, the shared override is stopped and it
+// returns true.
+func (m *Manager) Release(l *Lease) (bool, error) {
+	host, _ := os.Hostname()
+	pid := os.Getpid()
+
+	released := false
+	err := m.update(func(groups []Group) ([]Group, error) {
+		for i := range groups {
+			g := &groups[i]
+			if g.ScheduleID != l.ScheduleID || g.UserID != l.UserID {
+				continue
+			}
+
+			holders := g.Holders[:0]
+			for _, h := range g.Holders {
+				if h.Host == host && h.PID == pid {
+					continue
+				}
+				holders = append(holders, h)
+			}
+			g.Holders = holders
+
+			if len(g.Holders) > 0 {
+				return groups, nil
+			}
+
+			if err := m.Dutyme.DeleteOverride(g.ScheduleID, g.OverrideID); err != nil {
+				return nil, errors.Wrap(err, "failed to stop shared override")
+			}
+			released = true
+
+			return append(groups[:i], groups[i+1:]...), nil
+		}
+		return groups, nil
+	})
+
+	return released, err
+}
+
+// Orphaned returns the groups which lost all holders (their processes
+// were killed) but whose overrides are not ended yet.
+func (m *Manager) Orphaned() ([]Group, error) {
+	groups, err := m.Groups()
+	if err != nil {
+		return nil, err
+	}
+
+	var orphaned []Group
+	for _, g := range groups {
+		if len(g.Holders) == 0 {
+			orphaned = append(orphaned, g)
+		}
+	}
+	return orphaned, nil
+}
+
+// Stop stops the override of the orphaned group and removes the group.
+// The group which has holders is never stopped.
+func (m *Manager) Stop(overrideID string) error {
+	return m.update(func(groups []Group) ([]Group, error) {
+		for i, g := range groups {
+			if g.OverrideID != overrideID {
+				continue
+			}
+
+			if len(g.Holders) != 0 {
+				return nil, errors.Errorf("override %s is held by %d process(es)", overrideID, len(g.Holders))
+			}
+
+			if err := m.Dutyme.DeleteOverride(g.ScheduleID, g.OverrideID); err != nil {
+				return nil, errors.Wrap(err, "failed to stop orphaned override")
+			}
+			return append(groups[:i], groups[i+1:]...), nil
+		}
+		return nil, errors.Errorf("no such lease group: %s", overrideID)
+	})
+}
+
+// Groups returns the overrides shared by lease holders.
+func (m *Manager) Groups() ([]Group, error) {
+	var groups []Group
+	err := m.update(func(current []Group) ([]Group, error) {
+		groups = current
+		return current, nil
+	})
+	return groups, err
+}
+
+// update reads groups, calls fn with them and writes the groups returned
+// by fn while holding the exclusive lock. Before fn is called, ended
+// groups and holders whose process doesn't exist are removed (the group
+// which loses all holders is kept as orphaned).
+func (m *Manager) update(fn func([]Group) ([]Group, error)) error {
+	if err := os.MkdirAll(filepath.Dir(m.Path), 0700); err != nil {
+		return errors.Wrap(err, "failed to create lease directory")
+	}
+
+	unlock, err := state.Lock(m.Path + ".lock")
+	if err != nil {
+		return errors.Wrap(err, "failed to lock lease file")
+	}
+	defer unlock()
+
+	groups, err := m.read()
+	if err != nil {
+		return err
+	}
+
+	groups, err = fn(prune(groups, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	return m.write(groups)
+}
+
+func (m *Manager) read() ([]Group, error) {
+	buf, err := ioutil.ReadFile(m.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read lease file")
+	}
+
+	var groups []Group
+	if err := json.Unmarshal(buf, &groups); err != nil {
+		return nil, errors.Wrap(err, "failed to decode lease file")
+	}
+
+	return groups, nil
+}
+
+func (m *Manager) write(groups []Group) error {
+	if groups == nil {
+		groups = []Group{}
+	}
+
+	buf, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode leases")
+	}
+
+	tmp := m.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return errors.Wrap(err, "failed to write lease file")
+	}
+
+	if err := os.Rename(tmp, m.Path); err != nil {
+		return errors.Wrap(err, "failed to replace lease file")
+	}
+
+	return nil
+}
+
+// prune removes ended groups and the holders on this host whose
+// process doesn't exist anymore.
+func prune(groups []Group, now time.Time) []Group {
+	host, _ := os.Hostname()
+
+	var kept []Group
+	for _, g := range groups {
+		if !g.End.After(now) {
+			continue
+		}
+
+		holders := g.Holders[:0]
+		for _, h := range g.Holders {
+			if h.Host == host {
+				if exists, err := state.ProcessExists(h.PID); err == nil && !exists {
+					continue
+				}
+			}
+			holders = append(holders, h)
+		}
+		g.Holders = holders
+		kept = append(kept, g)
+	}
+	return kept
+}
+
+func currentHolder(end time.Time) Holder {
+	host, _ := os.Hostname()
+	return Holder{
+		Host: host,
+		PID:  os.Getpid(),
+		End:  end,
+	}
+}
@@ -0,0 +1,213 @@
+package lease
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/pdtest"
+)
+
+const testScheduleID = "PI7DH85"
+
+func testManager(t *testing.T) (*Manager, *pdtest.PD, func()) {
+	dir, err := ioutil.TempDir("", "dutyme-lease")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+
+	pd := &pdtest.PD{}
+	return &Manager{
+		Path:   filepath.Join(dir, "leases.json"),
+		Dutyme: &dutyme.Dutyme{PD: pd},
+	}, pd, func() { os.RemoveAll(dir) }
+}
+
+var testUser = &dutyme.User{
+	Email: "taichi.nakashima@dutyme.com",
+	Obj:   &pagerduty.APIObject{ID: "PXPGF42"},
+}
+
+func TestManager(t *testing.T) {
+	m, pd, cleanup := testManager(t)
+	defer cleanup()
+
+	now := time.Now()
+	first, err := m.Acquire(testScheduleID, testUser, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal("Acquire failed:", err)
+	}
+
+	if first.Joined {
+		t.Fatal("expect first lease to create override")
+	}
+
+	// Another job on another host joins.
+	if err := m.update(func(groups []Group) ([]Group, error) {
+		groups[0].Holders = append(groups[0].Holders, Holder{Host: "other", PID: 1})
+		return groups, nil
+	}); err != nil {
+		t.Fatal("update failed:", err)
+	}
+
+	// Joining with earlier end doesn't change the override.
+	second, err := m.Acquire(testScheduleID, testUser, now.Add(30*time.Minute))
+	if err != nil {
+		t.Fatal("Acquire failed:", err)
+	}
+
+	if !second.Joined || second.OverrideID != first.OverrideID {
+		t.Fatalf("expect to join override %s: %#v", first.OverrideID, second)
+	}
+
+	// Joining with later end extends the override.
+	third, err := m.Acquire(testScheduleID, testUser, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal("Acquire failed:", err)
+	}
+
+	if third.OverrideID == first.OverrideID {
+		t.Fatal("expect override to be replaced")
+	}
+
+	if got, want := third.End, now.Add(2*time.Hour); !got.Equal(want) {
+		t.Fatalf("shared override end = %s, want %s", got, want)
+	}
+
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	// Other holder still holds it.
+	released, err := m.Release(third)
+	if err != nil {
+		t.Fatal("Release failed:", err)
+	}
+
+	if released {
+		t.Fatal("expect override not to be released while other holder holds it")
+	}
+
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+}
+
+func TestManager_partialReplace(t *testing.T) {
+	m, pd, cleanup := testManager(t)
+	defer cleanup()
+
+	now := time.Now()
+	first, err := m.Acquire(testScheduleID, testUser, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal("Acquire failed:", err)
+	}
+
+	// The new override is created but the old one can't be deleted.
+	pd.DeleteErr = errors.New("network is unreachable")
+	if _, err := m.Acquire(testScheduleID, testUser, now.Add(2*time.Hour)); err == nil {
+		t.Fatal("expect Acquire to fail")
+	}
+
+	groups, err := m.Groups()
+	if err != nil {
+		t.Fatal("Groups failed:", err)
+	}
+
+	if len(groups) != 1 || groups[0].OverrideID == first.OverrideID {
+		t.Fatalf("groups = %#v, want the new override", groups)
+	}
+
+	if got, want := groups[0].OverrideID, pd.Overrides[1].ID; got != want {
+		t.Fatalf("override of group = %s, want %s", want, got)
+	}
+
+	if got, want := len(groups[0].Holders), 1; got != want {
+		t.Fatalf("holders number = %d, want %d", got, want)
+	}
+}
+
+func TestManager_Release(t *testing.T) {
+	m, pd, cleanup := testManager(t)
+	defer cleanup()
+
+	l, err := m.Acquire(testScheduleID, testUser, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal("Acquire failed:", err)
+	}
+
+	released, err := m.Release(l)
+	if err != nil {
+		t.Fatal("Release failed:", err)
+	}
+
+	if !released {
+		t.Fatal("expect override to be released by last holder")
+	}
+
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	groups, err := m.Groups()
+	if err != nil {
+		t.Fatal("Groups failed:", err)
+	}
+
+	if got, want := len(groups), 0; got != want {
+		t.Fatalf("groups number = %d, want %d", got, want)
+	}
+}
+
+func TestManager_Orphaned(t *testing.T) {
+	m, pd, cleanup := testManager(t)
+	defer cleanup()
+
+	l, err := m.Acquire(testScheduleID, testUser, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal("Acquire failed:", err)
+	}
+
+	// The holder is killed by SIGKILL and can't release the lease.
+	if err := m.update(func(groups []Group) ([]Group, error) {
+		groups[0].Holders[0].PID = 99999999
+		return groups, nil
+	}); err != nil {
+		t.Fatal("update failed:", err)
+	}
+
+	orphaned, err := m.Orphaned()
+	if err != nil {
+		t.Fatal("Orphaned failed:", err)
+	}
+
+	if got, want := len(orphaned), 1; got != want {
+		t.Fatalf("orphaned groups number = %d, want %d", got, want)
+	}
+
+	if got, want := orphaned[0].OverrideID, l.OverrideID; got != want {
+		t.Fatalf("orphaned override = %q, want %q", got, want)
+	}
+
+	if err := m.Stop(l.OverrideID); err != nil {
+		t.Fatal("Stop failed:", err)
+	}
+
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	groups, err := m.Groups()
+	if err != nil {
+		t.Fatal("Groups failed:", err)
+	}
+
+	if got, want := len(groups), 0; got != want {
+		t.Fatalf("groups number = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+// Package mapping maps external identities (e.g., GitHub login or Slack
+// user ID) to PagerDuty users and aliases (e.g., deploy environment or
+// short name) to PagerDuty schedules. It's used by the servers which
+// receive requests from other services (webhook and Slack).
+package mapping
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Mapping is the content of the mapping file, e.g.,
+//
+//	{
+//	  "users": {"octocat": "octocat@example.com"},
+//	  "schedules": {"production": "PI7DH85"},
+//	  "default_schedule": "PI7DH85"
+//	}
+type Mapping struct {
+	// Users maps external identity to PagerDuty user email.
+	Users map[string]string `json:"users,omitempty"`
+
+	// Schedules maps alias to PagerDuty schedule ID.
+	Schedules map[string]string `json:"schedules,omitempty"`
+
+	// DefaultSchedule is used when no schedule is specified.
+	DefaultSchedule string `json:"default_schedule,omitempty"`
+}
+
+// ParseFile parses the mapping file.
+func ParseFile(path string) (*Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open mapping file")
+	}
+	defer f.Close()
+
+	var m Mapping
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return nil, errors.Wrap(err, "failed to decode mapping file")
+	}
+
+	return &m, nil
+}
+
+// Email returns the PagerDuty user email of the given identity.
+func (m *Mapping) Email(identity string) (string, error) {
+	email, ok := m.Users[identity]
+	if !ok || len(email) == 0 {
+		return "", errors.Errorf("%q is not mapped to PagerDuty user", identity)
+	}
+	return email, nil
+}
+
+// Schedule returns the PagerDuty schedule ID of the given alias. When
+// alias is empty, the default schedule is returned. When alias is not
+// in the mapping, it's regarded as schedule ID.
+func (m *Mapping) Schedule(alias string) (string, error) {
+	if len(alias) == 0 {
+		if len(m.DefaultSchedule) == 0 {
+			return "", errors.New("schedule is not specified and no default schedule is configured")
+		}
+		return m.DefaultSchedule, nil
+	}
+
+	if id, ok := m.Schedules[alias]; ok {
+		return id, nil
+	}
+	return alias, nil
+}
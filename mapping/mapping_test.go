@@ -0,0 +1,58 @@
+package mapping
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dutyme-mapping")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "mapping.json")
+	content := `{
+  "users": {"octocat": "octocat@example.com"},
+  "schedules": {"production": "PI7DH85"},
+  "default_schedule": "PI9DH21"
+}`
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal("WriteFile failed:", err)
+	}
+
+	m, err := ParseFile(path)
+	if err != nil {
+		t.Fatal("ParseFile failed:", err)
+	}
+
+	if email, err := m.Email("octocat"); err != nil || email != "octocat@example.com" {
+		t.Fatalf("Email = %q, %v", email, err)
+	}
+
+	if _, err := m.Email("unknown"); err == nil {
+		t.Fatal("expect unknown identity to fail")
+	}
+
+	cases := []struct {
+		alias, want string
+	}{
+		{"production", "PI7DH85"},
+		{"", "PI9DH21"},
+		{"PABCDEF", "PABCDEF"},
+	}
+
+	for _, tc := range cases {
+		got, err := m.Schedule(tc.alias)
+		if err != nil {
+			t.Fatalf("Schedule(%q) failed: %s", tc.alias, err)
+		}
+
+		if got != tc.want {
+			t.Fatalf("Schedule(%q) = %q, want %q", tc.alias, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sendMail is replaced in tests.
+var sendMail = smtp.SendMail
+
+// EmailNotifier sends notification by email via SMTP server.
+// If Username is set, it authenticates by PLAIN auth (then the
+// server must support TLS unless it's localhost).
+type EmailNotifier struct {
+	// Addr is the SMTP server address (host:port).
+	Addr string
+
+	From string
+	To   []string
+
+	// ToDisplaced sends the email to the displaced users too (they
+	// may not watch the To addresses, e.g., the team mailing list).
+	ToDisplaced bool
+
+	Username string
+	Password string
+}
+
+func (n *EmailNotifier) Notify(notification *Notification) error {
+	to := n.recipients(notification)
+	if len(to) == 0 {
+		return errors.New("no email recipient is configured")
+	}
+
+	var auth smtp.Auth
+	if len(n.Username) != 0 {
+		host, _, err := net.SplitHostPort(n.Addr)
+		if err != nil {
+			return errors.Wrap(err, "invalid SMTP server address")
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	if err := sendMail(n.Addr, auth, n.From, to, n.message(notification, to)); err != nil {
+		return errors.Wrap(err, "failed to send email")
+	}
+
+	return nil
+}
+
+// recipients returns To and, if ToDisplaced is set, the emails of the
+// displaced users without duplicates.
+func (n *EmailNotifier) recipients(notification *Notification) []string {
+	to := make([]string, 0, len(n.To)+len(notification.DisplacedEmails))
+	seen := make(map[string]bool)
+	add := func(addrs []string) {
+		for _, addr := range addrs {
+			if seen[strings.ToLower(addr)] {
+				continue
+			}
+			seen[strings.ToLower(addr)] = true
+			to = append(to, addr)
+		}
+	}
+
+	add(n.To)
+	if n.ToDisplaced {
+		add(notification.DisplacedEmails)
+	}
+	return to
+}
+
+func (n *EmailNotifier) message(notification *Notification, to []string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: [dutyme] %s\r\n", notification.Subject())
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(&buf, "\r\n")
+	fmt.Fprintf(&buf, "%s\r\n", strings.Replace(notification.Text(), "\n", "\r\n", -1))
+	return buf.Bytes()
+}
@@ -0,0 +1,103 @@
+// Package notify notifies the change of on-call made by dutyme (e.g.,
+// the person who was on call is relieved) to webhook, Slack and email.
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// ActionStart is the action when user takes on-call by override.
+	ActionStart = "start"
+
+	// ActionStop is the action when user stops override and on-call
+	// goes back to the rotation.
+	ActionStop = "stop"
+)
+
+// timeFmt is used for displaying the window in messages.
+const timeFmt = "2006-01-02 15:04 MST"
+
+// Notification is the change of on-call made by dutyme.
+type Notification struct {
+	Action string `json:"action"`
+
+	OverrideID   string `json:"override_id"`
+	ScheduleID   string `json:"schedule_id"`
+	ScheduleName string `json:"schedule_name,omitempty"`
+
+	// Start and End is the override window. On stop, Start is the time
+	// when it's stopped and End is when the override would have ended.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// User is the new on-call (on stop, the user who stops).
+	User string `json:"user"`
+
+	// Displaced is the users who are on call by the rotation in the
+	// window. They are relieved on start and back on call on stop.
+	Displaced []string `json:"displaced,omitempty"`
+
+	// DisplacedEmails is the emails of the displaced users.
+	DisplacedEmails []string `json:"displaced_emails,omitempty"`
+
+	// Reason and Ticket are why user overrides the schedule.
+	Reason string `json:"reason,omitempty"`
+	Ticket string `json:"ticket,omitempty"`
+}
+
+// Notifier notifies the change of on-call.
+type Notifier interface {
+	Notify(n *Notification) error
+}
+
+// Schedule returns the schedule name with its ID.
+func (n *Notification) Schedule() string {
+	if len(n.ScheduleName) == 0 {
+		return n.ScheduleID
+	}
+	return fmt.Sprintf("%s (%s)", n.ScheduleName, n.ScheduleID)
+}
+
+// Window returns the override window in human readable format.
+func (n *Notification) Window() string {
+	return fmt.Sprintf("%s - %s",
+		n.Start.Local().Format(timeFmt), n.End.Local().Format(timeFmt))
+}
+
+// Subject returns one line summary of the notification.
+func (n *Notification) Subject() string {
+	if n.Action == ActionStop {
+		return fmt.Sprintf("%s stopped overriding %s", n.User, n.Schedule())
+	}
+	return fmt.Sprintf("%s is on call for %s", n.User, n.Schedule())
+}
+
+// Text returns the message of the notification.
+func (n *Notification) Text() string {
+	displaced := "nobody"
+	if len(n.Displaced) != 0 {
+		displaced = strings.Join(n.Displaced, ", ")
+	}
+
+	lines := []string{n.Subject() + "."}
+	if n.Action == ActionStop {
+		lines = append(lines, fmt.Sprintf("On-call goes back to %s until %s.",
+			displaced, n.End.Local().Format(timeFmt)))
+	} else {
+		lines = append(lines, fmt.Sprintf("Window: %s", n.Window()))
+		lines = append(lines, fmt.Sprintf("Relieved: %s", displaced))
+	}
+
+	if len(n.Reason) != 0 {
+		lines = append(lines, fmt.Sprintf("Reason: %s", n.Reason))
+	}
+
+	if len(n.Ticket) != 0 {
+		lines = append(lines, fmt.Sprintf("Ticket: %s", n.Ticket))
+	}
+
+	return strings.Join(lines, "\n")
+}
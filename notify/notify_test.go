@@ -0,0 +1,230 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testNotification() *Notification {
+	start := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+	return &Notification{
+		Action:       ActionStart,
+		OverrideID:   "PEYSGVF",
+		ScheduleID:   "PI7DH85",
+		ScheduleName: "Dutyme primary",
+		Start:        start,
+		End:          start.Add(2 * time.Hour),
+		User:         "Taichi",
+		Displaced:    []string{"Alice", "Bob"},
+		Reason:       "deploy checkout-api",
+		Ticket:       "OPS-123",
+	}
+}
+
+// testServer returns the server which records the posted body.
+func testServer(t *testing.T, body *[]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Content-Type"), "application/json"; got != want {
+			t.Fatalf("Content-Type = %q, want %q", got, want)
+		}
+
+		buf, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal("ReadAll failed:", err)
+		}
+		*body = buf
+	}))
+}
+
+func TestNotification_Text(t *testing.T) {
+	n := testNotification()
+	text := n.Text()
+	for _, want := range []string{"Taichi is on call for Dutyme primary (PI7DH85)", "Relieved: Alice, Bob", "Reason: deploy checkout-api", "Ticket: OPS-123"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("Text() = %q, want to contain %q", text, want)
+		}
+	}
+
+	n.Action = ActionStop
+	if text := n.Text(); !strings.Contains(text, "On-call goes back to Alice, Bob") {
+		t.Fatalf("Text() = %q, want to contain who is back on call", text)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var body []byte
+	ts := testServer(t, &body)
+	defer ts.Close()
+
+	n := &WebhookNotifier{URL: ts.URL}
+	if err := n.Notify(testNotification()); err != nil {
+		t.Fatal("Notify failed:", err)
+	}
+
+	var got Notification
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal("Unmarshal failed:", err)
+	}
+
+	if got.User != "Taichi" || len(got.Displaced) != 2 || got.Reason == "" {
+		t.Fatalf("posted notification = %#v", got)
+	}
+}
+
+func TestWebhookNotifier_template(t *testing.T) {
+	var body []byte
+	ts := testServer(t, &body)
+	defer ts.Close()
+
+	n := &WebhookNotifier{
+		URL:      ts.URL,
+		Template: `{"schedule": {{ json .ScheduleName }}, "relieved": {{ json (join .Displaced ", ") }}}`,
+	}
+	if err := n.Notify(testNotification()); err != nil {
+		t.Fatal("Notify failed:", err)
+	}
+
+	if got, want := string(body), `{"schedule": "Dutyme primary", "relieved": "Alice, Bob"}`; got != want {
+		t.Fatalf("posted body = %s, want %s", got, want)
+	}
+
+	// Broken template is not posted.
+	n.Template = `{"schedule": {{ .ScheduleName }}}`
+	if err := n.Notify(testNotification()); err == nil {
+		t.Fatal("expects to fail when template doesn't render JSON")
+	}
+}
+
+func TestWebhookNotifier_status(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	n := &WebhookNotifier{URL: ts.URL}
+	if err := n.Notify(testNotification()); err == nil {
+		t.Fatal("expects to fail when endpoint returns error status")
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var body []byte
+	ts := testServer(t, &body)
+	defer ts.Close()
+
+	n := &SlackNotifier{URL: ts.URL, Channel: "#deploy"}
+	if err := n.Notify(testNotification()); err != nil {
+		t.Fatal("Notify failed:", err)
+	}
+
+	var got slackMessage
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal("Unmarshal failed:", err)
+	}
+
+	if got.Channel != "#deploy" {
+		t.Fatalf("channel = %q, want #deploy", got.Channel)
+	}
+
+	if len(got.Attachments) != 1 || len(got.Attachments[0].Fields) != 6 {
+		t.Fatalf("attachments = %#v", got.Attachments)
+	}
+
+	if got, want := got.Attachments[0].Fields[3].Value, "Alice, Bob"; got != want {
+		t.Fatalf("relieved field = %q, want %q", got, want)
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	var (
+		gotAddr string
+		gotTo   []string
+		gotMsg  []byte
+	)
+	defer func(f func(string, smtp.Auth, string, []string, []byte) error) {
+		sendMail = f
+	}(sendMail)
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotTo, gotMsg = addr, to, msg
+		return nil
+	}
+
+	n := &EmailNotifier{
+		Addr:     "smtp.example.com:587",
+		From:     "dutyme@example.com",
+		To:       []string{"alice@example.com", "bob@example.com"},
+		Username: "dutyme",
+		Password: "secret",
+	}
+	if err := n.Notify(testNotification()); err != nil {
+		t.Fatal("Notify failed:", err)
+	}
+
+	if gotAddr != n.Addr || len(gotTo) != 2 {
+		t.Fatalf("sent to %s %v", gotAddr, gotTo)
+	}
+
+	msg := string(gotMsg)
+	for _, want := range []string{"Subject: [dutyme] Taichi is on call for Dutyme primary", "Relieved: Alice, Bob\r\n"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("message = %q, want to contain %q", msg, want)
+		}
+	}
+
+	if err := (&EmailNotifier{Addr: n.Addr}).Notify(testNotification()); err == nil {
+		t.Fatal("expects to fail without recipients")
+	}
+}
+
+func TestEmailNotifier_toDisplaced(t *testing.T) {
+	var (
+		gotTo  []string
+		gotMsg []byte
+	)
+	defer func(f func(string, smtp.Auth, string, []string, []byte) error) {
+		sendMail = f
+	}(sendMail)
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotTo, gotMsg = to, msg
+		return nil
+	}
+
+	notification := testNotification()
+	notification.DisplacedEmails = []string{"alice@example.com", "Oncall@example.com"}
+
+	n := &EmailNotifier{
+		Addr:        "localhost:25",
+		From:        "dutyme@example.com",
+		To:          []string{"oncall@example.com"},
+		ToDisplaced: true,
+	}
+	if err := n.Notify(notification); err != nil {
+		t.Fatal("Notify failed:", err)
+	}
+
+	want := []string{"oncall@example.com", "alice@example.com"}
+	if !reflect.DeepEqual(gotTo, want) {
+		t.Fatalf("sent to %v, want %v", gotTo, want)
+	}
+
+	if !strings.Contains(string(gotMsg), "To: oncall@example.com, alice@example.com\r\n") {
+		t.Fatalf("message = %q, want to contain all recipients", gotMsg)
+	}
+
+	// Without ToDisplaced, only To receives it.
+	n.ToDisplaced = false
+	if err := n.Notify(notification); err != nil {
+		t.Fatal("Notify failed:", err)
+	}
+
+	if want := n.To; !reflect.DeepEqual(gotTo, want) {
+		t.Fatalf("sent to %v, want %v", gotTo, want)
+	}
+}
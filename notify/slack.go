@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SlackNotifier posts notification to Slack incoming webhook URL.
+// If Channel is set, it overrides the webhook's default channel.
+type SlackNotifier struct {
+	URL     string
+	Channel string
+
+	Client *http.Client
+}
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Fallback string       `json:"fallback"`
+	Color    string       `json:"color,omitempty"`
+	Fields   []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (n *SlackNotifier) Notify(notification *Notification) error {
+	buf, err := json.Marshal(n.message(notification))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode slack message")
+	}
+
+	return post(n.Client, n.URL, buf)
+}
+
+func (n *SlackNotifier) message(notification *Notification) *slackMessage {
+	displaced := "nobody"
+	if len(notification.Displaced) != 0 {
+		displaced = strings.Join(notification.Displaced, ", ")
+	}
+
+	color, title := "warning", "Relieved"
+	if notification.Action == ActionStop {
+		color, title = "good", "Back on call"
+	}
+
+	fields := []slackField{
+		{Title: "Schedule", Value: notification.Schedule(), Short: true},
+		{Title: "Window", Value: notification.Window(), Short: true},
+		{Title: "On call", Value: notification.User, Short: true},
+		{Title: title, Value: displaced, Short: true},
+	}
+
+	if len(notification.Reason) != 0 {
+		fields = append(fields, slackField{Title: "Reason", Value: notification.Reason})
+	}
+
+	if len(notification.Ticket) != 0 {
+		fields = append(fields, slackField{Title: "Ticket", Value: notification.Ticket, Short: true})
+	}
+
+	return &slackMessage{
+		Channel: n.Channel,
+		Text:    notification.Subject(),
+		Attachments: []slackAttachment{
+			{
+				Fallback: notification.Text(),
+				Color:    color,
+				Fields:   fields,
+			},
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultTimeout is timeout of posting notification.
+const DefaultTimeout = 10 * time.Second
+
+// WebhookNotifier posts notification as JSON to the URL. If Template is
+// set, the body is rendered by it (text/template with Notification as
+// data), otherwise Notification is encoded as it is.
+//
+// In Template, "json" function encodes the value as JSON and "join"
+// joins strings with the separator. For example:
+//
+//	{"text": {{ json .Text }}, "relieved": {{ json (join .Displaced ", ") }}}
+type WebhookNotifier struct {
+	URL      string
+	Template string
+
+	Client *http.Client
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		buf, err := json.Marshal(v)
+		return string(buf), err
+	},
+	"join": strings.Join,
+}
+
+func (n *WebhookNotifier) Notify(notification *Notification) error {
+	body, err := n.body(notification)
+	if err != nil {
+		return err
+	}
+
+	return post(n.Client, n.URL, body)
+}
+
+func (n *WebhookNotifier) body(notification *Notification) ([]byte, error) {
+	if len(n.Template) == 0 {
+		buf, err := json.Marshal(notification)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode notification")
+		}
+		return buf, nil
+	}
+
+	tmpl, err := template.New("webhook").Funcs(templateFuncs).Parse(n.Template)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse webhook template")
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, notification); err != nil {
+		return nil, errors.Wrap(err, "failed to render webhook template")
+	}
+
+	// Catch broken template here rather than on receiver.
+	var v interface{}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		return nil, errors.Wrap(err, "webhook template doesn't render valid JSON")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// post posts the given JSON body to the URL.
+func post(client *http.Client, url string, body []byte) error {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	res, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to post notification")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("notification endpoint returns unexpected status: %s", res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package pdtest provides fake PagerDuty which is used by tests of the
+// packages built on dutyme (e.g., api, hold and webhook).
+package pdtest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/pkg/errors"
+	"github.com/tcnksm/dutyme/dutyme"
+)
+
+// UserID is the ID of the users returned by GetUser.
+const UserID = "PXPGF42"
+
+// PD is fake PagerDuty which holds overrides in memory. Methods which
+// are not implemented here are not used by the tests (calling them
+// panics).
+type PD struct {
+	dutyme.PagerDuty
+
+	// Schedules are returned by GetSchedule. If it's nil, any schedule
+	// exists and it has only ID.
+	Schedules map[string]*pagerduty.Schedule
+
+	// EscalationPolicies are returned by GetEscalationPolicy.
+	EscalationPolicies map[string]*pagerduty.EscalationPolicy
+
+	// Incidents are returned by ListIncidents.
+	Incidents []pagerduty.Incident
+
+	// Overrides are the overrides which exist now.
+	Overrides []pagerduty.Override
+
+	// Created is the number of overrides created.
+	Created int
+
+	// DeleteErr is returned by DeleteOverride when it's set (the
+	// override is not deleted).
+	DeleteErr error
+
+	// Now returns current time which decides the overrides returned by
+	// GetOverrides (only the ones which haven't started are editable).
+	// If it's nil, time.Now is used.
+	Now func() time.Time
+}
+
+func (c *PD) GetUser(email string) (*dutyme.User, error) {
+	return &dutyme.User{
+		Email: email,
+		Obj:   &pagerduty.APIObject{ID: UserID},
+	}, nil
+}
+
+// GetUserByID returns the user whose email is made from the ID
+// (e.g., PALICE1@example.com).
+func (c *PD) GetUserByID(userID string) (*dutyme.User, error) {
+	return &dutyme.User{
+		Email: userID + "@example.com",
+		Obj:   &pagerduty.APIObject{ID: userID},
+	}, nil
+}
+
+func (c *PD) GetSchedule(scheduleID string, since, until time.Time) (*pagerduty.Schedule, error) {
+	if c.Schedules == nil {
+		return &pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: scheduleID}}, nil
+	}
+
+	schedule, ok := c.Schedules[scheduleID]
+	if !ok {
+		return nil, errors.Errorf("schedule %s doesn't exist", scheduleID)
+	}
+	return schedule, nil
+}
+
+func (c *PD) GetEscalationPolicy(policyID string) (*pagerduty.EscalationPolicy, error) {
+	policy, ok := c.EscalationPolicies[policyID]
+	if !ok {
+		return nil, errors.Errorf("escalation policy %s doesn't exist", policyID)
+	}
+	return policy, nil
+}
+
+func (c *PD) ListIncidents(userID string, serviceIDs, statuses []string) ([]pagerduty.Incident, error) {
+	return c.Incidents, nil
+}
+
+// GetOverrides returns the editable overrides like PagerDuty API with
+// editable=true: the ones in progress are not included.
+func (c *PD) GetOverrides(scheduleID string, since, until time.Time) ([]pagerduty.Override, error) {
+	now := time.Now()
+	if c.Now != nil {
+		now = c.Now()
+	}
+
+	var overrides []pagerduty.Override
+	for _, o := range c.Overrides {
+		if start, err := time.Parse(time.RFC3339, o.Start); err == nil && start.After(now) {
+			overrides = append(overrides, o)
+		}
+	}
+	return overrides, nil
+}
+
+func (c *PD) GetAllOverrides(scheduleID string, since, until time.Time) ([]pagerduty.Override, error) {
+	return c.Overrides, nil
+}
+
+func (c *PD) Override(scheduleID string, user *dutyme.User, start, end time.Time) (*pagerduty.Override, error) {
+	c.Created++
+	override := pagerduty.Override{
+		ID:    fmt.Sprintf("P%06d", c.Created),
+		Start: start.Format(time.RFC3339),
+		End:   end.Format(time.RFC3339),
+	}
+	if user != nil && user.Obj != nil {
+		override.User = *user.Obj
+	}
+	c.Overrides = append(c.Overrides, override)
+	return &override, nil
+}
+
+func (c *PD) DeleteOverride(scheduleID, overrideID string) error {
+	if c.DeleteErr != nil {
+		return c.DeleteErr
+	}
+
+	for i, o := range c.Overrides {
+		if o.ID == overrideID {
+			c.Overrides = append(c.Overrides[:i], c.Overrides[i+1:]...)
+			return nil
+		}
+	}
+	return errors.Errorf("override %s doesn't exist", overrideID)
+}
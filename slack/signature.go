@@ -0,0 +1,54 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxClockSkew is max difference between request timestamp and now.
+// Older requests are rejected to prevent replay attacks.
+const maxClockSkew = 5 * time.Minute
+
+// Sign returns the Slack request signature ("v0=<hex>") of body
+// with the given timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify verifies Slack request signature (X-Slack-Signature) and its
+// timestamp (X-Slack-Request-Timestamp).
+func verify(secret, body []byte, header http.Header, now time.Time) error {
+	if len(secret) == 0 {
+		return errors.New("signing secret is not configured")
+	}
+
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	sig := header.Get("X-Slack-Signature")
+	if len(timestamp) == 0 || len(sig) == 0 {
+		return errors.New("signature is missing")
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp")
+	}
+
+	if d := now.Sub(time.Unix(sec, 0)); d > maxClockSkew || d < -maxClockSkew {
+		return errors.New("timestamp is too old")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(Sign(secret, timestamp, body))) {
+		return errors.New("signature doesn't match")
+	}
+
+	return nil
+}
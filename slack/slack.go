@@ -0,0 +1,381 @@
+// Package slack implements Slack slash-command (/dutyme) and interactive
+// message endpoints.
+//
+// Slash command supports the following subcommands,
+//
+//	/dutyme start [DURATION] [SCHEDULE] [-ticket ID] [-reason TEXT...]
+//	/dutyme stop [SCHEDULE]
+//	/dutyme status
+//
+// As -reason and -ticket flags of start command, they are required when
+// the schedule requires reason. -reason takes the rest of the text.
+//
+// Status reply has buttons to stop or extend each override (interactive
+// message). Requests are verified by Slack signing secret and Slack users
+// are mapped to PagerDuty users by the mapping file. All replies are
+// ephemeral (only visible to the requester).
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/mapping"
+)
+
+const (
+	// DefaultWorking is default duration of override started by command.
+	DefaultWorking = 1 * time.Hour
+
+	// DefaultExtend is default duration of extend button.
+	DefaultExtend = 30 * time.Minute
+)
+
+// maxBodySize is max size of request body.
+const maxBodySize = 1 << 20
+
+// callbackID is callback ID of the interactive message attachments.
+const callbackID = "dutyme"
+
+// Message is the reply message.
+type Message struct {
+	ResponseType    string       `json:"response_type,omitempty"`
+	ReplaceOriginal bool         `json:"replace_original,omitempty"`
+	Text            string       `json:"text"`
+	Attachments     []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment is the message attachment with buttons.
+type Attachment struct {
+	Text       string   `json:"text"`
+	CallbackID string   `json:"callback_id,omitempty"`
+	Actions    []Action `json:"actions,omitempty"`
+}
+
+// Action is the button of interactive message.
+type Action struct {
+	Name  string `json:"name"`
+	Text  string `json:"text,omitempty"`
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value"`
+}
+
+// interaction is the payload of interactive message action.
+type interaction struct {
+	Type       string   `json:"type"`
+	CallbackID string   `json:"callback_id"`
+	Actions    []Action `json:"actions"`
+	User       struct {
+		ID string `json:"id"`
+	} `json:"user"`
+}
+
+// Server handles Slack requests.
+type Server struct {
+	Dutyme  *dutyme.Dutyme
+	Mapping *mapping.Mapping
+
+	// SigningSecret is Slack app signing secret.
+	SigningSecret []byte
+
+	// Working is the duration of override when start doesn't specify
+	// it. If it's zero, DefaultWorking is used.
+	Working time.Duration
+
+	// Extend is the duration of extend button. If it's zero,
+	// DefaultExtend is used.
+	Extend time.Duration
+
+	// Log is used for logging handled requests.
+	Log io.Writer
+
+	// Now returns current time. It's replaced in test.
+	Now func() time.Time
+}
+
+// ServeHTTP handles slash command and interactive message requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if err := verify(s.SigningSecret, body, r.Header, s.now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	var msg *Message
+	if payload := form.Get("payload"); len(payload) != 0 {
+		msg = s.interact(payload)
+	} else {
+		msg = s.command(form.Get("user_id"), form.Get("text"))
+	}
+
+	msg.ResponseType = "ephemeral"
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
+
+// command handles slash command text of the given Slack user.
+func (s *Server) command(slackUser, text string) *Message {
+	args := strings.Fields(text)
+	if len(args) == 0 {
+		return usage()
+	}
+	s.logf("%s: /dutyme %s", slackUser, text)
+
+	user, err := s.user(slackUser)
+	if err != nil {
+		return errorMessage(err)
+	}
+
+	switch args[0] {
+	case "start":
+		return s.start(user, args[1:])
+	case "stop":
+		return s.stop(user, args[1:])
+	case "status":
+		return s.status(user)
+	default:
+		return usage()
+	}
+}
+
+func (s *Server) start(user *dutyme.User, args []string) *Message {
+	working := s.Working
+	if working <= 0 {
+		working = DefaultWorking
+	}
+
+	// Duration and schedule are both optional.
+	var alias, reason, ticket string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-reason":
+			reason = strings.Join(args[i+1:], " ")
+			i = len(args)
+			continue
+		case "-ticket":
+			if i+1 == len(args) {
+				return errorMessage(fmt.Errorf("-ticket requires ticket ID"))
+			}
+			i++
+			ticket = args[i]
+			continue
+		}
+
+		if d, err := time.ParseDuration(arg); err == nil {
+			working = d
+			continue
+		}
+		alias = arg
+	}
+
+	if working <= 0 {
+		return errorMessage(fmt.Errorf("duration must be positive"))
+	}
+
+	scheduleID, err := s.Mapping.Schedule(alias)
+	if err != nil {
+		return errorMessage(err)
+	}
+
+	start := s.now()
+	end := start.Add(working)
+	d := s.Dutyme.WithReason(reason, ticket)
+	override, err := d.Override(scheduleID, user, start, end, true)
+	if err != nil {
+		return errorMessage(fmt.Errorf("failed to override: %s", err))
+	}
+
+	return &Message{
+		Text: fmt.Sprintf("You are on call of %s until %s (override %s)",
+			scheduleLabel(alias, scheduleID), end.Format("15:04 MST"), override.ID),
+	}
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Server) stop(user *dutyme.User, args []string) *Message {
+	var scheduleID string
+	if len(args) > 0 {
+		id, err := s.Mapping.Schedule(args[0])
+		if err != nil {
+			return errorMessage(err)
+		}
+		scheduleID = id
+	}
+
+	stopped, err := s.Dutyme.StopFor(user, scheduleID)
+	if err != nil {
+		return errorMessage(fmt.Errorf("failed to stop override: %s", err))
+	}
+
+	if len(stopped) == 0 {
+		return &Message{Text: "You have no active override created by dutyme"}
+	}
+
+	ids := make([]string, 0, len(stopped))
+	for _, e := range stopped {
+		ids = append(ids, e.OverrideID)
+	}
+	return &Message{Text: fmt.Sprintf("Stopped override %s", strings.Join(ids, ", "))}
+}
+
+func (s *Server) status(user *dutyme.User) *Message {
+	active, err := s.Dutyme.ActiveOverridesFor(user)
+	if err != nil {
+		return errorMessage(fmt.Errorf("failed to read active overrides: %s", err))
+	}
+
+	if len(active) == 0 {
+		return &Message{Text: "You have no active override created by dutyme"}
+	}
+
+	msg := &Message{Text: "Your active overrides"}
+	for _, e := range active {
+		msg.Attachments = append(msg.Attachments, Attachment{
+			Text:       eventText(e),
+			CallbackID: callbackID,
+			Actions: []Action{
+				{Name: "extend", Text: fmt.Sprintf("Extend %s", s.extend()), Type: "button", Value: e.OverrideID},
+				{Name: "stop", Text: "Stop", Type: "button", Value: e.OverrideID},
+			},
+		})
+	}
+	return msg
+}
+
+// interact handles the button action of interactive message.
+func (s *Server) interact(payload string) *Message {
+	var i interaction
+	if err := json.Unmarshal([]byte(payload), &i); err != nil {
+		return errorMessage(fmt.Errorf("invalid payload: %s", err))
+	}
+
+	if i.CallbackID != callbackID || len(i.Actions) == 0 {
+		return errorMessage(fmt.Errorf("unknown action"))
+	}
+	action := i.Actions[0]
+	s.logf("%s: %s %s", i.User.ID, action.Name, action.Value)
+
+	user, err := s.user(i.User.ID)
+	if err != nil {
+		return errorMessage(err)
+	}
+
+	// Only the overrides of the requester can be operated.
+	active, err := s.Dutyme.ActiveOverridesFor(user)
+	if err != nil {
+		return errorMessage(fmt.Errorf("failed to read active overrides: %s", err))
+	}
+
+	var target *journal.Event
+	for j := range active {
+		if active[j].OverrideID == action.Value {
+			target = &active[j]
+		}
+	}
+
+	if target == nil {
+		return errorMessage(fmt.Errorf("override %s is not your active override", action.Value))
+	}
+
+	msg := &Message{ReplaceOriginal: true}
+	switch action.Name {
+	case "stop":
+		if err := s.Dutyme.DeleteOverride(target.ScheduleID, target.OverrideID); err != nil {
+			return errorMessage(fmt.Errorf("failed to stop override: %s", err))
+		}
+		msg.Text = fmt.Sprintf("Stopped override %s", target.OverrideID)
+	case "extend":
+		override, end, err := s.Dutyme.Extend(*target, user, s.extend())
+		if err != nil {
+			return errorMessage(fmt.Errorf("failed to extend override: %s", err))
+		}
+		msg.Text = fmt.Sprintf("Extended override to %s (new override %s)", end.Format("15:04 MST"), override.ID)
+	default:
+		return errorMessage(fmt.Errorf("unknown action %q", action.Name))
+	}
+
+	return msg
+}
+
+// user returns PagerDuty user mapped from the Slack user.
+func (s *Server) user(slackUser string) (*dutyme.User, error) {
+	email, err := s.Mapping.Email(slackUser)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.Dutyme.PD.GetUser(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PagerDuty user: %s", err)
+	}
+	return user, nil
+}
+
+func (s *Server) extend() time.Duration {
+	if s.Extend > 0 {
+		return s.Extend
+	}
+	return DefaultExtend
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Log != nil {
+		fmt.Fprintf(s.Log, format+"\n", args...)
+	}
+}
+
+func usage() *Message {
+	return &Message{
+		Text: "Usage: `/dutyme start [DURATION] [SCHEDULE] [-ticket ID] [-reason TEXT...]`, `/dutyme stop [SCHEDULE]` or `/dutyme status`",
+	}
+}
+
+func errorMessage(err error) *Message {
+	return &Message{Text: fmt.Sprintf("Failed: %s", err)}
+}
+
+func scheduleLabel(alias, scheduleID string) string {
+	if len(alias) != 0 && alias != scheduleID {
+		return fmt.Sprintf("%s (%s)", alias, scheduleID)
+	}
+	return scheduleID
+}
+
+func eventText(e journal.Event) string {
+	name := e.ScheduleName
+	if len(name) == 0 {
+		name = e.ScheduleID
+	}
+	return fmt.Sprintf("%s: %s until %s", e.OverrideID, name, e.End.Format("15:04 MST"))
+}
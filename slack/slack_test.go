@@ -0,0 +1,201 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/mapping"
+	"github.com/tcnksm/dutyme/pdtest"
+)
+
+const (
+	testScheduleID = "PI7DH85"
+	testSlackUser  = "U2147483697"
+	testSecret     = "8f742231b10e8888abcd99yyyzzz85a5"
+)
+
+// testNow is the server clock. It's close to the wall clock because
+// overrides started by the server are checked against it by dutyme.
+var testNow = time.Now().Truncate(time.Second)
+
+func testServer(t *testing.T) (*Server, *pdtest.PD, func()) {
+	dir, err := ioutil.TempDir("", "dutyme-slack")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+
+	pd := &pdtest.PD{}
+	return &Server{
+		Dutyme: &dutyme.Dutyme{
+			PD:      pd,
+			Journal: &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")},
+		},
+		Mapping: &mapping.Mapping{
+			Users:     map[string]string{testSlackUser: "taichi.nakashima@dutyme.com"},
+			Schedules: map[string]string{"checkout": testScheduleID},
+		},
+		SigningSecret: []byte(testSecret),
+		Now:           func() time.Time { return testNow },
+	}, pd, func() { os.RemoveAll(dir) }
+}
+
+func testRequest(s *Server, form url.Values, sign bool) (*httptest.ResponseRecorder, *Message) {
+	body := form.Encode()
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if sign {
+		timestamp := strconv.FormatInt(testNow.Unix(), 10)
+		req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		req.Header.Set("X-Slack-Signature", Sign([]byte(testSecret), timestamp, []byte(body)))
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	var msg Message
+	json.NewDecoder(rec.Body).Decode(&msg)
+	return rec, &msg
+}
+
+func testCommand(s *Server, text string) *Message {
+	_, msg := testRequest(s, url.Values{
+		"command": {"/dutyme"},
+		"user_id": {testSlackUser},
+		"text":    {text},
+	}, true)
+	return msg
+}
+
+func TestServer_command(t *testing.T) {
+	s, pd, cleanup := testServer(t)
+	defer cleanup()
+
+	msg := testCommand(s, "start 2h checkout")
+	if got, want := msg.ResponseType, "ephemeral"; got != want {
+		t.Fatalf("response type = %q, want %q", got, want)
+	}
+
+	if !strings.Contains(msg.Text, "P000001") {
+		t.Fatalf("expect %q to contain override ID", msg.Text)
+	}
+
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	start, err := time.Parse(time.RFC3339, pd.Overrides[0].Start)
+	if err != nil {
+		t.Fatal("Parse failed:", err)
+	}
+
+	if !start.Equal(testNow) {
+		t.Fatalf("override start = %s, want %s", start, testNow)
+	}
+
+	msg = testCommand(s, "status")
+	if got, want := len(msg.Attachments), 1; got != want {
+		t.Fatalf("attachments number = %d, want %d", got, want)
+	}
+
+	if got, want := len(msg.Attachments[0].Actions), 2; got != want {
+		t.Fatalf("actions number = %d, want %d", got, want)
+	}
+
+	msg = testCommand(s, "stop")
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d: %s", got, want, msg.Text)
+	}
+
+	msg = testCommand(s, "unknown")
+	if !strings.HasPrefix(msg.Text, "Usage") {
+		t.Fatalf("expect usage: %q", msg.Text)
+	}
+}
+
+func TestServer_command_reason(t *testing.T) {
+	s, pd, cleanup := testServer(t)
+	defer cleanup()
+	s.Dutyme.ReasonPolicy = &dutyme.ReasonPolicy{Required: true}
+
+	msg := testCommand(s, "start checkout")
+	if !strings.Contains(msg.Text, "reason is required") {
+		t.Fatalf("expect %q to require reason", msg.Text)
+	}
+
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	msg = testCommand(s, "start 2h checkout -ticket OPS-123 -reason deploy v1.2.0")
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d: %s", got, want, msg.Text)
+	}
+
+	events, err := s.Dutyme.Journal.Events()
+	if err != nil {
+		t.Fatal("Events failed:", err)
+	}
+
+	if got, want := events[0].Reason, "deploy v1.2.0"; got != want {
+		t.Fatalf("reason = %q, want %q", got, want)
+	}
+
+	if got, want := events[0].Ticket, "OPS-123"; got != want {
+		t.Fatalf("ticket = %q, want %q", got, want)
+	}
+}
+
+func TestServer_interact(t *testing.T) {
+	s, pd, cleanup := testServer(t)
+	defer cleanup()
+
+	testCommand(s, "start checkout")
+
+	payload := `{"type": "interactive_message", "callback_id": "dutyme",
+"actions": [{"name": "extend", "value": "P000001"}], "user": {"id": "U2147483697"}}`
+	_, msg := testRequest(s, url.Values{"payload": {payload}}, true)
+	if !strings.Contains(msg.Text, "Extended") {
+		t.Fatalf("expect %q to be extended", msg.Text)
+	}
+
+	payload = `{"type": "interactive_message", "callback_id": "dutyme",
+"actions": [{"name": "stop", "value": "P000002"}], "user": {"id": "U2147483697"}}`
+	_, msg = testRequest(s, url.Values{"payload": {payload}}, true)
+	if !strings.Contains(msg.Text, "Stopped") {
+		t.Fatalf("expect %q to be stopped", msg.Text)
+	}
+
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+}
+
+func TestServer_unauthorized(t *testing.T) {
+	s, _, cleanup := testServer(t)
+	defer cleanup()
+
+	rec, _ := testRequest(s, url.Values{"user_id": {testSlackUser}, "text": {"status"}}, false)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// Replayed request (too old timestamp) is rejected.
+	s.Now = func() time.Time { return testNow.Add(10 * time.Minute) }
+	rec, _ = testRequest(s, url.Values{"user_id": {testSlackUser}, "text": {"status"}}, true)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
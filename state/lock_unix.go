@@ -0,0 +1,39 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package state
+
+import (
+	"os"
+	"syscall"
+)
+
+// Lock takes the exclusive lock on the given lock file and returns the
+// function to release it. It blocks until the lock is taken. The lock is
+// released by the kernel when the process dies.
+func Lock(path string) (func(), error) {
+	return flock(path, syscall.LOCK_EX)
+}
+
+// RLock takes the shared lock on the given lock file for reading. Other
+// readers can take it at the same time but writers (Lock) can't.
+func RLock(path string) (func(), error) {
+	return flock(path, syscall.LOCK_SH)
+}
+
+func flock(path string, how int) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
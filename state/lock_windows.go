@@ -0,0 +1,44 @@
+//go:build windows
+// +build windows
+
+package state
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lockTimeout is how long lock waits for the lock file to be removed.
+const lockTimeout = 10 * time.Second
+
+// Lock takes the exclusive lock by creating the lock file exclusively
+// and returns the function to release it (remove the lock file). If the
+// process dies while holding the lock, the lock file must be removed by
+// hand.
+func Lock(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("lock file %s is held too long (remove it if no dutyme is running)", path)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// RLock takes the lock for reading. Lock file can't be shared, so it's
+// the same as Lock.
+func RLock(path string) (func(), error) {
+	return Lock(path)
+}
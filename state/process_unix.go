@@ -0,0 +1,22 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package state
+
+import "syscall"
+
+// ProcessExists returns true if the process of pid exists. It sends
+// signal 0 which only checks the existence (EPERM means the process
+// exists but it's owned by another user).
+func ProcessExists(pid int) (bool, error) {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	if err == nil || err == syscall.EPERM {
+		return true, nil
+	}
+
+	if err == syscall.ESRCH {
+		return false, nil
+	}
+
+	return false, err
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package state
+
+import "os"
+
+// ProcessExists returns true if the process of pid exists. On Windows,
+// FindProcess fails when the process doesn't exist.
+func ProcessExists(pid int) (bool, error) {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false, nil
+	}
+	p.Release()
+
+	return true, nil
+}
@@ -0,0 +1,185 @@
+// Package state persists in-flight sessions, i.e., overrides which are
+// held by running dutyme processes (e.g., hold command), to the state
+// file. When the owning process is killed (e.g., by SIGKILL) and can't
+// clean up its override, the session is left on the state file and it's
+// detected as orphaned by checking the process existence.
+//
+// The state file is written while holding an exclusive lock on the lock
+// file next to it, so concurrent processes don't lose sessions of each
+// other. It's read while holding a shared lock.
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Session is the override which is held by a running process.
+type Session struct {
+	// Host and PID identify the owning process. Process existence
+	// can only be checked for the sessions on the same host.
+	Host string `json:"host"`
+	PID  int    `json:"pid"`
+
+	// Command is the dutyme subcommand which owns the session.
+	Command string `json:"command"`
+
+	OverrideID   string `json:"override_id"`
+	ScheduleID   string `json:"schedule_id"`
+	ScheduleName string `json:"schedule_name,omitempty"`
+
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// State is the state file of sessions.
+type State struct {
+	Path string
+}
+
+// NewSession returns the session owned by the current process.
+func NewSession(command string) Session {
+	host, _ := os.Hostname()
+	return Session{
+		Host:    host,
+		PID:     os.Getpid(),
+		Command: command,
+	}
+}
+
+// Sessions returns all sessions on the state file. It only reads the
+// file while holding the shared lock (it's called on every command to
+// warn orphaned sessions).
+func (s *State) Sessions() ([]Session, error) {
+	if _, err := os.Stat(filepath.Dir(s.Path)); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	unlock, err := RLock(s.Path + ".lock")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to lock state file")
+	}
+	defer unlock()
+
+	return s.read()
+}
+
+// Put adds the given session or replaces the session which is owned by
+// the same process.
+func (s *State) Put(session Session) error {
+	session.UpdatedAt = time.Now()
+	return s.Update(func(sessions []Session) ([]Session, error) {
+		for i, current := range sessions {
+			if current.Host == session.Host && current.PID == session.PID {
+				sessions[i] = session
+				return sessions, nil
+			}
+		}
+		return append(sessions, session), nil
+	})
+}
+
+// Remove removes the session which is owned by the given process.
+func (s *State) Remove(host string, pid int) error {
+	return s.Update(func(sessions []Session) ([]Session, error) {
+		kept := sessions[:0]
+		for _, current := range sessions {
+			if current.Host == host && current.PID == pid {
+				continue
+			}
+			kept = append(kept, current)
+		}
+		return kept, nil
+	})
+}
+
+// Update reads sessions, calls fn with them and writes the sessions
+// returned by fn while holding the exclusive lock. The state file is
+// replaced atomically, so it's not corrupted even if the process is
+// killed while writing.
+func (s *State) Update(fn func([]Session) ([]Session, error)) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return errors.Wrap(err, "failed to create state directory")
+	}
+
+	unlock, err := Lock(s.Path + ".lock")
+	if err != nil {
+		return errors.Wrap(err, "failed to lock state file")
+	}
+	defer unlock()
+
+	sessions, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	updated, err := fn(sessions)
+	if err != nil {
+		return err
+	}
+
+	return s.write(updated)
+}
+
+func (s *State) read() ([]Session, error) {
+	buf, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read state file")
+	}
+
+	var sessions []Session
+	if err := json.Unmarshal(buf, &sessions); err != nil {
+		return nil, errors.Wrap(err, "failed to decode state file")
+	}
+
+	return sessions, nil
+}
+
+func (s *State) write(sessions []Session) error {
+	if sessions == nil {
+		sessions = []Session{}
+	}
+
+	buf, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode sessions")
+	}
+
+	tmp := s.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return errors.Wrap(err, "failed to write state file")
+	}
+
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return errors.Wrap(err, "failed to replace state file")
+	}
+
+	return nil
+}
+
+// Orphaned returns the sessions on the given host whose owning process
+// doesn't exist anymore and whose override is not ended yet.
+func Orphaned(sessions []Session, host string, now time.Time) []Session {
+	var orphaned []Session
+	for _, session := range sessions {
+		if session.Host != host || !session.End.After(now) {
+			continue
+		}
+
+		if exists, err := ProcessExists(session.PID); err != nil || exists {
+			continue
+		}
+		orphaned = append(orphaned, session)
+	}
+	return orphaned
+}
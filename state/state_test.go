@@ -0,0 +1,115 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testState(t *testing.T) (*State, func()) {
+	dir, err := ioutil.TempDir("", "dutyme-state")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+
+	return &State{
+		Path: filepath.Join(dir, "sub", "sessions.json"),
+	}, func() { os.RemoveAll(dir) }
+}
+
+func TestState(t *testing.T) {
+	s, cleanup := testState(t)
+	defer cleanup()
+
+	sessions, err := s.Sessions()
+	if err != nil {
+		t.Fatal("Sessions failed:", err)
+	}
+
+	if got, want := len(sessions), 0; got != want {
+		t.Fatalf("sessions number = %d, want %d", got, want)
+	}
+
+	// Reading doesn't create the state directory.
+	if _, err := os.Stat(filepath.Dir(s.Path)); !os.IsNotExist(err) {
+		t.Fatalf("expect state directory not to exist: %v", err)
+	}
+
+	session := NewSession("hold")
+	session.OverrideID = "P1"
+	if err := s.Put(session); err != nil {
+		t.Fatal("Put failed:", err)
+	}
+
+	// Session owned by the same process is replaced.
+	session.OverrideID = "P2"
+	if err := s.Put(session); err != nil {
+		t.Fatal("Put failed:", err)
+	}
+
+	other := Session{Host: "other", PID: 1, OverrideID: "P3"}
+	if err := s.Put(other); err != nil {
+		t.Fatal("Put failed:", err)
+	}
+
+	// Reading doesn't write the state file.
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(s.Path, past, past); err != nil {
+		t.Fatal("Chtimes failed:", err)
+	}
+
+	sessions, err = s.Sessions()
+	if err != nil {
+		t.Fatal("Sessions failed:", err)
+	}
+
+	if info, err := os.Stat(s.Path); err != nil || !info.ModTime().Equal(past) {
+		t.Fatalf("expect state file not to be written: %v", err)
+	}
+
+	if got, want := len(sessions), 2; got != want {
+		t.Fatalf("sessions number = %d, want %d", got, want)
+	}
+
+	if got, want := sessions[0].OverrideID, "P2"; got != want {
+		t.Fatalf("override ID = %q, want %q", got, want)
+	}
+
+	if err := s.Remove(session.Host, session.PID); err != nil {
+		t.Fatal("Remove failed:", err)
+	}
+
+	sessions, err = s.Sessions()
+	if err != nil {
+		t.Fatal("Sessions failed:", err)
+	}
+
+	if len(sessions) != 1 || sessions[0].OverrideID != "P3" {
+		t.Fatalf("sessions = %v, want only P3", sessions)
+	}
+}
+
+func TestOrphaned(t *testing.T) {
+	// Run process which exits immediately to get PID which doesn't exist.
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("failed to run process:", err)
+	}
+	deadPID := cmd.Process.Pid
+
+	now := time.Now()
+	sessions := []Session{
+		{Host: "local", PID: os.Getpid(), OverrideID: "PALIVE", End: now.Add(time.Hour)},
+		{Host: "local", PID: deadPID, OverrideID: "PDEAD", End: now.Add(time.Hour)},
+		{Host: "local", PID: deadPID, OverrideID: "PENDED", End: now.Add(-time.Hour)},
+		{Host: "remote", PID: deadPID, OverrideID: "PREMOTE", End: now.Add(time.Hour)},
+	}
+
+	orphaned := Orphaned(sessions, "local", now)
+	if len(orphaned) != 1 || orphaned[0].OverrideID != "PDEAD" {
+		t.Fatalf("Orphaned = %v, want only PDEAD", orphaned)
+	}
+}
@@ -0,0 +1,65 @@
+// Package term provides terminal raw mode and key reading which are
+// shared by interactive components (finder and dashboard).
+package term
+
+import (
+	"bufio"
+)
+
+// Special keys are represented by negative runes so that they never
+// conflict with printable characters.
+const (
+	KeyUp rune = -(iota + 1)
+	KeyDown
+	KeyRight
+	KeyLeft
+)
+
+// Control keys sent by terminal in raw mode.
+const (
+	KeyCtrlC     rune = 3
+	KeyBackspace rune = 8
+	KeyNewline   rune = 10
+	KeyEnter     rune = 13
+	KeyCtrlN     rune = 14
+	KeyCtrlP     rune = 16
+	KeyCtrlU     rune = 21
+	KeyEscape    rune = 27
+	KeyDelete    rune = 127
+)
+
+// ReadKey reads one key from the given reader. Arrow keys (sent as
+// ESC [ A-D) are returned as KeyUp, KeyDown, KeyRight and KeyLeft.
+// Other escape sequences are returned as 0.
+func ReadKey(rd *bufio.Reader) (rune, error) {
+	r, _, err := rd.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	if r != KeyEscape {
+		return r, nil
+	}
+
+	if next, _, err := rd.ReadRune(); err != nil || next != '[' {
+		return 0, nil
+	}
+
+	arrow, _, err := rd.ReadRune()
+	if err != nil {
+		return 0, nil
+	}
+
+	switch arrow {
+	case 'A':
+		return KeyUp, nil
+	case 'B':
+		return KeyDown, nil
+	case 'C':
+		return KeyRight, nil
+	case 'D':
+		return KeyLeft, nil
+	}
+
+	return 0, nil
+}
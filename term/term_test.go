@@ -0,0 +1,33 @@
+package term
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	cases := []struct {
+		input string
+		want  []rune
+	}{
+		{"ab", []rune{'a', 'b'}},
+		{"\x1b[A\x1b[B\x1b[C\x1b[D", []rune{KeyUp, KeyDown, KeyRight, KeyLeft}},
+		{"\x03\r", []rune{KeyCtrlC, KeyEnter}},
+		{"\x1b[Zx", []rune{0, 'x'}},
+	}
+
+	for _, tc := range cases {
+		rd := bufio.NewReader(bytes.NewBufferString(tc.input))
+		for _, want := range tc.want {
+			got, err := ReadKey(rd)
+			if err != nil {
+				t.Fatalf("ReadKey(%q) failed: %s", tc.input, err)
+			}
+
+			if got != want {
+				t.Fatalf("ReadKey(%q) = %d, want %d", tc.input, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package term
+
+import (
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// IsTerminal returns true if the given file is the terminal.
+func IsTerminal(f *os.File) bool {
+	return terminal.IsTerminal(int(f.Fd()))
+}
+
+// MakeRaw puts the terminal into raw mode and returns the function
+// to restore the previous state.
+func MakeRaw(f *os.File) (func(), error) {
+	fd := int(f.Fd())
+	oldState, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		terminal.Restore(fd, oldState)
+	}, nil
+}
+
+// Size returns the width and height of the terminal.
+func Size(f *os.File) (int, int, error) {
+	return terminal.GetSize(int(f.Fd()))
+}
@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package term
+
+import (
+	"errors"
+	"os"
+)
+
+// Windows console doesn't always support ANSI escape sequences
+// which interactive components use for rendering. So treat it as
+// not a terminal and let caller fall back to other way.
+func IsTerminal(f *os.File) bool {
+	return false
+}
+
+func MakeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("raw mode is not supported")
+}
+
+func Size(f *os.File) (int, int, error) {
+	return 0, 0, errors.New("terminal size is not supported")
+}
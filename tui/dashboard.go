@@ -0,0 +1,354 @@
+// Package tui provides full-screen terminal dashboard which shows
+// schedules as horizontal timelines and lets user manage overrides
+// with keystrokes.
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/term"
+)
+
+const (
+	// DefaultWidth is default number of columns of timeline.
+	DefaultWidth = 72
+
+	// DefaultRefresh is default interval of reloading schedules.
+	DefaultRefresh = 1 * time.Minute
+
+	// DefaultStep is default duration which is used for creating,
+	// extending and shortening override.
+	DefaultStep = 30 * time.Minute
+)
+
+// Spans is the time ranges which can be displayed (toggled by 't').
+var Spans = []time.Duration{
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// ANSI escape sequences used for rendering.
+const (
+	escClear      = "\x1b[H\x1b[2J"
+	escAltScreen  = "\x1b[?1049h\x1b[?25l"
+	escMainScreen = "\x1b[?25h\x1b[?1049l"
+	escReset      = "\x1b[0m"
+	escInverse    = "\x1b[7m"
+	escMine       = "\x1b[32m"
+	escOthers     = "\x1b[33m"
+)
+
+const helpLine = "up/down: schedule  left/right: override  n: new  +: extend  -: shorten  d: delete  t: 24h/7d  r: refresh  q: quit"
+
+// Dashboard is full-screen dashboard of schedules.
+type Dashboard struct {
+	Dutyme *dutyme.Dutyme
+
+	// User is used for creating overrides and finding user's own
+	// overrides (only they can be changed on dashboard).
+	User *dutyme.User
+
+	Schedules []pagerduty.APIObject
+
+	// In is used for reading keys. If it's terminal, it's put into
+	// raw mode while running.
+	In  io.Reader
+	Out io.Writer
+
+	// Width is the number of columns of timeline.
+	Width int
+
+	// Refresh is the interval of reloading schedules.
+	Refresh time.Duration
+
+	// Step is the duration used for creating, extending and shortening.
+	Step time.Duration
+
+	// Now returns current time. It's replaced in test.
+	Now func() time.Time
+
+	span      int
+	timelines []*dutyme.Timeline
+	updated   time.Time
+	row       int
+	col       int
+	message   string
+
+	// reason is the reason being typed for creating override. It's
+	// non-nil while asking it.
+	reason []rune
+}
+
+func (d *Dashboard) setDefault() {
+	if d.Width <= 0 {
+		d.Width = DefaultWidth
+	}
+
+	if d.Refresh <= 0 {
+		d.Refresh = DefaultRefresh
+	}
+
+	if d.Step <= 0 {
+		d.Step = DefaultStep
+	}
+
+	if d.Now == nil {
+		d.Now = time.Now
+	}
+}
+
+// Run starts dashboard and blocks until user quits.
+func (d *Dashboard) Run() error {
+	d.setDefault()
+
+	if file, ok := d.In.(*os.File); ok && term.IsTerminal(file) {
+		restore, err := term.MakeRaw(file)
+		if err != nil {
+			return err
+		}
+		defer restore()
+
+		fmt.Fprint(d.Out, escAltScreen)
+		defer fmt.Fprint(d.Out, escMainScreen)
+	}
+
+	keyCh := make(chan rune)
+	errCh := make(chan error, 1)
+	go func() {
+		rd := bufio.NewReader(d.In)
+		for {
+			key, err := term.ReadKey(rd)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			keyCh <- key
+		}
+	}()
+
+	ticker := time.NewTicker(d.Refresh)
+	defer ticker.Stop()
+
+	d.Load()
+	for {
+		d.Render(d.Out)
+
+		select {
+		case key := <-keyCh:
+			if d.Handle(key) {
+				return nil
+			}
+		case <-ticker.C:
+			d.Load()
+		case err := <-errCh:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Load reloads timelines of all schedules. Error is shown as message.
+func (d *Dashboard) Load() {
+	d.setDefault()
+
+	now := d.Now()
+	until := now.Add(Spans[d.span])
+
+	timelines := make([]*dutyme.Timeline, 0, len(d.Schedules))
+	for _, schedule := range d.Schedules {
+		t, err := d.Dutyme.Timeline(schedule.ID, now, until)
+		if err != nil {
+			d.message = fmt.Sprintf("Failed to load schedule %q: %s", schedule.Summary, err)
+			return
+		}
+		timelines = append(timelines, t)
+	}
+
+	d.timelines = timelines
+	d.updated = now
+	if mine := d.mine(); d.col >= len(mine) {
+		d.col = 0
+	}
+}
+
+// Handle handles the given key and returns true if user quits.
+func (d *Dashboard) Handle(key rune) bool {
+	d.setDefault()
+
+	if d.reason != nil {
+		d.inputReason(key)
+		return false
+	}
+
+	switch key {
+	case 'q', term.KeyCtrlC:
+		return true
+	case 'k', term.KeyUp:
+		if d.row > 0 {
+			d.row--
+			d.col = 0
+		}
+	case 'j', term.KeyDown:
+		if d.row < len(d.timelines)-1 {
+			d.row++
+			d.col = 0
+		}
+	case 'h', term.KeyLeft:
+		if d.col > 0 {
+			d.col--
+		}
+	case 'l', term.KeyRight:
+		if d.col < len(d.mine())-1 {
+			d.col++
+		}
+	case 't':
+		d.span = (d.span + 1) % len(Spans)
+		d.Load()
+	case 'r':
+		d.message = ""
+		d.Load()
+	case 'n':
+		d.create()
+	case '+':
+		d.extend(d.Step)
+	case '-':
+		d.extend(-d.Step)
+	case 'd':
+		d.remove()
+	}
+
+	return false
+}
+
+func (d *Dashboard) create() {
+	if len(d.timelines) == 0 {
+		return
+	}
+	t := d.timelines[d.row]
+
+	// Ask reason (on the message line) if the schedule requires it and
+	// it's not given by flags.
+	if d.Dutyme.ReasonRequired(t.ScheduleID) && len(d.Dutyme.Reason) == 0 && len(d.Dutyme.Ticket) == 0 {
+		d.reason = []rune{}
+		d.message = reasonPrompt
+		return
+	}
+
+	d.override(d.Dutyme)
+}
+
+const reasonPrompt = "Reason (enter: create, ctrl-c: cancel): "
+
+// inputReason handles the key while asking reason.
+func (d *Dashboard) inputReason(key rune) {
+	switch key {
+	case term.KeyCtrlC:
+		d.reason = nil
+		d.message = "Override canceled"
+		return
+	case term.KeyEnter, term.KeyNewline:
+		reason := strings.TrimSpace(string(d.reason))
+		d.reason = nil
+		if len(reason) == 0 {
+			d.message = "Override canceled: reason is required"
+			return
+		}
+		d.override(d.Dutyme.WithReason(reason, d.Dutyme.Ticket))
+		return
+	case term.KeyBackspace, term.KeyDelete:
+		if len(d.reason) > 0 {
+			d.reason = d.reason[:len(d.reason)-1]
+		}
+	default:
+		if unicode.IsPrint(key) {
+			d.reason = append(d.reason, key)
+		}
+	}
+	d.message = reasonPrompt + string(d.reason)
+}
+
+// override creates override from now on the selected schedule by the
+// given Dutyme (it may have reason).
+func (d *Dashboard) override(dm *dutyme.Dutyme) {
+	t := d.timelines[d.row]
+
+	start := d.Now()
+	end := start.Add(d.Step)
+	override, err := dm.Override(t.ScheduleID, d.User, start, end, true)
+	if err != nil {
+		d.message = fmt.Sprintf("Failed to create override: %s", err)
+		return
+	}
+
+	d.message = fmt.Sprintf("Created override %s until %s", override.ID, end.Format(timeFmt))
+	d.Load()
+}
+
+// extend moves the end of the selected override by delta. Negative
+// delta shortens it.
+func (d *Dashboard) extend(delta time.Duration) {
+	mine := d.mine()
+	if len(mine) == 0 {
+		d.message = "No override of yours is selected"
+		return
+	}
+	o := mine[d.col]
+
+	start := o.Start
+	if now := d.Now(); start.Before(now) {
+		start = now
+	}
+
+	end := o.End.Add(delta)
+	if !end.After(start) {
+		d.message = "Override can't be shortened anymore (delete it by 'd')"
+		return
+	}
+
+	t := d.timelines[d.row]
+	override, err := d.Dutyme.ReplaceOverride(t.ScheduleID, o.ID, d.User, start, end)
+	if err != nil {
+		d.message = fmt.Sprintf("Failed to change override: %s", err)
+		d.Load()
+		return
+	}
+
+	d.message = fmt.Sprintf("Changed override %s to end at %s", override.ID, end.Format(timeFmt))
+	d.Load()
+}
+
+func (d *Dashboard) remove() {
+	mine := d.mine()
+	if len(mine) == 0 {
+		d.message = "No override of yours is selected"
+		return
+	}
+	o := mine[d.col]
+
+	t := d.timelines[d.row]
+	if err := d.Dutyme.DeleteOverride(t.ScheduleID, o.ID); err != nil {
+		d.message = fmt.Sprintf("Failed to delete override: %s", err)
+		return
+	}
+
+	d.message = fmt.Sprintf("Deleted override %s", o.ID)
+	d.Load()
+}
+
+// mine returns the user's overrides on the selected schedule.
+func (d *Dashboard) mine() []dutyme.OverrideSegment {
+	if d.row >= len(d.timelines) {
+		return nil
+	}
+	return d.timelines[d.row].Mine(d.User)
+}
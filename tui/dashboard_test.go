@@ -0,0 +1,209 @@
+package tui
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/pdtest"
+	"github.com/tcnksm/dutyme/term"
+)
+
+const (
+	testScheduleID   = "PI7DH85"
+	testScheduleName = "Dutyme primary"
+	testUserID       = "PXPGF42"
+)
+
+var testNow = time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func testNewDashboard(t *testing.T, input string) (*Dashboard, *pdtest.PD) {
+	pd := &pdtest.PD{
+		Schedules: map[string]*pagerduty.Schedule{
+			testScheduleID: {
+				APIObject: pagerduty.APIObject{ID: testScheduleID},
+				Name:      testScheduleName,
+				FinalSchedule: pagerduty.ScheduleLayer{
+					RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{
+						{
+							Start: "2017-01-01T00:00:00Z",
+							End:   "2017-01-08T00:00:00Z",
+							User:  pagerduty.APIObject{ID: "PALICE1", Summary: "Alice"},
+						},
+					},
+				},
+			},
+		},
+	}
+	return &Dashboard{
+		Dutyme: &dutyme.Dutyme{PD: pd},
+		User: &dutyme.User{
+			Email: "taichi.nakashima@dutyme.com",
+			Obj:   &pagerduty.APIObject{ID: testUserID, Summary: "Taichi Nakashima"},
+		},
+		Schedules: []pagerduty.APIObject{
+			{ID: testScheduleID, Summary: testScheduleName},
+		},
+		In:  bytes.NewBufferString(input),
+		Out: ioutil.Discard,
+		Now: func() time.Time { return testNow },
+	}, pd
+}
+
+func TestDashboard_Handle(t *testing.T) {
+	d, pd := testNewDashboard(t, "")
+	d.Load()
+
+	d.Handle('n')
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	d.Handle('+')
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	if got, want := pd.Overrides[0].End, testNow.Add(2*DefaultStep).Format(time.RFC3339); got != want {
+		t.Fatalf("extended override end = %s, want %s", got, want)
+	}
+
+	d.Handle('-')
+	if got, want := pd.Overrides[0].End, testNow.Add(DefaultStep).Format(time.RFC3339); got != want {
+		t.Fatalf("shortened override end = %s, want %s", got, want)
+	}
+
+	// Can't shorten to zero length.
+	d.Handle('-')
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	d.Handle('d')
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	if !d.Handle('q') {
+		t.Fatal("expect q to quit")
+	}
+}
+
+func TestDashboard_Handle_reason(t *testing.T) {
+	d, pd := testNewDashboard(t, "")
+	d.Dutyme.ReasonPolicy = &dutyme.ReasonPolicy{Required: true}
+	d.Load()
+
+	// Reason is asked and canceled.
+	d.Handle('n')
+	for _, key := range []rune{'x', term.KeyCtrlC} {
+		d.Handle(key)
+	}
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	d.Handle('n')
+	for _, key := range "deploy v1.2.x" {
+		d.Handle(key)
+	}
+	d.Handle(term.KeyBackspace)
+	for _, key := range []rune{'0', term.KeyEnter} {
+		d.Handle(key)
+	}
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d: %s", got, want, d.message)
+	}
+
+	// Reason is used only for the override.
+	if len(d.Dutyme.Reason) != 0 {
+		t.Fatalf("reason = %q, want empty", d.Dutyme.Reason)
+	}
+
+	// Keys are handled as usual after asking.
+	if !d.Handle('q') {
+		t.Fatal("expect q to quit")
+	}
+}
+
+func TestDashboard_Handle_inProgress(t *testing.T) {
+	d, pd := testNewDashboard(t, "")
+	pd.Now = d.Now
+	pd.Overrides = []pagerduty.Override{
+		{
+			ID:    "PMINE01",
+			Start: testNow.Add(-30 * time.Minute).Format(time.RFC3339),
+			End:   testNow.Add(30 * time.Minute).Format(time.RFC3339),
+			User:  pagerduty.APIObject{ID: testUserID, Summary: "Taichi Nakashima"},
+		},
+	}
+	d.Load()
+
+	// The override which already started is the user's and can be
+	// extended.
+	d.Handle('+')
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d: %s", got, want, d.message)
+	}
+
+	if got, want := pd.Overrides[0].End, testNow.Add(30*time.Minute+DefaultStep).Format(time.RFC3339); got != want {
+		t.Fatalf("extended override end = %s, want %s: %s", got, want, d.message)
+	}
+}
+
+func TestDashboard_Handle_othersOverride(t *testing.T) {
+	d, pd := testNewDashboard(t, "")
+	pd.Overrides = []pagerduty.Override{
+		{
+			ID:    "POTHER1",
+			Start: "2017-01-01T11:00:00Z",
+			End:   "2017-01-01T12:00:00Z",
+			User:  pagerduty.APIObject{ID: "PBOB123", Summary: "Bob"},
+		},
+	}
+	d.Load()
+
+	// Others overrides can't be deleted.
+	d.Handle('d')
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+}
+
+func TestDashboard_Render(t *testing.T) {
+	d, _ := testNewDashboard(t, "")
+	d.Load()
+	d.Handle('n')
+
+	var buf bytes.Buffer
+	d.Render(&buf)
+
+	out := buf.String()
+	for _, want := range []string{testScheduleName, "A: Alice", "P000001", "next 24h"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expect %q to contain %q", out, want)
+		}
+	}
+
+	d.Handle('t')
+	buf.Reset()
+	d.Render(&buf)
+	if want := "next 7d"; !strings.Contains(buf.String(), want) {
+		t.Fatalf("expect %q to contain %q", buf.String(), want)
+	}
+}
+
+func TestDashboard_Run(t *testing.T) {
+	d, pd := testNewDashboard(t, "n+q")
+	if err := d.Run(); err != nil {
+		t.Fatal("Run failed:", err)
+	}
+
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+}
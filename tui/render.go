@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tcnksm/dutyme/dutyme"
+)
+
+const (
+	// timeFmt is used for displaying time on dashboard.
+	timeFmt = "01/02 15:04"
+
+	markNobody   = '.'
+	markMine     = '#'
+	markOthers   = '='
+	labelPadding = "               "
+)
+
+// Render writes whole screen of dashboard. Lines are terminated by CRLF
+// since terminal is in raw mode.
+func (d *Dashboard) Render(w io.Writer) {
+	d.setDefault()
+
+	var buf bytes.Buffer
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&buf, format+"\r\n", args...)
+	}
+
+	buf.WriteString(escClear)
+	line("dutyme - next %s (updated at %s)", spanName(Spans[d.span]), d.updated.Format("15:04:05"))
+	line("")
+
+	if len(d.timelines) > 0 {
+		t := d.timelines[0]
+		pad := d.Width - 2*len(timeFmt)
+		if pad < 1 {
+			pad = 1
+		}
+		line("%s%s%s%s", labelPadding, t.Since.Format(timeFmt), strings.Repeat(" ", pad), t.Until.Format(timeFmt))
+	}
+
+	for i, t := range d.timelines {
+		selected := i == d.row
+
+		marker := "  "
+		if selected {
+			marker = "> "
+		}
+		line("%s%s (%s)", marker, t.ScheduleName, t.ScheduleID)
+
+		entries, legend := d.entriesLine(t)
+		line("    on-call    %s", entries)
+		line("    overrides  %s", d.overridesLine(t, selected))
+
+		var labels []string
+		for j, o := range t.Mine(d.User) {
+			label := fmt.Sprintf("%s %s-%s", o.ID, o.Start.Format(timeFmt), o.End.Format(timeFmt))
+			if selected && j == d.col {
+				label = escInverse + label + escReset
+			}
+			labels = append(labels, label)
+		}
+		if len(labels) == 0 {
+			labels = append(labels, "-")
+		}
+		line("    yours      %s", strings.Join(labels, "  "))
+		line("    %s", legend)
+		line("")
+	}
+
+	line("%s", helpLine)
+	if len(d.message) != 0 {
+		line("%s", d.message)
+	}
+
+	w.Write(buf.Bytes())
+}
+
+// entriesLine returns the timeline of the final schedule where each user
+// is represented by a letter, and its legend.
+func (d *Dashboard) entriesLine(t *dutyme.Timeline) (string, string) {
+	marks := make(map[string]rune)
+	var legend []string
+
+	cols := make([]rune, d.Width)
+	for i := range cols {
+		cols[i] = markNobody
+		at := d.at(t, i)
+		for _, e := range t.Entries {
+			if at.Before(e.Start) || !at.Before(e.End) {
+				continue
+			}
+
+			m, ok := marks[e.User]
+			if !ok {
+				m = rune('A' + len(marks)%26)
+				marks[e.User] = m
+				legend = append(legend, fmt.Sprintf("%c: %s", m, e.User))
+			}
+			cols[i] = m
+		}
+	}
+
+	legend = append(legend, fmt.Sprintf("%c: yours", markMine))
+	legend = append(legend, fmt.Sprintf("%c: others", markOthers))
+	return string(cols), strings.Join(legend, "  ")
+}
+
+// overridesLine returns the timeline of overrides. User's own overrides
+// and others are highlighted in different colors and the selected one
+// is inverted.
+func (d *Dashboard) overridesLine(t *dutyme.Timeline, selected bool) string {
+	var selectedID string
+	if mine := t.Mine(d.User); selected && d.col < len(mine) {
+		selectedID = mine[d.col].ID
+	}
+
+	var userID string
+	if d.User != nil && d.User.Obj != nil {
+		userID = d.User.Obj.ID
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < d.Width; i++ {
+		at := d.at(t, i)
+
+		var found *dutyme.OverrideSegment
+		for j, o := range t.Overrides {
+			if !at.Before(o.Start) && at.Before(o.End) {
+				found = &t.Overrides[j]
+			}
+		}
+
+		switch {
+		case found == nil:
+			buf.WriteRune(markNobody)
+		case found.UserID == userID:
+			esc := escMine
+			if found.ID == selectedID {
+				esc += escInverse
+			}
+			fmt.Fprintf(&buf, "%s%c%s", esc, markMine, escReset)
+		default:
+			fmt.Fprintf(&buf, "%s%c%s", escOthers, markOthers, escReset)
+		}
+	}
+
+	return buf.String()
+}
+
+// at returns the time which the given column represents.
+func (d *Dashboard) at(t *dutyme.Timeline, col int) time.Time {
+	step := t.Until.Sub(t.Since) / time.Duration(d.Width)
+	return t.Since.Add(step*time.Duration(col) + step/2)
+}
+
+func spanName(span time.Duration) string {
+	if span >= 48*time.Hour && span%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", span/(24*time.Hour))
+	}
+	return fmt.Sprintf("%dh", span/time.Hour)
+}
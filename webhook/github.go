@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// githubDeployment is the part of GitHub deployment and
+// deployment_status event payload which is used.
+type githubDeployment struct {
+	Deployment struct {
+		ID          int64  `json:"id"`
+		Environment string `json:"environment"`
+		Description string `json:"description"`
+		Creator     struct {
+			Login string `json:"login"`
+		} `json:"creator"`
+	} `json:"deployment"`
+
+	DeploymentStatus struct {
+		State string `json:"state"`
+	} `json:"deployment_status"`
+}
+
+// parseGitHub converts GitHub event to the generic event. Deployment
+// environment is used as schedule alias, description as reason and
+// deployment ID as the key which pairs deployment_status with deployment.
+// When the event doesn't trigger any action, it returns nil.
+func parseGitHub(name string, body []byte) (*Event, error) {
+	var payload githubDeployment
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid GitHub %s event: %s", name, err)
+	}
+
+	event := &Event{
+		Actor:    payload.Deployment.Creator.Login,
+		Schedule: payload.Deployment.Environment,
+		Reason:   payload.Deployment.Description,
+		Key:      fmt.Sprintf("github/deployment/%d", payload.Deployment.ID),
+	}
+
+	switch name {
+	case "deployment":
+		event.Action = ActionStart
+	case "deployment_status":
+		switch payload.DeploymentStatus.State {
+		case "success", "failure", "error", "inactive":
+			event.Action = ActionStop
+		default:
+			// Deployment is still in progress (pending, queued, in_progress).
+			return nil, nil
+		}
+	case "ping":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported GitHub event %q", name)
+	}
+
+	if len(event.Actor) == 0 {
+		return nil, fmt.Errorf("deployment creator is missing")
+	}
+
+	return event, nil
+}
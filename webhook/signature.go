@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// Sign returns the signature of body in "sha256=<hex>" format.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify verifies the HMAC signature of body. It checks the headers in
+// order of X-Dutyme-Signature, X-Hub-Signature-256 and X-Hub-Signature
+// (legacy SHA1 signature of GitHub).
+func verify(secret, body []byte, header http.Header) error {
+	if len(secret) == 0 {
+		return errors.New("secret is not configured")
+	}
+
+	var sig string
+	for _, name := range []string{"X-Dutyme-Signature", "X-Hub-Signature-256", "X-Hub-Signature"} {
+		if sig = header.Get(name); len(sig) != 0 {
+			break
+		}
+	}
+
+	if len(sig) == 0 {
+		return errors.New("signature is missing")
+	}
+
+	parts := strings.SplitN(sig, "=", 2)
+	if len(parts) != 2 {
+		return errors.New("invalid signature format")
+	}
+
+	var h func() hash.Hash
+	switch parts[0] {
+	case "sha256":
+		h = sha256.New
+	case "sha1":
+		h = sha1.New
+	default:
+		return errors.New("unsupported signature algorithm")
+	}
+
+	got, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return errors.New("invalid signature format")
+	}
+
+	mac := hmac.New(h, secret)
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errors.New("signature doesn't match")
+	}
+
+	return nil
+}
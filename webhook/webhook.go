@@ -0,0 +1,305 @@
+// Package webhook receives events from CI/CD pipelines and starts or
+// stops overrides for the person who triggered them.
+//
+// It accepts GitHub deployment and deployment_status events and the
+// generic event format,
+//
+//	{"actor": "octocat", "schedule": "production", "action": "start", "working": "1h",
+//	 "reason": "deploy v1.2.0", "ticket": "OPS-123"}
+//
+// Every request must be signed by HMAC-SHA256 of the body with the shared
+// secret (X-Hub-Signature-256 header for GitHub, X-Dutyme-Signature for
+// generic events, both in "sha256=<hex>" format). Actors and schedules
+// are mapped to PagerDuty users and schedules by the mapping file.
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tcnksm/dutyme/config"
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/mapping"
+)
+
+// DefaultWorking is default duration of override started by event.
+const DefaultWorking = 1 * time.Hour
+
+// maxBodySize is max size of request body.
+const maxBodySize = 1 << 20
+
+// Actions of events.
+const (
+	ActionStart = "start"
+	ActionStop  = "stop"
+)
+
+// Event is the generic event.
+type Event struct {
+	// Actor is the identity of the person who triggered the event.
+	// It's mapped to PagerDuty user by mapping file.
+	Actor string `json:"actor"`
+
+	// Schedule is schedule alias (or ID). If it's empty, the default
+	// schedule on mapping file is used.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Action is start or stop.
+	Action string `json:"action"`
+
+	// Working is the duration of override (only for start).
+	Working config.Duration `json:"working,omitempty"`
+
+	// Reason and Ticket are why the override is started (only for
+	// start). They are required when the schedule requires reason.
+	Reason string `json:"reason,omitempty"`
+	Ticket string `json:"ticket,omitempty"`
+
+	// Key pairs stop with start: stop only stops the overrides started
+	// by the events with the same key (e.g., GitHub deployment ID). If
+	// it's empty, actor and schedule are used.
+	Key string `json:"key,omitempty"`
+}
+
+// Response is the response of the handled event.
+type Response struct {
+	Action      string   `json:"action"`
+	ScheduleID  string   `json:"schedule_id,omitempty"`
+	UserEmail   string   `json:"user_email,omitempty"`
+	OverrideIDs []string `json:"override_ids,omitempty"`
+
+	// Ignored is true when the event doesn't trigger any action
+	// (e.g., deployment_status which is still in progress).
+	Ignored bool `json:"ignored,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Server handles webhook requests.
+type Server struct {
+	Dutyme  *dutyme.Dutyme
+	Mapping *mapping.Mapping
+
+	// Secret is the shared secret of HMAC signature. It must not be
+	// empty (unsigned requests are always rejected).
+	Secret []byte
+
+	// Working is the duration of override when event doesn't specify
+	// it. If it's zero, DefaultWorking is used.
+	Working time.Duration
+
+	// Log is used for logging handled events.
+	Log io.Writer
+
+	mu sync.Mutex
+	// started is the IDs of the overrides started by events keyed by
+	// the event key. Overrides started manually are not stopped by
+	// events.
+	started map[string][]string
+}
+
+// ServeHTTP handles the webhook request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeJSON(w, http.StatusMethodNotAllowed, &Response{
+			Error: fmt.Sprintf("method %s is not allowed", r.Method),
+		})
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &Response{Error: "failed to read body"})
+		return
+	}
+
+	if err := verify(s.Secret, body, r.Header); err != nil {
+		writeJSON(w, http.StatusUnauthorized, &Response{Error: err.Error()})
+		return
+	}
+
+	var event *Event
+	if name := r.Header.Get("X-GitHub-Event"); len(name) != 0 {
+		event, err = parseGitHub(name, body)
+	} else {
+		event, err = parseGeneric(body)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &Response{Error: err.Error()})
+		return
+	}
+
+	if event == nil {
+		writeJSON(w, http.StatusOK, &Response{Ignored: true})
+		return
+	}
+
+	res, code := s.handle(event)
+	s.logf("%s %s by %s: %s", event.Action, res.ScheduleID, event.Actor, resultString(res))
+	writeJSON(w, code, res)
+}
+
+func (s *Server) handle(event *Event) (*Response, int) {
+	res := &Response{Action: event.Action}
+
+	email, err := s.Mapping.Email(event.Actor)
+	if err != nil {
+		res.Error = err.Error()
+		return res, http.StatusBadRequest
+	}
+	res.UserEmail = email
+
+	scheduleID, err := s.Mapping.Schedule(event.Schedule)
+	if err != nil {
+		res.Error = err.Error()
+		return res, http.StatusBadRequest
+	}
+	res.ScheduleID = scheduleID
+
+	user, err := s.Dutyme.PD.GetUser(email)
+	if err != nil {
+		res.Error = fmt.Sprintf("failed to get PagerDuty user: %s", err)
+		return res, http.StatusInternalServerError
+	}
+
+	switch event.Action {
+	case ActionStart:
+		working := time.Duration(event.Working)
+		if working <= 0 {
+			working = s.Working
+		}
+		if working <= 0 {
+			working = DefaultWorking
+		}
+
+		start := time.Now()
+		d := s.Dutyme.WithReason(event.Reason, event.Ticket)
+		override, err := d.Override(scheduleID, user, start, start.Add(working), true)
+		if err != nil {
+			res.Error = fmt.Sprintf("failed to override: %s", err)
+			return res, statusCode(err)
+		}
+		res.OverrideIDs = []string{override.ID}
+		s.remember(eventKey(event, scheduleID), override.ID)
+
+	case ActionStop:
+		stopped, err := s.stop(eventKey(event, scheduleID))
+		res.OverrideIDs = stopped
+		if err != nil {
+			res.Error = fmt.Sprintf("failed to stop override: %s", err)
+			return res, http.StatusInternalServerError
+		}
+
+	default:
+		res.Error = fmt.Sprintf("unknown action %q", event.Action)
+		return res, http.StatusBadRequest
+	}
+
+	return res, http.StatusOK
+}
+
+// eventKey returns the key which pairs stop event with start event.
+func eventKey(event *Event, scheduleID string) string {
+	if len(event.Key) != 0 {
+		return event.Key
+	}
+	return event.Actor + "/" + scheduleID
+}
+
+// remember records the overrides started by the event of the given key.
+func (s *Server) remember(key string, overrideIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started == nil {
+		s.started = make(map[string][]string)
+	}
+	s.started[key] = append(s.started[key], overrideIDs...)
+}
+
+// stop stops the overrides started by the events of the given key (or
+// the ones they are extended to). Overrides which are already stopped
+// or ended are skipped. It returns the IDs of the stopped overrides.
+func (s *Server) stop(key string) ([]string, error) {
+	s.mu.Lock()
+	ids := s.started[key]
+	delete(s.started, key)
+	s.mu.Unlock()
+
+	var events []journal.Event
+	if s.Dutyme.Journal != nil {
+		var err error
+		events, err = s.Dutyme.Journal.Events()
+		if err != nil {
+			s.remember(key, ids...)
+			return nil, err
+		}
+	}
+
+	var stopped []string
+	for i, id := range ids {
+		e, err := s.Dutyme.Stop(journal.Latest(events, id))
+		if err != nil {
+			if dutyme.IsNotFound(err) {
+				continue
+			}
+			// Keep the rest to stop them by the next event.
+			s.remember(key, ids[i:]...)
+			return stopped, err
+		}
+		stopped = append(stopped, e.OverrideID)
+	}
+	return stopped, nil
+}
+
+// statusCode returns the status code of the error of overriding.
+func statusCode(err error) int {
+	// Missing reason or invalid ticket is the fault of the event.
+	if _, ok := err.(*dutyme.ReasonError); ok {
+		return http.StatusBadRequest
+	}
+
+	if _, ok := err.(*dutyme.PolicyError); ok {
+		return http.StatusForbidden
+	}
+
+	return http.StatusInternalServerError
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Log != nil {
+		fmt.Fprintf(s.Log, format+"\n", args...)
+	}
+}
+
+func resultString(res *Response) string {
+	if len(res.Error) != 0 {
+		return "failed: " + res.Error
+	}
+	return fmt.Sprintf("overrides %v", res.OverrideIDs)
+}
+
+func parseGeneric(body []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid event: %s", err)
+	}
+
+	if len(event.Actor) == 0 || len(event.Action) == 0 {
+		return nil, fmt.Errorf("actor and action are required")
+	}
+
+	return &event, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,290 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tcnksm/dutyme/dutyme"
+	"github.com/tcnksm/dutyme/journal"
+	"github.com/tcnksm/dutyme/mapping"
+	"github.com/tcnksm/dutyme/pdtest"
+)
+
+const (
+	testScheduleID = "PI7DH85"
+	testEmail      = "octocat@example.com"
+	testSecret     = "s3cr3t"
+)
+
+func testServer(t *testing.T) (*Server, *pdtest.PD, func()) {
+	dir, err := ioutil.TempDir("", "dutyme-webhook")
+	if err != nil {
+		t.Fatal("TempDir failed:", err)
+	}
+
+	pd := &pdtest.PD{}
+	return &Server{
+		Dutyme: &dutyme.Dutyme{
+			PD:      pd,
+			Journal: &journal.Journal{Path: filepath.Join(dir, "journal.jsonl")},
+		},
+		Mapping: &mapping.Mapping{
+			Users:     map[string]string{"octocat": testEmail},
+			Schedules: map[string]string{"production": testScheduleID},
+		},
+		Secret: []byte(testSecret),
+	}, pd, func() { os.RemoveAll(dir) }
+}
+
+func testRequest(s *Server, header map[string]string, body string) (*httptest.ResponseRecorder, *Response) {
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	var res Response
+	json.NewDecoder(rec.Body).Decode(&res)
+	return rec, &res
+}
+
+func TestServer_generic(t *testing.T) {
+	s, pd, cleanup := testServer(t)
+	defer cleanup()
+
+	start := `{"actor": "octocat", "schedule": "production", "action": "start", "working": "2h"}`
+	rec, res := testRequest(s, map[string]string{
+		"X-Dutyme-Signature": Sign([]byte(testSecret), []byte(start)),
+	}, start)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, res.Error)
+	}
+
+	if got, want := res.ScheduleID, testScheduleID; got != want {
+		t.Fatalf("schedule ID = %q, want %q", got, want)
+	}
+
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	stop := `{"actor": "octocat", "schedule": "production", "action": "stop"}`
+	rec, res = testRequest(s, map[string]string{
+		"X-Dutyme-Signature": Sign([]byte(testSecret), []byte(stop)),
+	}, stop)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, res.Error)
+	}
+
+	if got, want := len(pd.Overrides), 0; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+}
+
+func TestServer_github(t *testing.T) {
+	s, pd, cleanup := testServer(t)
+	defer cleanup()
+
+	deployment := `{"deployment": {"environment": "production", "creator": {"login": "octocat"}}}`
+	rec, res := testRequest(s, map[string]string{
+		"X-GitHub-Event":      "deployment",
+		"X-Hub-Signature-256": Sign([]byte(testSecret), []byte(deployment)),
+	}, deployment)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, res.Error)
+	}
+
+	if got, want := len(pd.Overrides), 1; got != want {
+		t.Fatalf("overrides number = %d, want %d", got, want)
+	}
+
+	cases := []struct {
+		state   string
+		ignored bool
+		remain  int
+	}{
+		{"in_progress", true, 1},
+		{"success", false, 0},
+	}
+
+	for _, tc := range cases {
+		status := fmt.Sprintf(`{"deployment_status": {"state": %q}, "deployment": {"environment": "production", "creator": {"login": "octocat"}}}`, tc.state)
+		rec, res := testRequest(s, map[string]string{
+			"X-GitHub-Event":      "deployment_status",
+			"X-Hub-Signature-256": Sign([]byte(testSecret), []byte(status)),
+		}, status)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d: %s", tc.state, rec.Code, http.StatusOK, res.Error)
+		}
+
+		if res.Ignored != tc.ignored {
+			t.Fatalf("%s: ignored = %v, want %v", tc.state, res.Ignored, tc.ignored)
+		}
+
+		if got := len(pd.Overrides); got != tc.remain {
+			t.Fatalf("%s: overrides number = %d, want %d", tc.state, got, tc.remain)
+		}
+	}
+}
+
+func TestServer_stop(t *testing.T) {
+	s, pd, cleanup := testServer(t)
+	defer cleanup()
+
+	// Override started manually must not be stopped by events.
+	user, _ := pd.GetUser(testEmail)
+	start := time.Now()
+	manual, err := s.Dutyme.Override(testScheduleID, user, start, start.Add(time.Hour), true)
+	if err != nil {
+		t.Fatal("Override failed:", err)
+	}
+
+	deployment := `{"deployment": {"id": 42, "environment": "production", "creator": {"login": "octocat"}}}`
+	rec, res := testRequest(s, map[string]string{
+		"X-GitHub-Event":      "deployment",
+		"X-Hub-Signature-256": Sign([]byte(testSecret), []byte(deployment)),
+	}, deployment)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, res.Error)
+	}
+
+	// Override extended after the event is stopped instead.
+	e, err := s.Dutyme.FindActive(res.OverrideIDs[0])
+	if err != nil {
+		t.Fatal("FindActive failed:", err)
+	}
+
+	extended, _, err := s.Dutyme.Extend(*e, user, time.Hour)
+	if err != nil {
+		t.Fatal("Extend failed:", err)
+	}
+
+	cases := []struct {
+		id      int
+		stopped []string
+	}{
+		// Other deployment
+		{43, nil},
+		{42, []string{extended.ID}},
+	}
+
+	for _, tc := range cases {
+		status := fmt.Sprintf(`{"deployment_status": {"state": "success"}, "deployment": {"id": %d, "environment": "production", "creator": {"login": "octocat"}}}`, tc.id)
+		rec, res := testRequest(s, map[string]string{
+			"X-GitHub-Event":      "deployment_status",
+			"X-Hub-Signature-256": Sign([]byte(testSecret), []byte(status)),
+		}, status)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%d: status = %d, want %d: %s", tc.id, rec.Code, http.StatusOK, res.Error)
+		}
+
+		if got := res.OverrideIDs; !reflect.DeepEqual(got, tc.stopped) {
+			t.Fatalf("%d: stopped = %v, want %v", tc.id, got, tc.stopped)
+		}
+	}
+
+	if len(pd.Overrides) != 1 || pd.Overrides[0].ID != manual.ID {
+		t.Fatalf("overrides = %v, want only %s", pd.Overrides, manual.ID)
+	}
+}
+
+func TestServer_invalid(t *testing.T) {
+	s, _, cleanup := testServer(t)
+	defer cleanup()
+
+	body := `{"actor": "octocat", "action": "start"}`
+	cases := []struct {
+		header map[string]string
+		body   string
+		code   int
+	}{
+		// No signature
+		{map[string]string{}, body, http.StatusUnauthorized},
+
+		// Wrong secret
+		{map[string]string{"X-Dutyme-Signature": Sign([]byte("wrong"), []byte(body))}, body, http.StatusUnauthorized},
+
+		// Unknown actor
+		{
+			map[string]string{"X-Dutyme-Signature": Sign([]byte(testSecret), []byte(`{"actor": "unknown", "action": "start"}`))},
+			`{"actor": "unknown", "action": "start"}`,
+			http.StatusBadRequest,
+		},
+
+		// No schedule and no default schedule
+		{map[string]string{"X-Dutyme-Signature": Sign([]byte(testSecret), []byte(body))}, body, http.StatusBadRequest},
+	}
+
+	for i, tc := range cases {
+		rec, _ := testRequest(s, tc.header, tc.body)
+		if rec.Code != tc.code {
+			t.Fatalf("#%d status = %d, want %d", i, rec.Code, tc.code)
+		}
+	}
+}
+
+func TestServer_reason(t *testing.T) {
+	s, pd, cleanup := testServer(t)
+	defer cleanup()
+	s.Dutyme.ReasonPolicy = &dutyme.ReasonPolicy{Required: true}
+
+	cases := []struct {
+		event  string
+		body   string
+		code   int
+		remain int
+	}{
+		{
+			body: `{"actor": "octocat", "schedule": "production", "action": "start"}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			body:   `{"actor": "octocat", "schedule": "production", "action": "start", "ticket": "OPS-123"}`,
+			code:   http.StatusOK,
+			remain: 1,
+		},
+		{
+			// Deployment description is used as reason.
+			event:  "deployment",
+			body:   `{"deployment": {"environment": "production", "description": "deploy v1.2.0", "creator": {"login": "octocat"}}}`,
+			code:   http.StatusOK,
+			remain: 2,
+		},
+	}
+
+	for i, tc := range cases {
+		header := map[string]string{"X-Dutyme-Signature": Sign([]byte(testSecret), []byte(tc.body))}
+		if len(tc.event) != 0 {
+			header = map[string]string{
+				"X-GitHub-Event":      tc.event,
+				"X-Hub-Signature-256": Sign([]byte(testSecret), []byte(tc.body)),
+			}
+		}
+
+		rec, res := testRequest(s, header, tc.body)
+		if rec.Code != tc.code {
+			t.Fatalf("#%d status = %d, want %d: %s", i, rec.Code, tc.code, res.Error)
+		}
+
+		if got := len(pd.Overrides); got != tc.remain {
+			t.Fatalf("#%d overrides number = %d, want %d", i, got, tc.remain)
+		}
+	}
+}